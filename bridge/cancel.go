@@ -0,0 +1,135 @@
+package main
+
+import "C"
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// defaultConversionTimeout bounds every conversion unless changed with
+// SetConversionTimeout or overridden by timeout_ms
+const defaultConversionTimeout = 30 * time.Second
+
+var conversionTimeout atomic.Int64 // Nanoseconds, 0 for no limit
+
+func init() {
+	conversionTimeout.Store(int64(defaultConversionTimeout))
+}
+
+// cancelHandle lets the caller stop a conversion from another thread
+type cancelHandle struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+var (
+	cancelsMu    sync.Mutex
+	cancels      = map[int64]*cancelHandle{}
+	nextCancelID int64
+
+	errUnknownCancelHandle = newBridgeError(codeUnknownHandle, "unknown cancel handle")
+)
+
+func lookupCancelHandle(handle int64) *cancelHandle {
+	cancelsMu.Lock()
+	defer cancelsMu.Unlock()
+	return cancels[handle]
+}
+
+// conversionContext bounds a conversion by timeout (the default
+// conversion timeout if 0) and, if handle is not 0, by that cancel handle
+func conversionContext(timeout time.Duration, handle int64) (context.Context, context.CancelFunc, error) {
+	ctx := context.Background()
+	if handle != 0 {
+		h := lookupCancelHandle(handle)
+		if h == nil {
+			return nil, nil, errUnknownCancelHandle
+		}
+		ctx = h.ctx
+	}
+	if timeout == 0 {
+		timeout = time.Duration(conversionTimeout.Load())
+	}
+	if timeout > 0 {
+		ctx, cancel := context.WithTimeout(ctx, timeout)
+		return ctx, cancel, nil
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	return ctx, cancel, nil
+}
+
+// isStopped reports whether err means ctx is done rather than that the
+// input is broken
+func isStopped(ctx context.Context, err error) bool {
+	return err != nil && ctx.Err() != nil && errors.Is(err, ctx.Err())
+}
+
+// countLines counts the lines of content that a stopped conversion left
+// out before it could split them
+func countLines(content string) int {
+	return strings.Count(strings.TrimRight(content, "\n"), "\n") + 1
+}
+
+// stoppedWarning explains why a conversion stopped early and how many
+// lines or proxies (what) it left out
+func stoppedWarning(ctx context.Context, left int, what string) string {
+	reason := "it was canceled"
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		reason = "the deadline passed"
+	}
+	return fmt.Sprintf("conversion stopped because %s, %d more %s were left out", reason, left, what)
+}
+
+// CreateCancelHandle returns a handle to pass as cancel_handle in the
+// conversion options, so that CancelConversion can stop those conversions
+//
+//export CreateCancelHandle
+func CreateCancelHandle() C.longlong {
+	defer recoverPanic()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancelsMu.Lock()
+	defer cancelsMu.Unlock()
+	nextCancelID++
+	cancels[nextCancelID] = &cancelHandle{ctx: ctx, cancel: cancel}
+	return C.longlong(nextCancelID)
+}
+
+// CancelConversion stops the conversions running under a cancel handle.
+// They return what they converted so far, marked truncated. Conversions
+// started later with the handle stop at once.
+//
+//export CancelConversion
+func CancelConversion(handle C.longlong) {
+	defer recoverPanic()
+	if h := lookupCancelHandle(int64(handle)); h != nil {
+		h.cancel()
+	}
+}
+
+// ReleaseCancelHandle discards a cancel handle, stopping the conversions
+// still running under it
+//
+//export ReleaseCancelHandle
+func ReleaseCancelHandle(handle C.longlong) {
+	defer recoverPanic()
+	cancelsMu.Lock()
+	defer cancelsMu.Unlock()
+	if h := cancels[int64(handle)]; h != nil {
+		h.cancel()
+		delete(cancels, int64(handle))
+	}
+}
+
+// SetConversionTimeout sets the default time limit of a conversion in
+// milliseconds. 0 removes the limit.
+//
+//export SetConversionTimeout
+func SetConversionTimeout(ms C.longlong) {
+	defer recoverPanic()
+	conversionTimeout.Store(int64(max(ms, 0)) * int64(time.Millisecond))
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// countdownContext is done after Err has been called n times, so that a
+// conversion stops part way through its input
+type countdownContext struct {
+	context.Context
+	n int
+}
+
+func (c *countdownContext) Err() error {
+	if c.n <= 0 {
+		return context.Canceled
+	}
+	c.n--
+	return nil
+}
+
+// trojanLinks returns n trojan links named 1 to n
+func trojanLinks(n int) string {
+	links := make([]string, n)
+	for i := range links {
+		links[i] = fmt.Sprintf("trojan://pw@a.com:443#%d", i+1)
+	}
+	return strings.Join(links, "\n")
+}
+
+func TestConvertLinksStopped(t *testing.T) {
+	// Bridge-parsed lines are checked one by one; links for mihomo are
+	// checked again before their batch is converted
+	const links = "ssh://u:p@a.com:22#1\nssh://u:p@b.com:22#2\ntrojan://pw@c.com:443#3\ntrojan://pw@d.com:443#4"
+
+	tests := []struct {
+		name  string
+		after int // Err calls before ctx is done
+		names []string
+		left  int
+	}{
+		{name: "before the first line", after: 0, left: 4},
+		{name: "after a bridge line", after: 1, names: []string{"1"}, left: 3},
+		{name: "with a batch pending", after: 3, names: []string{"1", "2"}, left: 2},
+		{name: "not stopped", after: 100, names: []string{"1", "2", "3", "4"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := &countdownContext{Context: context.Background(), n: tt.after}
+			proxies, _, left, err := convertLinks(ctx, links)
+			if err != nil {
+				t.Fatalf("convertLinks: %v", err)
+			}
+			if got := proxyNames(proxies); !slices.Equal(got, tt.names) || left != tt.left {
+				t.Errorf("convertLinks = %q, %d left, want %q, %d left", got, left, tt.names, tt.left)
+			}
+		})
+	}
+}
+
+func TestConvertLinksStoppedBetweenBatches(t *testing.T) {
+	// Stops after 300 lines: the first batch is converted, the 44 links
+	// pending for the second are left out with the unread lines
+	ctx := &countdownContext{Context: context.Background(), n: 300}
+	proxies, _, left, err := convertLinks(ctx, trojanLinks(600))
+	if err != nil {
+		t.Fatalf("convertLinks: %v", err)
+	}
+	if len(proxies) != convertBatchSize || left != 600-convertBatchSize {
+		t.Errorf("convertLinks = %d proxies, %d left, want %d, %d", len(proxies), left, convertBatchSize, 600-convertBatchSize)
+	}
+}
+
+func TestConversionDeadline(t *testing.T) {
+	ctx, cancel, err := conversionContext(time.Nanosecond, 0)
+	if err != nil {
+		t.Fatalf("conversionContext: %v", err)
+	}
+	defer cancel()
+	<-ctx.Done()
+
+	subscription := trojanLinks(3)
+	if _, _, err := decodeEnvelope(ctx, subscription); !isStopped(ctx, err) {
+		t.Errorf("decodeEnvelope after the deadline = %v, want %v", err, context.DeadlineExceeded)
+	}
+
+	result, warnings, err := convertSubscriptionEx(ctx, subscription, convertOptions{Preprocess: preprocessNone})
+	if err != nil {
+		t.Fatalf("convertSubscriptionEx: %v", err)
+	}
+	want := "conversion stopped because the deadline passed, 3 more lines were left out"
+	if !result.Truncated || len(result.Proxies) != 0 || !slices.Equal(warnings, []string{want}) {
+		t.Errorf("convertSubscriptionEx = %d proxies, truncated %v, warnings %q, want 0, true, %q",
+			len(result.Proxies), result.Truncated, warnings, want)
+	}
+}
+
+func TestCancelHandle(t *testing.T) {
+	handle := CreateCancelHandle()
+	defer ReleaseCancelHandle(handle)
+
+	opts := convertOptions{CancelHandle: int64(handle)}
+	ctx, cancel, err := opts.context()
+	if err != nil {
+		t.Fatalf("context: %v", err)
+	}
+	defer cancel()
+	if ctx.Err() != nil {
+		t.Fatalf("context is done before the handle was canceled")
+	}
+
+	CancelConversion(handle)
+	if !errors.Is(ctx.Err(), context.Canceled) {
+		t.Fatalf("context error = %v after CancelConversion, want %v", ctx.Err(), context.Canceled)
+	}
+
+	// Envelope decoding stops before any line is read
+	subscription := "dHJvamFuOi8vcHdAYS5jb206NDQzIzEKdHJvamFuOi8vcHdAYi5jb206NDQzIzI="
+	result, warnings, err := convertSubscriptionEx(ctx, subscription, opts)
+	if err != nil {
+		t.Fatalf("convertSubscriptionEx: %v", err)
+	}
+	want := "conversion stopped because it was canceled, 1 more lines were left out"
+	if !result.Truncated || !slices.Equal(warnings, []string{want}) {
+		t.Errorf("truncated = %v, warnings %q, want true, %q", result.Truncated, warnings, want)
+	}
+
+	// Importer results stop too
+	proxies := []map[string]any{{"name": "A", "type": "ss"}, {"name": "B", "type": "ss"}}
+	kept, truncated, warnings := opts.applyToProxies(ctx, proxies)
+	want = "conversion stopped because it was canceled, 2 more proxies were left out"
+	if len(kept) != 0 || !truncated || !slices.Equal(warnings, []string{want}) {
+		t.Errorf("applyToProxies = %d, %v, %q, want 0, true, %q", len(kept), truncated, warnings, want)
+	}
+}
+
+func TestUnknownCancelHandle(t *testing.T) {
+	opts := convertOptions{CancelHandle: -1}
+	if _, _, err := opts.context(); !errors.Is(err, errUnknownCancelHandle) {
+		t.Errorf("context = %v, want %v", err, errUnknownCancelHandle)
+	}
+}
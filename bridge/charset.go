@@ -0,0 +1,124 @@
+package main
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/simplifiedchinese"
+	"golang.org/x/text/encoding/traditionalchinese"
+	"golang.org/x/text/encoding/unicode"
+)
+
+// legacyCharsets are the non-UTF-8 encodings found in real subscriptions
+var legacyCharsets = []struct {
+	name     string
+	encoding encoding.Encoding
+}{
+	{"gbk", simplifiedchinese.GBK},
+	{"big5", traditionalchinese.Big5},
+}
+
+// commonNameRunes are characters that show up in most Chinese node names,
+// in simplified and traditional form. Decodings producing them are far
+// more likely to be right than ones producing rare ideographs.
+var commonNameRunes = func() map[rune]bool {
+	runes := make(map[rune]bool)
+	for _, r := range "节點点香港台臺湾灣日本美国國新加坡韩韓专專线線倍率剩余餘流量到期时時间間官网網套餐直连連中转轉" {
+		runes[r] = true
+	}
+	return runes
+}()
+
+// transcodeText converts UTF-16 and GBK/Big5 text to UTF-8. charset is
+// empty if s already was UTF-8. Lines of mixed content are transcoded on
+// their own so valid UTF-8 lines are kept as they are.
+func transcodeText(s string) (text, charset string) {
+	if name, endian := detectUTF16(s); name != "" {
+		decoded, err := unicode.UTF16(endian, unicode.UseBOM).NewDecoder().String(s)
+		if err == nil {
+			return decoded, name
+		}
+	}
+	if utf8.ValidString(s) {
+		return s, ""
+	}
+
+	lines := strings.Split(s, "\n")
+	for i, line := range lines {
+		if utf8.ValidString(line) {
+			continue
+		}
+		var lineCharset string
+		lines[i], lineCharset = transcodeLegacy(line)
+		if charset == "" {
+			charset = lineCharset
+		}
+	}
+	return strings.Join(lines, "\n"), charset
+}
+
+// detectUTF16 recognizes UTF-16 by its BOM, or by the NUL high bytes of
+// ASCII characters when the BOM is missing. name is empty for other text.
+func detectUTF16(s string) (name string, endian unicode.Endianness) {
+	switch {
+	case strings.HasPrefix(s, "\xff\xfe"):
+		return "utf-16le", unicode.LittleEndian
+	case strings.HasPrefix(s, "\xfe\xff"):
+		return "utf-16be", unicode.BigEndian
+	case len(s) < 4 || len(s)%2 != 0:
+		return
+	}
+
+	evenNUL, oddNUL := 0, 0
+	for i := 0; i < len(s); i += 2 {
+		if s[i] == 0 {
+			evenNUL++
+		}
+		if s[i+1] == 0 {
+			oddNUL++
+		}
+	}
+	half := len(s) / 2
+	switch {
+	case oddNUL*2 > half && evenNUL == 0:
+		return "utf-16le", unicode.LittleEndian
+	case evenNUL*2 > half && oddNUL == 0:
+		return "utf-16be", unicode.BigEndian
+	}
+	return
+}
+
+// transcodeLegacy decodes s with the legacy charset that reads best.
+// Bytes no charset can decode are dropped.
+func transcodeLegacy(s string) (string, string) {
+	best, bestCharset, bestScore := strings.ToValidUTF8(s, ""), "", 0
+	for _, candidate := range legacyCharsets {
+		decoded, err := candidate.encoding.NewDecoder().String(s)
+		if err != nil {
+			continue
+		}
+		if score := legacyScore(decoded); bestCharset == "" || score > bestScore {
+			best, bestCharset, bestScore = decoded, candidate.name, score
+		}
+	}
+	return strings.ReplaceAll(best, string(utf8.RuneError), ""), bestCharset
+}
+
+// legacyScore rates how plausible a decoding is
+func legacyScore(s string) int {
+	score := 0
+	for _, r := range s {
+		switch {
+		case r == utf8.RuneError:
+			score -= 10
+		case commonNameRunes[r]:
+			score += 5
+		case r < 0x80, r >= 0x4e00 && r <= 0x9fff, r >= 0x3000 && r <= 0x303f, r >= 0xff00 && r <= 0xffef:
+			score++
+		default:
+			score--
+		}
+	}
+	return score
+}
@@ -0,0 +1,503 @@
+package main
+
+import "C"
+import (
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/metacubex/mihomo/adapter"
+	"github.com/metacubex/mihomo/adapter/outbound"
+	"github.com/metacubex/mihomo/adapter/outboundgroup"
+	"github.com/metacubex/mihomo/adapter/provider"
+	"github.com/metacubex/mihomo/common/structure"
+	"github.com/metacubex/mihomo/config"
+	CT "github.com/metacubex/mihomo/constant"
+	P "github.com/metacubex/mihomo/constant/provider"
+	"github.com/metacubex/mihomo/listener"
+	R "github.com/metacubex/mihomo/rules"
+	RC "github.com/metacubex/mihomo/rules/common"
+	"github.com/metacubex/mihomo/rules/logic"
+	RP "github.com/metacubex/mihomo/rules/provider"
+	"gopkg.in/yaml.v3"
+)
+
+// yamlLineRegexp finds the line number in yaml.v3 error messages
+var yamlLineRegexp = regexp.MustCompile(`line (\d+):`)
+
+// Rule types backed by GeoIP/GeoSite/ASN databases. Loading them would
+// download the databases, so offline validation only checks their syntax.
+var geodataRuleTypes = map[string]CT.RuleType{
+	"GEOSITE":    CT.GEOSITE,
+	"GEOIP":      CT.GEOIP,
+	"SRC-GEOIP":  CT.SrcGEOIP,
+	"IP-ASN":     CT.IPASN,
+	"SRC-IP-ASN": CT.SrcIPASN,
+}
+
+// configError is one problem found in a config, located by its YAML path
+type configError struct {
+	Path    string `json:"path,omitempty"` // e.g. proxy-groups[2] or rules[10]
+	Line    int    `json:"line,omitempty"`
+	Column  int    `json:"column,omitempty"`
+	Message string `json:"message"`
+}
+
+// configValidation is the verdict of ValidateConfig
+type configValidation struct {
+	Valid  bool          `json:"valid"`
+	Errors []configError `json:"errors"`
+}
+
+// offlineRule stands in for rules whose matcher needs a geodata database
+type offlineRule struct {
+	RC.Base
+	ruleType CT.RuleType
+	payload  string
+	adapter  string
+}
+
+func (r *offlineRule) RuleType() CT.RuleType { return r.ruleType }
+
+func (r *offlineRule) Match(*CT.Metadata, CT.RuleMatchHelper) (bool, string) { return false, "" }
+
+func (r *offlineRule) Adapter() string { return r.adapter }
+
+func (r *offlineRule) Payload() string { return r.payload }
+
+// parseRuleOffline is mihomo's rules.ParseRule without database access.
+// Logic and SUB-RULE rules recurse into it for their sub-rules.
+func parseRuleOffline(tp, payload, target string, params []string, subRules map[string][]CT.Rule) (CT.Rule, error) {
+	if ruleType, ok := geodataRuleTypes[tp]; ok && payload != "" {
+		return &offlineRule{ruleType: ruleType, payload: payload, adapter: target}, nil
+	}
+
+	switch tp {
+	case "SUB-RULE":
+		return logic.NewSubRule(payload, target, subRules, parseRuleOffline)
+	case "AND":
+		return logic.NewAND(payload, target, parseRuleOffline)
+	case "OR":
+		return logic.NewOR(payload, target, parseRuleOffline)
+	case "NOT":
+		return logic.NewNOT(payload, target, parseRuleOffline)
+	}
+	return R.ParseRule(tp, payload, target, params, subRules)
+}
+
+// configChecker collects errors and maps YAML paths back to positions
+type configChecker struct {
+	nodes   map[string]*yaml.Node
+	lines   map[int]string
+	errors  []configError
+	closers []io.Closer // Adapters and providers built while checking
+}
+
+// track remembers v to be closed once the check is done, if it can be
+func (c *configChecker) track(v any) {
+	if closer, ok := v.(io.Closer); ok {
+		c.closers = append(c.closers, closer)
+	}
+}
+
+// close releases everything track collected, like validateProxy does
+// for a single proxy
+func (c *configChecker) close() {
+	for _, closer := range c.closers {
+		_ = closer.Close()
+	}
+	c.closers = nil
+}
+
+// indexYAML records the node of every path, and the outermost path that
+// starts on each line
+func (c *configChecker) indexYAML(node *yaml.Node, path string) {
+	switch node.Kind {
+	case yaml.DocumentNode:
+		for _, child := range node.Content {
+			c.indexYAML(child, path)
+		}
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key := node.Content[i]
+			childPath := key.Value
+			if path != "" {
+				childPath = path + "." + key.Value
+			}
+			c.nodes[childPath] = key
+			if _, ok := c.lines[key.Line]; !ok {
+				c.lines[key.Line] = childPath
+			}
+			c.indexYAML(node.Content[i+1], childPath)
+		}
+	case yaml.SequenceNode:
+		for i, child := range node.Content {
+			childPath := fmt.Sprintf("%s[%d]", path, i)
+			c.nodes[childPath] = child
+			if _, ok := c.lines[child.Line]; !ok {
+				c.lines[child.Line] = childPath
+			}
+			c.indexYAML(child, childPath)
+		}
+	}
+}
+
+// fail records an error at a YAML path
+func (c *configChecker) fail(path string, err error) {
+	configErr := configError{Path: path, Message: err.Error()}
+	if node, ok := c.nodes[path]; ok {
+		configErr.Line = node.Line
+		configErr.Column = node.Column
+	}
+	c.errors = append(c.errors, configErr)
+}
+
+// failYAML records a YAML decoding error, one entry per reported line
+func (c *configChecker) failYAML(err error) {
+	messages := []string{err.Error()}
+	var typeErr *yaml.TypeError
+	if errors.As(err, &typeErr) {
+		messages = typeErr.Errors
+	}
+
+	for _, message := range messages {
+		configErr := configError{Message: message}
+		if match := yamlLineRegexp.FindStringSubmatch(message); match != nil {
+			configErr.Line, _ = strconv.Atoi(match[1])
+			configErr.Path = c.lines[configErr.Line]
+		}
+		c.errors = append(c.errors, configErr)
+	}
+}
+
+// placeholderProxy reserves a name so that references to it resolve
+// while the real adapter is unavailable or not built yet
+func placeholderProxy(name string) CT.Proxy {
+	return adapter.NewProxy(outbound.NewDirectWithOption(outbound.DirectOption{Name: name}))
+}
+
+// checkProxies follows config.parseProxies, but reports every failing
+// proxy, group and provider instead of stopping at the first one
+func (c *configChecker) checkProxies(rawCfg *config.RawConfig) map[string]CT.Proxy {
+	proxies := map[string]CT.Proxy{
+		"DIRECT":      adapter.NewProxy(outbound.NewDirect()),
+		"REJECT":      adapter.NewProxy(outbound.NewReject()),
+		"REJECT-DROP": adapter.NewProxy(outbound.NewRejectDrop()),
+		"COMPATIBLE":  adapter.NewProxy(outbound.NewCompatible()),
+		"PASS":        adapter.NewProxy(outbound.NewPass()),
+	}
+	proxyPaths := map[string]string{}
+
+	var allProxies []string
+	for idx, mapping := range rawCfg.Proxy {
+		path := fmt.Sprintf("proxies[%d]", idx)
+		name, _ := mapping["name"].(string)
+		if _, exist := proxies[name]; exist && name != "" {
+			c.fail(path, fmt.Errorf("proxy %s is the duplicate name", name))
+			continue
+		}
+
+		proxy, err := adapter.ParseProxy(mapping)
+		if err != nil {
+			c.fail(path, err)
+			if name != "" {
+				proxies[name] = placeholderProxy(name)
+				allProxies = append(allProxies, name)
+			}
+			continue
+		}
+		c.track(proxy)
+		proxies[proxy.Name()] = proxy
+		proxyPaths[proxy.Name()] = path
+		allProxies = append(allProxies, proxy.Name())
+	}
+
+	providers := map[string]P.ProxyProvider{}
+	var allProviders []string
+	for _, name := range slices.Sorted(maps.Keys(rawCfg.ProxyProvider)) {
+		mapping := rawCfg.ProxyProvider[name]
+		path := "proxy-providers." + name
+		if name == provider.ReservedName {
+			c.fail(path, fmt.Errorf("can not defined a provider called `%s`", provider.ReservedName))
+			continue
+		}
+		pd, err := provider.ParseProxyProvider(name, mapping)
+		if err != nil {
+			c.fail(path, err)
+			continue
+		}
+		c.track(pd)
+		providers[name] = pd
+		allProviders = append(allProviders, name)
+	}
+
+	groupDecoder := structure.NewDecoder(structure.Option{TagName: "group", WeaklyTypedInput: true})
+	groupMembers := map[string][]string{}
+	groupPaths := map[string]string{}
+	for idx, mapping := range rawCfg.ProxyGroup {
+		path := fmt.Sprintf("proxy-groups[%d]", idx)
+		option := &outboundgroup.GroupCommonOption{}
+		if err := groupDecoder.Decode(mapping, option); err != nil {
+			c.fail(path, err)
+			continue
+		}
+		if option.Name == "" {
+			c.fail(path, errors.New("missing name"))
+			continue
+		}
+		if _, exist := proxies[option.Name]; exist {
+			c.fail(path, fmt.Errorf("proxy group %s: the duplicate name", option.Name))
+			continue
+		}
+		proxies[option.Name] = placeholderProxy(option.Name)
+		groupMembers[option.Name] = option.Proxies
+		groupPaths[option.Name] = path
+	}
+
+	for idx, mapping := range rawCfg.ProxyGroup {
+		path := fmt.Sprintf("proxy-groups[%d]", idx)
+		name, _ := mapping["name"].(string)
+		if groupPaths[name] != path {
+			continue
+		}
+		if loop := findGroupLoop(name, groupMembers); loop != nil {
+			c.fail(path+".proxies", fmt.Errorf("loop is detected in ProxyGroup: %s", strings.Join(loop, " -> ")))
+			continue
+		}
+
+		group, err := outboundgroup.ParseProxyGroup(mapping, proxies, providers, allProxies, allProviders)
+		if err != nil {
+			c.fail(path, err)
+			continue
+		}
+		if proxyGroup, ok := group.(outboundgroup.ProxyGroup); ok {
+			for _, pd := range proxyGroup.Providers() {
+				c.track(pd) // Includes the provider made for inline proxies
+			}
+		}
+		proxies[name] = adapter.NewProxy(group)
+		c.track(proxies[name])
+	}
+
+	for name, path := range proxyPaths {
+		dialerProxy := proxies[name].ProxyInfo().DialerProxy
+		if dialerProxy == "" {
+			continue
+		}
+		if _, exist := proxies[dialerProxy]; !exist {
+			c.fail(path+".dialer-proxy", fmt.Errorf("proxy [%s] dialer-proxy [%s] not found", name, dialerProxy))
+			continue
+		}
+		for seen, current := map[string]bool{name: true}, dialerProxy; current != ""; current = proxies[current].ProxyInfo().DialerProxy {
+			if seen[current] {
+				c.fail(path+".dialer-proxy", fmt.Errorf("proxy [%s] has circular dialer-proxy dependency", name))
+				break
+			}
+			seen[current] = true
+			if _, exist := proxies[current]; !exist {
+				break
+			}
+		}
+	}
+
+	return proxies
+}
+
+// findGroupLoop returns a chain of groups leading from start back to
+// itself, or nil if start is not part of a loop
+func findGroupLoop(start string, members map[string][]string) []string {
+	visited := map[string]bool{}
+	var walk func(name string, chain []string) []string
+	walk = func(name string, chain []string) []string {
+		for _, member := range members[name] {
+			if member == start {
+				return append(chain, member)
+			}
+			if _, isGroup := members[member]; !isGroup || visited[member] {
+				continue
+			}
+			visited[member] = true
+			if loop := walk(member, append(chain, member)); loop != nil {
+				return loop
+			}
+		}
+		return nil
+	}
+	return walk(start, []string{start})
+}
+
+// checkRules follows config.parseRules, reporting every failing line
+func (c *configChecker) checkRules(path string, lines []string, proxies map[string]CT.Proxy, ruleProviders map[string]P.RuleProvider, subRules map[string][]CT.Rule) []CT.Rule {
+	var rules []CT.Rule
+	for idx, line := range lines {
+		linePath := fmt.Sprintf("%s[%d]", path, idx)
+		tp, payload, target, params := RC.ParseRulePayload(line, true)
+		if target == "" {
+			c.fail(linePath, fmt.Errorf("[%s] error: format invalid", line))
+			continue
+		}
+
+		if _, ok := proxies[target]; !ok {
+			if tp != "SUB-RULE" {
+				c.fail(linePath, fmt.Errorf("[%s] error: proxy [%s] not found", line, target))
+				continue
+			} else if _, ok = subRules[target]; !ok {
+				c.fail(linePath, fmt.Errorf("[%s] error: sub-rule [%s] not found", line, target))
+				continue
+			}
+		}
+
+		parsed, err := parseRuleOffline(tp, payload, target, params, subRules)
+		if err != nil {
+			c.fail(linePath, fmt.Errorf("[%s] error: %w", line, err))
+			continue
+		}
+
+		missing := false
+		for _, name := range parsed.ProviderNames() {
+			if _, ok := ruleProviders[name]; !ok {
+				c.fail(linePath, fmt.Errorf("[%s] error: rule set [%s] not found", line, name))
+				missing = true
+			}
+		}
+		if !missing {
+			rules = append(rules, parsed)
+		}
+	}
+	return rules
+}
+
+// checkSubRuleLoops mirrors config.verifySubRuleCircularReferences
+func (c *configChecker) checkSubRuleLoops(name string, subRules map[string][]CT.Rule, chain []string) bool {
+	chain = append(chain, name)
+	for _, rule := range subRules[name] {
+		if rule.RuleType() != CT.SubRules {
+			continue
+		}
+		for _, visited := range chain {
+			if visited == rule.Adapter() {
+				c.fail("sub-rules."+chain[0], fmt.Errorf("sub-rule error: circular references [%s]", strings.Join(append(chain, rule.Adapter()), "->")))
+				return false
+			}
+		}
+		if !c.checkSubRuleLoops(rule.Adapter(), subRules, chain) {
+			return false
+		}
+	}
+	return true
+}
+
+// validateConfig runs the offline parts of mihomo's config parsing:
+// YAML decoding into RawConfig, proxies, proxy-providers, proxy-groups,
+// listeners, rule-providers, sub-rules, rules and tunnels. Nothing is
+// started, downloaded or resolved; GEOIP/GEOSITE/IP-ASN rules are only
+// checked for syntax and the dns/tun/sniffer sections only for types.
+func validateConfig(buf []byte) *configValidation {
+	c := &configChecker{nodes: map[string]*yaml.Node{}, lines: map[int]string{}}
+	defer c.close()
+	return c.check(buf)
+}
+
+// check runs the checks of validateConfig, leaving what it built for
+// close
+func (c *configChecker) check(buf []byte) *configValidation {
+	result := &configValidation{Errors: []configError{}}
+
+	var root yaml.Node
+	if err := yaml.Unmarshal(buf, &root); err != nil {
+		c.failYAML(err)
+		result.Errors = c.errors
+		return result
+	}
+	c.indexYAML(&root, "")
+
+	rawCfg, err := config.UnmarshalRawConfig(buf)
+	if err != nil {
+		c.failYAML(err)
+		result.Errors = c.errors
+		return result
+	}
+
+	proxies := c.checkProxies(rawCfg)
+
+	listeners := map[string]bool{}
+	for idx, mapping := range rawCfg.Listeners {
+		path := fmt.Sprintf("listeners[%d]", idx)
+		inbound, err := listener.ParseListener(mapping)
+		if err != nil {
+			c.fail(path, err)
+			continue
+		}
+		c.track(inbound)
+		if listeners[inbound.Name()] {
+			c.fail(path, fmt.Errorf("listener %s is the duplicate name", inbound.Name()))
+			continue
+		}
+		listeners[inbound.Name()] = true
+	}
+
+	ruleProviders := map[string]P.RuleProvider{}
+	for _, name := range slices.Sorted(maps.Keys(rawCfg.RuleProvider)) {
+		mapping := rawCfg.RuleProvider[name]
+		rp, err := RP.ParseRuleProvider(name, mapping, parseRuleOffline)
+		if err != nil {
+			c.fail("rule-providers."+name, err)
+			continue
+		}
+		c.track(rp)
+		ruleProviders[name] = rp
+	}
+
+	subRules := map[string][]CT.Rule{}
+	for name := range rawCfg.SubRules {
+		subRules[name] = make([]CT.Rule, 0)
+	}
+	subRuleNames := slices.Sorted(maps.Keys(rawCfg.SubRules))
+	for _, name := range subRuleNames {
+		lines := rawCfg.SubRules[name]
+		if name == "" {
+			c.fail("sub-rules", errors.New("sub-rule name is empty"))
+			continue
+		}
+		subRules[name] = c.checkRules("sub-rules."+name, lines, proxies, ruleProviders, subRules)
+	}
+	for _, name := range subRuleNames {
+		if !c.checkSubRuleLoops(name, subRules, nil) {
+			break
+		}
+	}
+
+	c.checkRules("rules", rawCfg.Rule, proxies, ruleProviders, subRules)
+
+	for idx, tunnel := range rawCfg.Tunnels {
+		if tunnel.Proxy == "" {
+			continue
+		}
+		if _, ok := proxies[tunnel.Proxy]; !ok {
+			c.fail(fmt.Sprintf("tunnels[%d]", idx), fmt.Errorf("tunnel proxy %s not found", tunnel.Proxy))
+		}
+	}
+
+	result.Errors = append(result.Errors, c.errors...)
+	result.Valid = len(result.Errors) == 0
+	return result
+}
+
+// ValidateConfig checks a complete mihomo config (YAML) offline and
+// reports every problem the core would reject it for, each located by
+// its YAML path and line.
+//
+//export ValidateConfig
+func ValidateConfig(data *C.char) *C.char {
+	return respond("ValidateConfig", func() (any, []string, error) {
+		if data == nil {
+			return nil, nil, errNullInput
+		}
+		return validateConfig([]byte(C.GoString(data))), nil, nil
+	})
+}
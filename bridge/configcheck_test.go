@@ -0,0 +1,124 @@
+package main
+
+import (
+	"io"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+const validConfig = `
+mixed-port: 7890
+proxies:
+  - {name: HK, type: trojan, server: a.com, port: 443, password: pw}
+  - {name: JP, type: ss, server: b.com, port: 8388, cipher: aes-128-gcm, password: pw}
+proxy-groups:
+  - {name: Proxy, type: select, proxies: [HK, JP, Auto]}
+  - {name: Auto, type: url-test, proxies: [HK, JP], url: 'http://www.gstatic.com/generate_204', interval: 300}
+rules:
+  - DOMAIN-SUFFIX,google.com,Proxy
+  - AND,((NETWORK,udp),(DST-PORT,443)),REJECT
+  - MATCH,DIRECT
+`
+
+func TestValidateConfig(t *testing.T) {
+	tests := []struct {
+		name   string
+		config string
+		errors []configError // Path, line and the start of the message
+	}{
+		{
+			name:   "valid",
+			config: validConfig,
+		},
+		{
+			name:   "bad proxy",
+			config: strings.Replace(validConfig, "cipher: aes-128-gcm", "cipher: nope", 1),
+			errors: []configError{{Path: "proxies[1]", Line: 5}},
+		},
+		{
+			name:   "group with a missing proxy",
+			config: strings.Replace(validConfig, "proxies: [HK, JP, Auto]", "proxies: [HK, SG, Auto]", 1),
+			errors: []configError{{Path: "proxy-groups[0]", Line: 7, Message: "Proxy: 'SG' not found"}},
+		},
+		{
+			name:   "bad rule",
+			config: strings.Replace(validConfig, "DOMAIN-SUFFIX,google.com,Proxy", "DOMAIN-SUFFIX,google.com,Missing", 1),
+			errors: []configError{{Path: "rules[0]", Line: 10, Message: "[DOMAIN-SUFFIX,google.com,Missing] error: proxy [Missing] not found"}},
+		},
+		{
+			name:   "unknown rule type",
+			config: strings.Replace(validConfig, "MATCH,DIRECT", "FOO,x,DIRECT", 1),
+			errors: []configError{{Path: "rules[2]", Line: 12}},
+		},
+		{
+			name:   "not YAML",
+			config: "proxies: [",
+			errors: []configError{{Line: 1}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := validateConfig([]byte(tt.config))
+			if result.Valid != (len(tt.errors) == 0) || len(result.Errors) != len(tt.errors) {
+				t.Fatalf("validateConfig = %v, %+v, want %d errors", result.Valid, result.Errors, len(tt.errors))
+			}
+			for i, want := range tt.errors {
+				got := result.Errors[i]
+				if got.Path != want.Path || got.Line != want.Line || !strings.HasPrefix(got.Message, want.Message) {
+					t.Errorf("error %d = %+v, want %+v", i, got, want)
+				}
+			}
+		})
+	}
+}
+
+// countingCloser counts the Close calls of the closer it wraps
+type countingCloser struct {
+	io.Closer
+	closed *int
+}
+
+func (c countingCloser) Close() error {
+	*c.closed++
+	return c.Closer.Close()
+}
+
+func TestValidateConfigCloses(t *testing.T) {
+	config := validConfig + `
+proxy-providers:
+  inline:
+    type: inline
+    payload:
+      - {name: SG, type: trojan, server: c.com, port: 443, password: pw}
+listeners:
+  - {name: in, type: socks, port: 10808}
+rule-providers:
+  local:
+    type: file
+    behavior: domain
+    path: ./ruleset/local.yaml
+`
+	c := &configChecker{nodes: map[string]*yaml.Node{}, lines: map[int]string{}}
+	if result := c.check([]byte(config)); !result.Valid {
+		t.Fatalf("check = %+v", result.Errors)
+	}
+
+	// 2 proxies, the proxy provider, 2 groups with a provider each for
+	// their listed proxies, the listener and the rule provider. Inline
+	// rule providers have nothing to close.
+	if len(c.closers) != 9 {
+		t.Errorf("tracked %d closers, want 9", len(c.closers))
+	}
+	closed := 0
+	for i, closer := range c.closers {
+		c.closers[i] = countingCloser{Closer: closer, closed: &closed}
+	}
+	tracked := len(c.closers)
+	c.close()
+	if closed != tracked || c.closers != nil {
+		t.Errorf("closed %d of %d closers, %d left", closed, tracked, len(c.closers))
+	}
+}
@@ -5,81 +5,58 @@ package main
 */
 import "C"
 import (
-	"encoding/json"
-	"net/url"
-	"strings"
 	"unsafe"
-
-	"github.com/metacubex/mihomo/common/convert"
 )
 
-// preprocessSubscription fixes URL encoding issues in subscription links
-// Decodes the entire URL line to ensure Mihomo parser receives properly unencoded links
-func preprocessSubscription(subscription string) string {
-	lines := strings.Split(subscription, "\n")
-	var result []string
-
-	for _, line := range lines {
-		line = strings.TrimRight(line, " \r")
-		if line == "" {
-			result = append(result, line)
-			continue
-		}
-
-		// Decode the entire URL line
-		// This fixes issues like v2rayN's uuid%3Apassword encoding
-		// Safe for all protocols: url.QueryUnescape only decodes %XX patterns
-		// and leaves structural characters (://, @, ?, #) intact
-		if decoded, err := url.QueryUnescape(line); err == nil {
-			line = decoded
-		}
-		// If decoding fails (malformed %), keep original line
-
-		result = append(result, line)
-	}
-
-	return strings.Join(result, "\n")
-}
-
-// ConvertSubscription converts V2Ray subscription links to mihomo proxy configs
+// ConvertSubscription converts V2Ray subscription links to mihomo proxy
+// configs. When the default conversion timeout passes it returns the
+// proxies converted so far with a warning.
 //
 //export ConvertSubscription
 func ConvertSubscription(data *C.char) *C.char {
-	if data == nil {
-		return C.CString(`{"error": "null input"}`)
-	}
-
-	// Convert C string to Go string
-	subscription := C.GoString(data)
+	return respond("ConvertSubscription", func() (any, []string, error) {
+		if data == nil {
+			return nil, nil, errNullInput
+		}
 
-	// Preprocess subscription to fix URL encoding issues (e.g., v2rayN exported links)
-	subscription = preprocessSubscription(subscription)
+		ctx, cancel, err := conversionContext(0, 0)
+		if err != nil {
+			return nil, nil, err
+		}
+		defer cancel()
 
-	// Call mihomo's converter
-	proxies, err := convert.ConvertsV2Ray([]byte(subscription))
-	if err != nil {
-		errJSON, _ := json.Marshal(map[string]string{
-			"error": err.Error(),
-		})
-		return C.CString(string(errJSON))
-	}
+		// Unwrap the envelope and normalize link encoding (e.g., v2rayN
+		// exported links)
+		input := C.GoString(data)
+		subscription, err := preprocessSubscription(ctx, input)
+		if isStopped(ctx, err) {
+			return make([]map[string]any, 0), []string{stoppedWarning(ctx, countLines(input), "lines")}, nil
+		}
+		if err != nil {
+			return nil, nil, err
+		}
 
-	// Marshal result to JSON
-	result, err := json.Marshal(proxies)
-	if err != nil {
-		errJSON, _ := json.Marshal(map[string]string{
-			"error": "failed to marshal result: " + err.Error(),
-		})
-		return C.CString(string(errJSON))
-	}
+		// Call mihomo's converter, and the bridge's parsers for the schemes
+		// it does not know
+		proxies, warnings, left, err := convertLinks(ctx, subscription)
+		if err != nil {
+			return nil, warnings, err
+		}
+		if left > 0 {
+			warnings = append(warnings, stoppedWarning(ctx, left, "lines"))
+		}
 
-	return C.CString(string(result))
+		// Names must be clean UTF-8 before they reach generated configs
+		sanitizeProxyNames(proxies)
+		return proxies, warnings, nil
+	})
 }
 
 // FreeString frees memory allocated by Go (must be called from C++ after using the result)
 //
 //export FreeString
 func FreeString(s *C.char) {
+	defer recoverPanic()
 	C.free(unsafe.Pointer(s))
 }
 
@@ -0,0 +1,317 @@
+package main
+
+import "C"
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Preprocess modes of ConvertSubscriptionEx
+const (
+	preprocessFull     = "full"     // Remove the envelope and normalize links
+	preprocessEnvelope = "envelope" // Remove the envelope only
+	preprocessNone     = "none"     // Convert the content as given; binary content is not accepted
+)
+
+// Name de-duplication policies of ConvertSubscriptionEx
+const (
+	dedupSuffix = "suffix" // Number repeated names like ConvertsV2Ray
+	dedupKeep   = "keep"   // Leave repeated names alone
+	dedupDrop   = "drop"   // Drop proxies whose name is taken
+)
+
+// Line codes of proxies left out by the conversion options
+const (
+	codeSchemeNotAllowed = "scheme_not_allowed"
+	codeMaxNodes         = "max_nodes"
+	codeDuplicateName    = "duplicate_name"
+)
+
+// convertOptions are the JSON options of ConvertSubscriptionEx and
+// ParseAny. The zero value converts like ConvertSubscription.
+type convertOptions struct {
+	Preprocess string `json:"preprocess"` // full (default), envelope or none
+	// Strict fails the conversion at the first line that names a scheme
+	// but yields no proxy. Importers report rejected outbounds as warnings
+	// either way.
+	Strict         bool           `json:"strict"`
+	AllowedSchemes []string       `json:"allowed_schemes"` // Link schemes or proxy types; empty allows all
+	MaxNodes       int            `json:"max_nodes"`       // 0 for no limit
+	Dedup          string         `json:"dedup"`           // suffix (default), keep or drop
+	DefaultParams  map[string]any `json:"default_params"`  // Set where the proxy type accepts the key and the proxy lacks it
+	// TimeoutMs stops the conversion after that many milliseconds; 0 uses
+	// the default conversion timeout
+	TimeoutMs    int64 `json:"timeout_ms"`
+	CancelHandle int64 `json:"cancel_handle"` // From CreateCancelHandle, 0 for none
+}
+
+// convertResult is the result of ConvertSubscriptionEx: the subscription
+// diagnostics, where lines left out by the options are marked skipped
+type convertResult struct {
+	subscriptionDiagnostics
+	// max_nodes was reached, or the conversion was stopped before the last
+	// line by its deadline or cancel handle
+	Truncated bool `json:"truncated,omitempty"`
+}
+
+// decodeConvertOptions reads the optional JSON options of
+// ConvertSubscriptionEx and ParseAny
+func decodeConvertOptions(data string) (convertOptions, error) {
+	var opts convertOptions
+	if strings.TrimSpace(data) == "" {
+		return opts, nil
+	}
+	if err := json.Unmarshal([]byte(data), &opts); err != nil {
+		return opts, fmt.Errorf("invalid conversion options: %w", err)
+	}
+
+	switch opts.Preprocess {
+	case "", preprocessFull, preprocessEnvelope, preprocessNone:
+	default:
+		return opts, fmt.Errorf("invalid conversion options: unknown preprocess mode %q", opts.Preprocess)
+	}
+	switch opts.Dedup {
+	case "", dedupSuffix, dedupKeep, dedupDrop:
+	default:
+		return opts, fmt.Errorf("invalid conversion options: unknown dedup policy %q", opts.Dedup)
+	}
+	if opts.MaxNodes < 0 {
+		return opts, fmt.Errorf("invalid conversion options: negative max_nodes %d", opts.MaxNodes)
+	}
+	if opts.TimeoutMs < 0 {
+		return opts, fmt.Errorf("invalid conversion options: negative timeout_ms %d", opts.TimeoutMs)
+	}
+	return opts, nil
+}
+
+// context returns the context a conversion under o runs in
+func (o *convertOptions) context() (context.Context, context.CancelFunc, error) {
+	return conversionContext(time.Duration(o.TimeoutMs)*time.Millisecond, o.CancelHandle)
+}
+
+// canonicalScheme resolves a scheme alias to the scheme it is parsed as
+func canonicalScheme(scheme string) string {
+	scheme = strings.ToLower(scheme)
+	return cmp.Or(schemeAliases[scheme], scheme)
+}
+
+// allows reports whether a proxy from a line with the given scheme (empty
+// for importers) passes allowed_schemes. A scheme matches its aliases and
+// the proxy type, so "ss" also allows SIP008 servers and Surge ss lines.
+func (o *convertOptions) allows(scheme string, proxy map[string]any) bool {
+	if len(o.AllowedSchemes) == 0 {
+		return true
+	}
+	proxyType := canonicalScheme(anyToString(proxy["type"]))
+	for _, allowed := range o.AllowedSchemes {
+		allowed = canonicalScheme(allowed)
+		if allowed == proxyType || (scheme != "" && allowed == canonicalScheme(scheme)) {
+			return true
+		}
+	}
+	return false
+}
+
+// proxyName applies the de-duplication policy to name. ok is false if
+// the proxy has to be dropped.
+func (o *convertOptions) proxyName(names map[string]int, name string) (string, bool) {
+	switch o.Dedup {
+	case dedupKeep:
+		return name, true
+	case dedupDrop:
+		if _, taken := names[name]; taken {
+			return name, false
+		}
+		names[name] = 0
+		return name, true
+	}
+	return uniqueProxyName(names, name), true
+}
+
+// applyDefaults sets the default params the proxy type accepts and the
+// proxy does not set itself. Every proxy gets its own copy of a value.
+func (o *convertOptions) applyDefaults(proxy map[string]any) {
+	params := proxyParamTable()[anyToString(proxy["type"])]
+	for key, value := range o.DefaultParams {
+		if _, set := proxy[key]; !set && params[key].Type != "" {
+			proxy[key] = cloneJSONValue(value)
+		}
+	}
+}
+
+// cloneJSONValue deep-copies a value decoded from JSON
+func cloneJSONValue(value any) any {
+	switch value := value.(type) {
+	case map[string]any:
+		clone := make(map[string]any, len(value))
+		for key, item := range value {
+			clone[key] = cloneJSONValue(item)
+		}
+		return clone
+	case []any:
+		clone := make([]any, len(value))
+		for i, item := range value {
+			clone[i] = cloneJSONValue(item)
+		}
+		return clone
+	}
+	return value
+}
+
+// maxNodesWarning reports the proxies left out by max_nodes
+func maxNodesWarning(limit, left int) string {
+	return fmt.Sprintf("stopped at max_nodes %d, %d more proxies were left out", limit, left)
+}
+
+// convertSubscriptionEx converts share links, proxy lines or a SIP008
+// document line by line under opts, until ctx is done
+func convertSubscriptionEx(ctx context.Context, subscription string, opts convertOptions) (convertResult, []string, error) {
+	data := subscription
+	envelope := subscriptionEnvelope{Path: make([]string, 0)}
+	if opts.Preprocess != preprocessNone {
+		var err error
+		data, envelope, err = decodeEnvelope(ctx, subscription)
+		if err != nil && !isStopped(ctx, err) {
+			return convertResult{}, nil, err
+		}
+	}
+
+	result := convertResult{subscriptionDiagnostics: subscriptionDiagnostics{
+		Proxies:  make([]map[string]any, 0),
+		Lines:    make([]lineDiagnostic, 0),
+		Envelope: envelope,
+	}}
+	if ctx.Err() != nil {
+		// Stopped while removing the envelope, before any line was read
+		result.Truncated = true
+		return result, []string{stoppedWarning(ctx, countLines(subscription), "lines")}, nil
+	}
+	names := make(map[string]int)
+	var left int
+	lines, stopped := diagnoseLines(ctx, data, opts.Preprocess == "" || opts.Preprocess == preprocessFull)
+	for _, line := range lines {
+		diag, proxy := line.diag, line.proxy
+		if opts.Strict && diag.Scheme != "" && diag.Status != lineParsed {
+			return result, nil, &bridgeError{
+				Code:    cmp.Or(diag.Code, codeInvalidInput),
+				Message: fmt.Sprintf("line %d (%s): %s", diag.Line, diag.Scheme, cmp.Or(diag.Message, diag.Code)),
+				Details: diag,
+			}
+		}
+
+		if proxy != nil {
+			skip := func(code, message string) {
+				diag.Status, diag.Code, diag.Message = lineSkipped, code, message
+			}
+			switch {
+			case !opts.allows(diag.Scheme, proxy):
+				skip(codeSchemeNotAllowed, fmt.Sprintf("%s proxies are not allowed", anyToString(proxy["type"])))
+			case opts.MaxNodes > 0 && len(result.Proxies) >= opts.MaxNodes:
+				result.Truncated = true
+				left++
+				skip(codeMaxNodes, fmt.Sprintf("only the first %d proxies are kept", opts.MaxNodes))
+			default:
+				if name, ok := opts.proxyName(names, diag.Name); ok {
+					diag.Name = name
+					proxy["name"] = name
+					opts.applyDefaults(proxy)
+					result.Proxies = append(result.Proxies, proxy)
+				} else {
+					skip(codeDuplicateName, fmt.Sprintf("name %q is already taken", name))
+				}
+			}
+		}
+
+		switch diag.Status {
+		case lineParsed:
+			result.Summary.Parsed++
+		case lineSkipped:
+			result.Summary.Skipped++
+		case lineFailed:
+			result.Summary.Failed++
+		}
+		result.Lines = append(result.Lines, diag)
+	}
+	result.Summary.Total = len(result.Lines)
+
+	var warnings []string
+	if result.Truncated {
+		warnings = append(warnings, maxNodesWarning(opts.MaxNodes, left))
+	}
+	if stopped > 0 {
+		result.Truncated = true
+		warnings = append(warnings, stoppedWarning(ctx, stopped, "lines"))
+	}
+	return result, warnings, nil
+}
+
+// applyToProxies applies allowed_schemes, max_nodes, dedup and
+// default_params to the proxies of an importer, until ctx is done
+func (o *convertOptions) applyToProxies(ctx context.Context, proxies []map[string]any) (kept []map[string]any, truncated bool, warnings []string) {
+	kept = make([]map[string]any, 0, len(proxies))
+	names := make(map[string]int)
+	var left int
+	for i, proxy := range proxies {
+		if ctx.Err() != nil {
+			warnings = append(warnings, stoppedWarning(ctx, len(proxies)-i, "proxies"))
+			truncated = true
+			break
+		}
+		name := anyToString(proxy["name"])
+		switch {
+		case !o.allows("", proxy):
+			warnings = append(warnings, fmt.Sprintf("proxy '%s' left out: %s proxies are not allowed",
+				name, anyToString(proxy["type"])))
+		case o.MaxNodes > 0 && len(kept) >= o.MaxNodes:
+			truncated = true
+			left++
+		default:
+			unique, ok := o.proxyName(names, name)
+			if !ok {
+				warnings = append(warnings, fmt.Sprintf("proxy '%s' left out: the name is already taken", name))
+				continue
+			}
+			proxy["name"] = unique
+			o.applyDefaults(proxy)
+			kept = append(kept, proxy)
+		}
+	}
+	if left > 0 {
+		warnings = append(warnings, maxNodesWarning(o.MaxNodes, left))
+	}
+	return kept, truncated, warnings
+}
+
+// ConvertSubscriptionEx converts subscription links like
+// ConvertSubscription, under JSON options for preprocessing, strictness,
+// allowed schemes, the node limit, name de-duplication, default params,
+// the timeout and a cancel handle. It reports every line like
+// DiagnoseSubscription.
+//
+//export ConvertSubscriptionEx
+func ConvertSubscriptionEx(data *C.char, options *C.char) *C.char {
+	return respond("ConvertSubscriptionEx", func() (any, []string, error) {
+		if data == nil {
+			return nil, nil, errNullInput
+		}
+
+		var optionsJSON string
+		if options != nil {
+			optionsJSON = C.GoString(options)
+		}
+		opts, err := decodeConvertOptions(optionsJSON)
+		if err != nil {
+			return nil, nil, err
+		}
+		ctx, cancel, err := opts.context()
+		if err != nil {
+			return nil, nil, err
+		}
+		defer cancel()
+		return convertSubscriptionEx(ctx, C.GoString(data), opts)
+	})
+}
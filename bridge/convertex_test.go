@@ -0,0 +1,207 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestConvertSubscriptionEx(t *testing.T) {
+	const links = "trojan://pw@a.com:443#A\n" +
+		"hy2://pw@b.com:443#B\n" +
+		"socks5://u:p@c.com:1080#C\n" +
+		"JP = ss, d.com, 8388, encrypt-method=aes-128-gcm, password=pw\n" +
+		"trojan://pw@e.com:443#A"
+
+	tests := []struct {
+		name      string
+		options   string
+		names     []string // Names of the converted proxies
+		codes     []string // Codes of the skipped lines
+		truncated bool
+		warning   string // Expected in the warnings
+	}{
+		{
+			name:  "defaults",
+			names: []string{"A", "B", "C", "JP", "A-01"},
+		},
+		{
+			name:    "scheme aliases",
+			options: `{"allowed_schemes": ["hysteria2", "socks"]}`,
+			names:   []string{"B", "C"},
+			codes:   []string{codeSchemeNotAllowed, codeSchemeNotAllowed, codeSchemeNotAllowed},
+		},
+		{
+			name:    "proxy type matches proxy lines",
+			options: `{"allowed_schemes": ["SS"]}`,
+			names:   []string{"JP"},
+			codes:   []string{codeSchemeNotAllowed, codeSchemeNotAllowed, codeSchemeNotAllowed, codeSchemeNotAllowed},
+		},
+		{
+			name:      "max nodes",
+			options:   `{"max_nodes": 2}`,
+			names:     []string{"A", "B"},
+			codes:     []string{codeMaxNodes, codeMaxNodes, codeMaxNodes},
+			truncated: true,
+			warning:   maxNodesWarning(2, 3),
+		},
+		{
+			name:    "keep duplicate names",
+			options: `{"dedup": "keep"}`,
+			names:   []string{"A", "B", "C", "JP", "A"},
+		},
+		{
+			name:    "drop duplicate names",
+			options: `{"dedup": "drop"}`,
+			names:   []string{"A", "B", "C", "JP"},
+			codes:   []string{codeDuplicateName},
+		},
+		{
+			name:    "suffix duplicate names",
+			options: `{"dedup": "suffix"}`,
+			names:   []string{"A", "B", "C", "JP", "A-01"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts, err := decodeConvertOptions(tt.options)
+			if err != nil {
+				t.Fatalf("decodeConvertOptions: %v", err)
+			}
+			result, warnings, err := convertSubscriptionEx(context.Background(), links, opts)
+			if err != nil {
+				t.Fatalf("convertSubscriptionEx: %v", err)
+			}
+
+			names := proxyNames(result.Proxies)
+			var codes []string
+			for _, line := range result.Lines {
+				if line.Status == lineSkipped {
+					codes = append(codes, line.Code)
+				}
+			}
+			if !slices.Equal(names, tt.names) || !slices.Equal(codes, tt.codes) {
+				t.Errorf("names = %q, skipped %q, want %q, %q", names, codes, tt.names, tt.codes)
+			}
+			if result.Truncated != tt.truncated {
+				t.Errorf("truncated = %v, want %v", result.Truncated, tt.truncated)
+			}
+			if tt.warning != "" && !slices.Contains(warnings, tt.warning) {
+				t.Errorf("warnings = %q, want %q", warnings, tt.warning)
+			}
+		})
+	}
+}
+
+func TestConvertSubscriptionExStrict(t *testing.T) {
+	const links = "trojan://pw@a.com:443#A\nssh://c.com:22#B\n"
+
+	opts := convertOptions{Strict: true}
+	_, _, err := convertSubscriptionEx(context.Background(), links, opts)
+	var bridgeErr *bridgeError
+	if !errors.As(err, &bridgeErr) || bridgeErr.Code != "missing_credentials" || !strings.HasPrefix(bridgeErr.Message, "line 2 (ssh)") {
+		t.Fatalf("strict error = %v, want missing_credentials at line 2", err)
+	}
+
+	// Without strict the line is reported and the rest converted
+	result, _, err := convertSubscriptionEx(context.Background(), links, convertOptions{})
+	if err != nil || len(result.Proxies) != 1 || result.Summary.Failed != 1 {
+		t.Errorf("convertSubscriptionEx = %d proxies, %d failed, %v, want 1, 1", len(result.Proxies), result.Summary.Failed, err)
+	}
+}
+
+func TestConvertDefaultParams(t *testing.T) {
+	const links = "trojan://pw@a.com:443#A\ntrojan://pw@b.com:443?type=ws&path=/p#B\nss://YWVzLTEyOC1nY206cHc@c.com:8388#C"
+	opts, err := decodeConvertOptions(`{"default_params": {"udp": false, "flow": "xtls-rprx-vision", "ws-opts": {"path": "/d"}}}`)
+	if err != nil {
+		t.Fatalf("decodeConvertOptions: %v", err)
+	}
+	result, _, err := convertSubscriptionEx(context.Background(), links, opts)
+	if err != nil || len(result.Proxies) != 3 {
+		t.Fatalf("convertSubscriptionEx = %d proxies, %v", len(result.Proxies), err)
+	}
+	a, b, c := result.Proxies[0], result.Proxies[1], result.Proxies[2]
+
+	// The converter sets udp itself, and trojan has no flow
+	if a["udp"] != true || a["flow"] != nil {
+		t.Errorf("trojan udp = %v, flow = %v, want true, unset", a["udp"], a["flow"])
+	}
+	if _, ok := c["ws-opts"]; ok {
+		t.Errorf("ss got ws-opts %v", c["ws-opts"])
+	}
+	// B keeps its own ws-opts, A gets a copy of the default
+	if path := b["ws-opts"].(map[string]any)["path"]; path != "/p" {
+		t.Errorf("B ws path = %v, want /p", path)
+	}
+	wsOpts, ok := a["ws-opts"].(map[string]any)
+	if !ok || wsOpts["path"] != "/d" {
+		t.Fatalf("A ws-opts = %v, want the default", a["ws-opts"])
+	}
+	wsOpts["path"] = "/changed"
+	if path := opts.DefaultParams["ws-opts"].(map[string]any)["path"]; path != "/d" {
+		t.Errorf("changing a proxy changed the default to %v", path)
+	}
+}
+
+func TestApplyToProxies(t *testing.T) {
+	proxies := func() []map[string]any {
+		return []map[string]any{
+			{"name": "A", "type": "trojan"},
+			{"name": "B", "type": "hysteria2"},
+			{"name": "A", "type": "vless"},
+			{"name": "C", "type": "ss"},
+		}
+	}
+
+	tests := []struct {
+		name      string
+		options   convertOptions
+		names     []string
+		truncated bool
+		warnings  []string
+	}{
+		{
+			name:  "defaults",
+			names: []string{"A", "B", "A-01", "C"},
+		},
+		{
+			name:     "allowed schemes",
+			options:  convertOptions{AllowedSchemes: []string{"hy2", "trojan"}},
+			names:    []string{"A", "B"},
+			warnings: []string{"proxy 'A' left out: vless proxies are not allowed", "proxy 'C' left out: ss proxies are not allowed"},
+		},
+		{
+			name:      "max nodes",
+			options:   convertOptions{MaxNodes: 1},
+			names:     []string{"A"},
+			truncated: true,
+			warnings:  []string{maxNodesWarning(1, 3)},
+		},
+		{
+			name:     "drop duplicates",
+			options:  convertOptions{Dedup: dedupDrop},
+			names:    []string{"A", "B", "C"},
+			warnings: []string{"proxy 'A' left out: the name is already taken"},
+		},
+		{
+			name:    "keep duplicates",
+			options: convertOptions{Dedup: dedupKeep},
+			names:   []string{"A", "B", "A", "C"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			kept, truncated, warnings := tt.options.applyToProxies(context.Background(), proxies())
+			if got := proxyNames(kept); !slices.Equal(got, tt.names) {
+				t.Errorf("names = %q, want %q", got, tt.names)
+			}
+			if truncated != tt.truncated || !slices.Equal(warnings, tt.warnings) {
+				t.Errorf("truncated = %v, warnings %q, want %v, %q", truncated, warnings, tt.truncated, tt.warnings)
+			}
+		})
+	}
+}
@@ -0,0 +1,142 @@
+package main
+
+import "C"
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync/atomic"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+)
+
+// defaultMaxDecompressedSize caps inflated payloads unless changed with
+// SetMaxDecompressedSize
+const defaultMaxDecompressedSize = 16 << 20
+
+var (
+	maxDecompressedSize atomic.Int64
+
+	errDecompressedTooLarge = errors.New("decompressed payload is too large")
+
+	gzipMagic = []byte{0x1f, 0x8b}
+	zstdMagic = []byte{0x28, 0xb5, 0x2f, 0xfd}
+)
+
+func init() {
+	maxDecompressedSize.Store(defaultMaxDecompressedSize)
+}
+
+// decompressPayload inflates gzip, zlib and zstd payloads recognized by
+// their magic bytes. Brotli has no magic bytes and is only tried on binary
+// content. format is empty if s was not compressed. Inflating stops with
+// ctx's error once ctx is done.
+func decompressPayload(ctx context.Context, s string) (text, format string, err error) {
+	buf := []byte(s)
+	var reader io.Reader
+	switch {
+	case bytes.HasPrefix(buf, gzipMagic):
+		format = "gzip"
+		reader, err = gzip.NewReader(bytes.NewReader(buf))
+	case bytes.HasPrefix(buf, zstdMagic):
+		format = "zstd"
+		var decoder *zstd.Decoder
+		if decoder, err = zstd.NewReader(bytes.NewReader(buf)); err == nil {
+			defer decoder.Close()
+			reader = decoder
+		}
+	case isZlibHeader(buf) && !isPlainText(s):
+		format = "zlib"
+		reader, err = zlib.NewReader(bytes.NewReader(buf))
+	case hasControlBytes(buf):
+		// Brotli has no magic bytes. Text (even GBK or Big5) has no
+		// control bytes, so only binary content is tried, and content
+		// that does not inflate to text is kept as is.
+		inflated, err := readDecompressed(ctx, brotli.NewReader(bytes.NewReader(buf)))
+		if ctx.Err() != nil {
+			return "", "", ctx.Err()
+		}
+		if err == nil && isPlainText(inflated) {
+			return inflated, "brotli", nil
+		}
+		return s, "", nil
+	default:
+		return s, "", nil
+	}
+	if err != nil {
+		return "", format, fmt.Errorf("invalid %s payload: %w", format, err)
+	}
+
+	text, err = readDecompressed(ctx, reader)
+	if errors.Is(err, errDecompressedTooLarge) {
+		return "", format, err
+	}
+	if ctx.Err() != nil {
+		return "", format, ctx.Err()
+	}
+	if err != nil {
+		return "", format, fmt.Errorf("invalid %s payload: %w", format, err)
+	}
+	return text, format, nil
+}
+
+// isZlibHeader checks the zlib CMF/FLG header (deflate, valid check bits)
+func isZlibHeader(buf []byte) bool {
+	return len(buf) >= 2 && buf[0]&0x0f == 8 && buf[0]>>4 <= 7 && (uint16(buf[0])<<8|uint16(buf[1]))%31 == 0
+}
+
+// hasControlBytes reports whether buf contains bytes no text has
+func hasControlBytes(buf []byte) bool {
+	for _, b := range buf {
+		if b < 0x20 && b != '\n' && b != '\r' && b != '\t' || b == 0x7f {
+			return true
+		}
+	}
+	return false
+}
+
+// contextReader fails once ctx is done, so that a payload inflating
+// slowly towards the size cap still stops at the deadline
+type contextReader struct {
+	ctx    context.Context
+	reader io.Reader
+}
+
+func (r contextReader) Read(p []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return r.reader.Read(p)
+}
+
+// readDecompressed reads a decompressor up to the configured size cap,
+// until ctx is done
+func readDecompressed(ctx context.Context, reader io.Reader) (string, error) {
+	reader = contextReader{ctx: ctx, reader: reader}
+	limit := maxDecompressedSize.Load()
+	if limit > 0 {
+		reader = io.LimitReader(reader, limit+1)
+	}
+	buf, err := io.ReadAll(reader)
+	if err != nil {
+		return "", err
+	}
+	if limit > 0 && int64(len(buf)) > limit {
+		return "", fmt.Errorf("%w (limit %d bytes)", errDecompressedTooLarge, limit)
+	}
+	return string(buf), nil
+}
+
+// SetMaxDecompressedSize sets the size cap for decompressed subscription
+// payloads in bytes. 0 removes the cap.
+//
+//export SetMaxDecompressedSize
+func SetMaxDecompressedSize(size C.longlong) {
+	defer recoverPanic()
+	maxDecompressedSize.Store(max(int64(size), 0))
+}
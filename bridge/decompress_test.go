@@ -0,0 +1,163 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"context"
+	"encoding/base64"
+	"errors"
+	"io"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+)
+
+// compressors write data in each format decompressPayload detects
+var compressors = map[string]func(io.Writer) io.WriteCloser{
+	"gzip": func(w io.Writer) io.WriteCloser { return gzip.NewWriter(w) },
+	"zlib": func(w io.Writer) io.WriteCloser { return zlib.NewWriter(w) },
+	"zstd": func(w io.Writer) io.WriteCloser {
+		encoder, _ := zstd.NewWriter(w)
+		return encoder
+	},
+	"brotli": func(w io.Writer) io.WriteCloser { return brotli.NewWriter(w) },
+}
+
+func compressString(t *testing.T, format, data string) string {
+	t.Helper()
+	var buf bytes.Buffer
+	w := compressors[format](&buf)
+	if _, err := io.WriteString(w, data); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.String()
+}
+
+// setMaxDecompressedSize changes the size cap for the rest of the test
+func setMaxDecompressedSize(t *testing.T, size int64) {
+	previous := maxDecompressedSize.Load()
+	maxDecompressedSize.Store(size)
+	t.Cleanup(func() { maxDecompressedSize.Store(previous) })
+}
+
+func TestDecompressPayload(t *testing.T) {
+	const links = "trojan://pw@a.com:443#A\nvmess://x@b.com:1#B\n"
+
+	for _, format := range []string{"gzip", "zlib", "zstd", "brotli"} {
+		t.Run(format, func(t *testing.T) {
+			text, got, err := decompressPayload(context.Background(), compressString(t, format, links))
+			if err != nil || text != links || got != format {
+				t.Errorf("decompressPayload = %q, %q, %v, want %q, %q", text, got, err, links, format)
+			}
+		})
+	}
+
+	kept := []struct {
+		name string
+		data string
+	}{
+		{name: "plain links", data: links},
+		// "x^" is a valid zlib header, but the content is text
+		{name: "text with a zlib header", data: "x^ plain text"},
+		{name: "GBK text", data: "\xc4\xe3\xba\xc3 HK"},
+		{name: "binary that is not brotli", data: "\x00\x01\x02\x03"},
+	}
+	for _, tt := range kept {
+		t.Run(tt.name, func(t *testing.T) {
+			text, format, err := decompressPayload(context.Background(), tt.data)
+			if err != nil || text != tt.data || format != "" {
+				t.Errorf("decompressPayload = %q, %q, %v, want it kept", text, format, err)
+			}
+		})
+	}
+}
+
+func TestDecompressPayloadErrors(t *testing.T) {
+	gzipped := compressString(t, "gzip", "trojan://pw@a.com:443#A")
+
+	tests := []struct {
+		name string
+		data string
+		want string
+	}{
+		{name: "truncated gzip", data: gzipped[:len(gzipped)-6], want: "invalid gzip payload"},
+		{name: "bad gzip header", data: "\x1f\x8b\x00\x00", want: "invalid gzip payload"},
+		{name: "bad zstd frame", data: "\x28\xb5\x2f\xfd\x00\x00\x00", want: "invalid zstd payload"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, _, err := decompressPayload(context.Background(), tt.data); err == nil || !strings.HasPrefix(err.Error(), tt.want) {
+				t.Errorf("decompressPayload = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestMaxDecompressedSize(t *testing.T) {
+	setMaxDecompressedSize(t, 1<<10)
+	// Compresses to a few dozen bytes but inflates far past the cap
+	bomb := strings.Repeat("trojan://pw@a.com:443#A\n", 1<<12)
+
+	for _, format := range []string{"gzip", "zlib", "zstd", "brotli"} {
+		t.Run(format, func(t *testing.T) {
+			data := compressString(t, format, bomb)
+			_, _, err := decompressPayload(context.Background(), data)
+			if format == "brotli" {
+				// Brotli is only guessed at, so an oversized payload is
+				// kept as it is rather than rejected
+				if err != nil {
+					t.Errorf("decompressPayload = %v, want the payload kept", err)
+				}
+				return
+			}
+			if !errors.Is(err, errDecompressedTooLarge) {
+				t.Errorf("decompressPayload = %v, want %v", err, errDecompressedTooLarge)
+			}
+		})
+	}
+
+	t.Run("at the cap", func(t *testing.T) {
+		data := strings.Repeat("a", 1<<10)
+		if text, _, err := decompressPayload(context.Background(), compressString(t, "gzip", data)); err != nil || text != data {
+			t.Errorf("decompressPayload = %d bytes, %v, want %d bytes", len(text), err, len(data))
+		}
+	})
+
+	t.Run("no cap", func(t *testing.T) {
+		SetMaxDecompressedSize(0)
+		if text, _, err := decompressPayload(context.Background(), compressString(t, "gzip", bomb)); err != nil || text != bomb {
+			t.Errorf("decompressPayload = %d bytes, %v, want %d bytes", len(text), err, len(bomb))
+		}
+	})
+}
+
+func TestDecodeEnvelopeDecompresses(t *testing.T) {
+	const links = "trojan://pw@a.com:443#A\nvmess://x@b.com:1#B"
+	subscription := base64.StdEncoding.EncodeToString([]byte(compressString(t, "gzip", links)))
+
+	got, envelope, err := decodeEnvelope(context.Background(), subscription)
+	if err != nil || got != links || !slices.Equal(envelope.Path, []string{"base64", "gzip"}) {
+		t.Errorf("decodeEnvelope = %q, %v, %v, want %q, [base64 gzip]", got, envelope.Path, err, links)
+	}
+
+	setMaxDecompressedSize(t, 16)
+	if _, _, err := decodeEnvelope(context.Background(), subscription); !errors.Is(err, errDecompressedTooLarge) {
+		t.Errorf("decodeEnvelope over the cap = %v, want %v", err, errDecompressedTooLarge)
+	}
+}
+
+func TestDecompressPayloadStopped(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, _, err := decompressPayload(ctx, compressString(t, "zstd", "trojan://pw@a.com:443#A")); !errors.Is(err, context.Canceled) {
+		t.Errorf("decompressPayload after cancel = %v, want %v", err, context.Canceled)
+	}
+}
@@ -0,0 +1,263 @@
+package main
+
+import "C"
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/metacubex/mihomo/common/convert"
+)
+
+// Line status values reported by DiagnoseSubscription
+const (
+	lineParsed  = "parsed"
+	lineSkipped = "skipped"
+	lineFailed  = "failed"
+)
+
+// lineDiagnostic describes what happened to one line of a subscription
+type lineDiagnostic struct {
+	Line    int    `json:"line"`
+	Scheme  string `json:"scheme,omitempty"`
+	Status  string `json:"status"`
+	Code    string `json:"code,omitempty"`
+	Message string `json:"message,omitempty"`
+	Name    string `json:"name,omitempty"`
+	Format  string `json:"format,omitempty"` // surge, loon, quanx or sip008
+
+	Unsupported []string `json:"unsupported,omitempty"` // Proxy line options that were not mapped
+
+	Rewrites     []linkRewrite `json:"rewrites,omitempty"`      // Encoding fixes applied before parsing
+	OriginalName string        `json:"original_name,omitempty"` // Name before cleanup, if it changed
+	NameFixes    []string      `json:"name_fixes,omitempty"`    // Cleanups applied to the name
+}
+
+type diagnosticSummary struct {
+	Total   int `json:"total"`
+	Parsed  int `json:"parsed"`
+	Skipped int `json:"skipped"`
+	Failed  int `json:"failed"`
+}
+
+type subscriptionDiagnostics struct {
+	Proxies []map[string]any  `json:"proxies"`
+	Lines   []lineDiagnostic  `json:"lines"`
+	Summary diagnosticSummary `json:"summary"`
+
+	Envelope subscriptionEnvelope `json:"envelope"` // How the content was decoded
+}
+
+// diagnosedLine is one line of a subscription with the proxy it yielded.
+// The proxy name is cleaned but not yet de-duplicated.
+type diagnosedLine struct {
+	diag  lineDiagnostic
+	proxy map[string]any
+}
+
+// diagnoseSubscription normalizes and converts a subscription line by
+// line, keeping a record for every line. Line numbers refer to the
+// content after the base64 envelope (if any) has been removed; for SIP008
+// documents they number the servers. The default conversion timeout
+// applies.
+func diagnoseSubscription(subscription string) (convertResult, []string, error) {
+	ctx, cancel, err := conversionContext(0, 0)
+	if err != nil {
+		return convertResult{}, nil, err
+	}
+	defer cancel()
+	return convertSubscriptionEx(ctx, subscription, convertOptions{})
+}
+
+// diagnoseLines converts data line by line, or server by server for a
+// SIP008 document. normalize fixes the encoding of share links first.
+// Once ctx is done it stops and returns the number of lines left out.
+func diagnoseLines(ctx context.Context, data string, normalize bool) ([]diagnosedLine, int) {
+	if doc, ok := decodeSIP008(data); ok {
+		return diagnoseSIP008(ctx, doc)
+	}
+
+	var lines []diagnosedLine
+	input := strings.Split(strings.TrimRight(data, "\n"), "\n")
+	for i, line := range input {
+		if ctx.Err() != nil {
+			return lines, len(input) - i
+		}
+
+		var diag lineDiagnostic
+		var proxy map[string]any
+
+		// Proxy lines may carry "://" in options such as test-url, so they
+		// are recognized before the line is treated as a share link
+		if lineProxy, l, handled, err := parseProxyLine(line); handled {
+			diag = lineDiagnostic{Scheme: l.kind, Status: lineParsed, Format: l.format}
+			var linkErr *linkError
+			if errors.As(err, &linkErr) {
+				diag.Status = lineFailed
+				diag.Code, diag.Message = linkErr.Code, linkErr.Message
+			} else {
+				proxy = lineProxy
+				diag.Unsupported = l.unused()
+			}
+		} else {
+			line = strings.TrimRight(line, " \r")
+			var rewrites []linkRewrite
+			if normalize {
+				line, rewrites = normalizeLink(line)
+			}
+			diag = diagnoseLine(line)
+			diag.Rewrites = rewrites
+			if diag.Status == lineParsed {
+				var code, message string
+				proxy, code, message = convertLine(diag.Scheme, line)
+				if proxy == nil {
+					diag.Status = lineFailed
+					diag.Code, diag.Message = code, message
+				}
+			}
+		}
+		diag.Line = i + 1
+
+		if proxy != nil {
+			nameDiagnostic(&diag, proxy)
+		}
+		lines = append(lines, diagnosedLine{diag: diag, proxy: proxy})
+	}
+	return lines, 0
+}
+
+// nameDiagnostic records the cleaned name of a converted proxy, and the
+// original name if cleaning changed it
+func nameDiagnostic(diag *lineDiagnostic, proxy map[string]any) {
+	name, fixes := cleanProxyMapName(proxy)
+	if len(fixes) > 0 {
+		diag.OriginalName = strings.ToValidUTF8(anyToString(proxy["name"]), "\ufffd")
+		diag.NameFixes = fixes
+	}
+	diag.Name = name
+}
+
+// diagnoseLine classifies a line before it is handed to the converter.
+// Lines that pass are marked parsed and may still fail conversion.
+func diagnoseLine(line string) lineDiagnostic {
+	if strings.TrimSpace(line) == "" {
+		return lineDiagnostic{Status: lineSkipped, Code: "empty_line"}
+	}
+
+	scheme, _, found := strings.Cut(line, "://")
+	if !found {
+		return lineDiagnostic{
+			Status:  lineSkipped,
+			Code:    "not_a_link",
+			Message: "line does not contain a scheme separator",
+		}
+	}
+
+	scheme = strings.ToLower(scheme)
+	if !convertSchemes[scheme] && bridgeLinkParsers[scheme] == nil {
+		return lineDiagnostic{
+			Scheme:  scheme,
+			Status:  lineSkipped,
+			Code:    "unsupported_scheme",
+			Message: fmt.Sprintf("scheme %q is not supported by the converter", scheme),
+		}
+	}
+
+	return lineDiagnostic{Scheme: scheme, Status: lineParsed}
+}
+
+// convertLine converts one share link with the bridge's parsers or
+// ConvertsV2Ray. code and message explain why proxy is nil.
+func convertLine(scheme, line string) (proxy map[string]any, code, message string) {
+	proxy, handled, err := parseBridgeLink(line)
+	if handled {
+		var linkErr *linkError
+		if errors.As(err, &linkErr) {
+			return nil, linkErr.Code, linkErr.Message
+		}
+		return proxy, "", ""
+	}
+
+	proxies, err := convert.ConvertsV2Ray([]byte(line))
+	if err != nil || len(proxies) == 0 {
+		code, message = explainRejectedLine(scheme, line)
+		return nil, code, message
+	}
+	return proxies[0], "", ""
+}
+
+// explainRejectedLine finds the most likely reason the converter dropped
+// a line, since ConvertsV2Ray itself does not say
+func explainRejectedLine(scheme, line string) (code, message string) {
+	_, body, _ := strings.Cut(line, "://")
+
+	switch scheme {
+	case "ssr":
+		if _, err := convert.TryDecodeBase64(body); err != nil {
+			return "invalid_base64", "ssr link body is not valid base64"
+		}
+		return "invalid_format", "ssr link does not match host:port:protocol:method:obfs:password/?params"
+	case "vmess":
+		if _, err := convert.TryDecodeBase64(body); err == nil {
+			return "invalid_json", "vmess link body is not a valid v2rayN JSON object with a \"ps\" field"
+		}
+	}
+
+	u, err := url.Parse(line)
+	if err != nil {
+		return "invalid_url", err.Error()
+	}
+	if u.Hostname() == "" {
+		return "missing_server", "link has no server address"
+	}
+	if u.Port() == "" && scheme != "hysteria2" && scheme != "hy2" && scheme != "ss" {
+		return "missing_port", "link has no port"
+	}
+	if ed := u.Query().Get("ed"); ed != "" {
+		if _, err := strconv.Atoi(ed); err != nil {
+			return "invalid_param", fmt.Sprintf("bad WebSocket max early data size %q", ed)
+		}
+	}
+	if scheme == "ss" {
+		return "invalid_credentials", "cannot decode ss method and password"
+	}
+
+	return "rejected", "link was rejected by the mihomo converter"
+}
+
+// uniqueProxyName numbers repeated names like ConvertsV2Ray ("A",
+// "A-01", "A-02"). Generated names are reserved as well, so a later
+// proxy that is itself named "A-01" becomes "A-01-01" instead of a second
+// "A-01".
+func uniqueProxyName(names map[string]int, name string) string {
+	index, taken := names[name]
+	if !taken {
+		names[name] = 0
+		return name
+	}
+	for {
+		index++
+		unique := fmt.Sprintf("%s-%02d", name, index)
+		if _, taken := names[unique]; !taken {
+			names[name] = index
+			names[unique] = 0
+			return unique
+		}
+	}
+}
+
+// DiagnoseSubscription converts subscription links like ConvertSubscription
+// and additionally reports the outcome of every input line
+//
+//export DiagnoseSubscription
+func DiagnoseSubscription(data *C.char) *C.char {
+	return respond("DiagnoseSubscription", func() (any, []string, error) {
+		if data == nil {
+			return nil, nil, errNullInput
+		}
+		return diagnoseSubscription(C.GoString(data))
+	})
+}
@@ -0,0 +1,196 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// maxEnvelopeDepth limits how many nested base64 layers are unwrapped
+const maxEnvelopeDepth = 4
+
+// subscriptionEnvelope records how a subscription was unwrapped. Path
+// lists the decoding steps, outermost first; it is empty for plain text.
+type subscriptionEnvelope struct {
+	Path  []string `json:"path"`
+	Lines []int    `json:"lines,omitempty"` // lines decoded one by one (mixed content)
+}
+
+// decodeEnvelope removes the envelope of a subscription. Besides what
+// mihomo's DecodeBase64 accepts, it handles URL-safe and unpadded base64,
+// line wrapping, stray whitespace, a BOM, nested base64 and base64 lines
+// mixed with plain links. Every layer is decompressed (gzip, zlib, zstd,
+// brotli) and transcoded to UTF-8 (UTF-16, GBK, Big5) as needed. Base64
+// is only unwrapped when it ends in share links, so plain text is never
+// mangled. Once ctx is done it stops with ctx's error.
+func decodeEnvelope(ctx context.Context, subscription string) (string, subscriptionEnvelope, error) {
+	envelope := subscriptionEnvelope{Path: make([]string, 0)}
+
+	subscription, steps, err := decodeLayer(ctx, subscription)
+	if err != nil {
+		return "", envelope, err
+	}
+	envelope.Path = append(envelope.Path, steps...)
+
+	text, steps, err := unwrapBase64(ctx, subscription, 0)
+	if err != nil {
+		return "", envelope, err
+	}
+	if steps != nil {
+		subscription = text
+		envelope.Path = append(envelope.Path, steps...)
+	}
+
+	// Some providers concatenate base64 blobs and plain links
+	lines := strings.Split(subscription, "\n")
+	for i, line := range lines {
+		if ctx.Err() != nil {
+			return "", envelope, ctx.Err()
+		}
+		line = strings.TrimSpace(line)
+		if line == "" || strings.Contains(line, "://") {
+			continue
+		}
+		text, steps, err := unwrapBase64(ctx, line, 0)
+		if ctx.Err() != nil {
+			return "", envelope, ctx.Err()
+		}
+		if err != nil {
+			return "", envelope, fmt.Errorf("line %d: %w", i+1, err)
+		}
+		if steps != nil {
+			lines[i] = strings.TrimRight(text, "\r\n")
+			envelope.Lines = append(envelope.Lines, i+1)
+		}
+	}
+	if len(envelope.Lines) > 0 {
+		subscription = strings.Join(lines, "\n")
+		envelope.Path = append(envelope.Path, "mixed")
+	}
+
+	return subscription, envelope, nil
+}
+
+// decodeLayer decompresses and transcodes one layer of content and strips
+// its BOM, returning the steps taken
+func decodeLayer(ctx context.Context, content string) (string, []string, error) {
+	var steps []string
+	content, format, err := decompressPayload(ctx, content)
+	if err != nil {
+		return "", nil, err
+	}
+	if format != "" {
+		steps = append(steps, format)
+	}
+	content, charset := transcodeText(content)
+	if charset != "" {
+		steps = append(steps, charset)
+	}
+	if text, found := strings.CutPrefix(content, "\ufeff"); found {
+		content = text
+		steps = append(steps, "bom")
+	}
+	return content, steps, nil
+}
+
+// unwrapBase64 decodes text as base64, layer by layer, until it yields
+// share links. steps is nil if text is not base64 or decodes to something
+// other than links.
+func unwrapBase64(ctx context.Context, text string, depth int) (string, []string, error) {
+	if depth >= maxEnvelopeDepth {
+		return "", nil, nil
+	}
+
+	compact := strings.Map(func(r rune) rune {
+		if unicode.IsSpace(r) || r == '\ufeff' {
+			return -1
+		}
+		return r
+	}, text)
+	encoding, step, ok := detectBase64(compact)
+	if !ok {
+		return "", nil, nil
+	}
+	buf, err := encoding.DecodeString(strings.TrimRight(compact, "="))
+	if err != nil {
+		return "", nil, nil
+	}
+
+	var steps []string
+	if strings.Contains(strings.TrimSpace(text), "\n") {
+		steps = append(steps, "unwrap")
+	}
+	steps = append(steps, step)
+
+	decoded, layerSteps, err := decodeLayer(ctx, string(buf))
+	if err != nil {
+		return "", nil, err
+	}
+	steps = append(steps, layerSteps...)
+	if !isPlainText(decoded) {
+		return "", nil, nil
+	}
+	if strings.Contains(decoded, "://") {
+		return decoded, steps, nil
+	}
+	inner, innerSteps, err := unwrapBase64(ctx, decoded, depth+1)
+	if err != nil || innerSteps == nil {
+		return "", nil, err
+	}
+	return inner, append(steps, innerSteps...), nil
+}
+
+// detectBase64 picks the base64 alphabet of s. Padding is stripped before
+// decoding, so the unpadded encodings are used for both forms.
+func detectBase64(s string) (*base64.Encoding, string, bool) {
+	trimmed := strings.TrimRight(s, "=")
+	if trimmed == "" || len(s)-len(trimmed) > 2 || len(trimmed)%4 == 1 {
+		return nil, "", false
+	}
+
+	standard, urlSafe := false, false
+	for i := 0; i < len(trimmed); i++ {
+		switch c := trimmed[i]; {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9':
+		case c == '+' || c == '/':
+			standard = true
+		case c == '-' || c == '_':
+			urlSafe = true
+		default:
+			return nil, "", false
+		}
+	}
+
+	padded := len(trimmed) != len(s) || len(trimmed)%4 == 0
+	switch {
+	case standard && urlSafe:
+		return nil, "", false
+	case urlSafe:
+		return base64.RawURLEncoding, paddingStep("base64url", padded), true
+	}
+	return base64.RawStdEncoding, paddingStep("base64", padded), true
+}
+
+func paddingStep(name string, padded bool) string {
+	if padded {
+		return name
+	}
+	return name + "-unpadded"
+}
+
+// isPlainText reports whether decoded content looks like a text subscription
+// rather than binary data
+func isPlainText(s string) bool {
+	if !utf8.ValidString(s) {
+		return false
+	}
+	for _, r := range s {
+		if r < 0x20 && r != '\n' && r != '\r' && r != '\t' {
+			return false
+		}
+	}
+	return true
+}
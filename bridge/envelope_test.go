@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"slices"
+	"testing"
+)
+
+func TestDecodeEnvelope(t *testing.T) {
+	const links = "trojan://pw@a.com:443#A\nvmess://x@b.com:1#B\n"
+	// Ends in "?>" so that its URL-safe encoding differs from the standard one
+	const urlSafeLinks = links + "?>"
+
+	tests := []struct {
+		name         string
+		subscription string
+		want         string
+		path         []string
+		lines        []int
+	}{
+		{
+			name:         "plain links",
+			subscription: links,
+			want:         links,
+			path:         []string{},
+		},
+		{
+			name:         "base64",
+			subscription: base64.StdEncoding.EncodeToString([]byte(links)),
+			want:         links,
+			path:         []string{"base64"},
+		},
+		{
+			name:         "unpadded base64url",
+			subscription: base64.RawURLEncoding.EncodeToString([]byte(urlSafeLinks)),
+			want:         urlSafeLinks,
+			path:         []string{"base64url-unpadded"},
+		},
+		{
+			name:         "base64 twice",
+			subscription: base64.StdEncoding.EncodeToString([]byte(base64.StdEncoding.EncodeToString([]byte(links)))),
+			want:         links,
+			path:         []string{"base64", "base64"},
+		},
+		{
+			name:         "blob mixed with links",
+			subscription: "trojan://pw@c.com:443#C\n" + base64.StdEncoding.EncodeToString([]byte(links)),
+			want:         "trojan://pw@c.com:443#C\ntrojan://pw@a.com:443#A\nvmess://x@b.com:1#B",
+			path:         []string{"mixed"},
+			lines:        []int{2},
+		},
+		{
+			name:         "byte order mark",
+			subscription: "\ufefftrojan://pw@a.com:443#A",
+			want:         "trojan://pw@a.com:443#A",
+			path:         []string{"bom"},
+		},
+		{
+			name:         "base64 of plain text is kept",
+			subscription: "aGVsbG8gd29ybGQ=",
+			want:         "aGVsbG8gd29ybGQ=",
+			path:         []string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, envelope, err := decodeEnvelope(context.Background(), tt.subscription)
+			if err != nil {
+				t.Fatalf("decodeEnvelope: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("decodeEnvelope = %q, want %q", got, tt.want)
+			}
+			if !slices.Equal(envelope.Path, tt.path) || !slices.Equal(envelope.Lines, tt.lines) {
+				t.Errorf("envelope = %v, lines %v, want %v, lines %v", envelope.Path, envelope.Lines, tt.path, tt.lines)
+			}
+
+			// Decoding the result again finds no further envelope
+			again, envelope, err := decodeEnvelope(context.Background(), got)
+			if err != nil || again != got || len(envelope.Path) > 0 {
+				t.Errorf("decodeEnvelope(%q) = %q, %v, %v, want it unchanged", got, again, envelope.Path, err)
+			}
+		})
+	}
+}
+
+func TestDecodeEnvelopeStopped(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	subscription := base64.StdEncoding.EncodeToString([]byte("trojan://pw@a.com:443#A"))
+	if _, _, err := decodeEnvelope(ctx, subscription); !isStopped(ctx, err) {
+		t.Errorf("decodeEnvelope after cancel = %v, want %v", err, context.Canceled)
+	}
+}
@@ -0,0 +1,99 @@
+package main
+
+import "C"
+import (
+	"maps"
+	"runtime"
+	"runtime/debug"
+	"slices"
+)
+
+// bridgeAPIVersion is bumped whenever an export is added or changes its
+// arguments or data
+const bridgeAPIVersion = 4
+
+const mihomoModule = "github.com/metacubex/mihomo"
+
+// schemeAliases maps alternative link schemes to the scheme they are
+// parsed as
+var schemeAliases = map[string]string{
+	"hy2":       "hysteria2",
+	"socks":     "socks5",
+	"socks5h":   "socks5",
+	"https":     "http",
+	"wg":        "wireguard",
+	"mierus":    "mieru",
+	"socks+tls": "socks5+tls",
+}
+
+// proxyTransports lists the network values mihomo's outbounds accept for
+// each proxy type with pluggable transports. "tcp" is the default;
+// HTTPUpgrade is ws with ws-opts.v2ray-http-upgrade set.
+var proxyTransports = map[string][]string{
+	"vmess":  {"tcp", "http", "h2", "ws", "grpc"},
+	"vless":  {"tcp", "http", "h2", "ws", "grpc"},
+	"trojan": {"tcp", "ws", "grpc"},
+}
+
+// bridgeInfo describes the linked parser, so generated configs can be
+// traced back to the exact mihomo release that produced them
+type bridgeInfo struct {
+	MihomoVersion   string              `json:"mihomo_version"`
+	GoVersion       string              `json:"go_version"`
+	APIVersion      int                 `json:"api_version"`
+	ResponseVersion int                 `json:"response_version"`
+	Schemes         []string            `json:"schemes"`
+	Aliases         map[string]string   `json:"aliases"`
+	Transports      map[string][]string `json:"transports"`
+	Formats         []string            `json:"formats"` // Formats detected by ParseAny
+}
+
+// mihomoVersion reads the version of the linked mihomo module from the
+// build info, following a replace directive if there is one
+func mihomoVersion() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return "unknown"
+	}
+	for _, dep := range info.Deps {
+		if dep.Path != mihomoModule {
+			continue
+		}
+		if dep.Replace != nil {
+			if dep.Replace.Version == "" {
+				return dep.Replace.Path
+			}
+			return dep.Replace.Path + " " + dep.Replace.Version
+		}
+		return dep.Version
+	}
+	return "unknown"
+}
+
+func newBridgeInfo() bridgeInfo {
+	schemes := slices.Collect(maps.Keys(convertSchemes))
+	schemes = slices.AppendSeq(schemes, maps.Keys(bridgeLinkParsers))
+	slices.Sort(schemes)
+
+	return bridgeInfo{
+		MihomoVersion:   mihomoVersion(),
+		GoVersion:       runtime.Version(),
+		APIVersion:      bridgeAPIVersion,
+		ResponseVersion: responseVersion,
+		Schemes:         slices.Compact(schemes),
+		Aliases:         schemeAliases,
+		Transports:      proxyTransports,
+		Formats: []string{formatBase64, formatLinks, formatProxyLines, formatClash,
+			formatSingBox, formatXray, formatSIP008, formatWireGuard},
+	}
+}
+
+// BridgeInfo reports the linked mihomo version, the bridge API version
+// and the schemes, aliases and transports the bridge supports
+//
+//export BridgeInfo
+func BridgeInfo() *C.char {
+	return respond("BridgeInfo", func() (any, []string, error) {
+		return newBridgeInfo(), nil, nil
+	})
+}
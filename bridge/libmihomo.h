@@ -21,6 +21,8 @@ extern const char *_GoStringPtr(_GoString_ s);
 /* Start of preamble from import "C" comments.  */
 
 
+
+
 #line 3 "converter.go"
 
 #include <stdlib.h>
@@ -28,6 +30,22 @@ extern const char *_GoStringPtr(_GoString_ s);
 #line 1 "cgo-generated-wrapper"
 
 
+
+
+
+
+
+
+
+
+
+
+
+
+
+
+
+
 /* End of preamble from import "C" comments.  */
 
 
@@ -87,8 +105,34 @@ typedef struct { void *data; GoInt len; GoInt cap; } GoSlice;
 extern "C" {
 #endif
 
+extern long long int CreateCancelHandle(void);
+extern void CancelConversion(long long int handle);
+extern void ReleaseCancelHandle(long long int handle);
+extern void SetConversionTimeout(long long int ms);
+extern char* ValidateConfig(char* data);
 extern char* ConvertSubscription(char* data);
 extern void FreeString(char* s);
+extern char* ConvertSubscriptionEx(char* data, char* options);
+extern void SetMaxDecompressedSize(long long int size);
+extern char* DiagnoseSubscription(char* data);
+extern char* BridgeInfo(void);
+extern char* ParamCompat(void);
+extern char* ParseProvider(char* data, char* options);
+extern char* ValidateRules(char* data, char* options);
+extern char* ConvertRuleSetToMrs(char* data, char* options);
+extern char* ConvertMrsToRuleSet(char* data);
+extern long long int CreateSession(void);
+extern char* CreateSessionEx(char* options);
+extern char* FeedSession(long long int handle, char* source, int group, char* data);
+extern char* FinalizeSession(long long int handle);
+extern void CloseSession(long long int handle);
+extern char* ExportShareLinks(char* data);
+extern char* ExportSingBox(char* data);
+extern char* ImportSingBox(char* data);
+extern char* ParseAny(char* data, char* options);
+extern char* ValidateProxies(char* data);
+extern char* ImportWireGuardConf(char* data);
+extern char* ImportXray(char* data);
 
 #ifdef __cplusplus
 }
@@ -0,0 +1,209 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"net/url"
+	"strings"
+)
+
+// linkRewrite records one change made to a share link before it is
+// handed to the converter
+type linkRewrite struct {
+	Component string `json:"component"` // link, userinfo, path, query or fragment
+	Before    string `json:"before"`
+	After     string `json:"after"`
+}
+
+// userinfoMode describes how the converter reads the userinfo of a scheme
+type userinfoMode int
+
+const (
+	userinfoSingle userinfoMode = iota + 1 // one credential from User.Username()
+	userinfoPair                           // user:password from Username()/Password()
+	userinfoRaw                            // still escaped, from User.String()
+)
+
+var userinfoModes = map[string]userinfoMode{
+	"tuic": userinfoPair, "ss": userinfoPair,
+	"trojan": userinfoSingle, "vless": userinfoSingle, "vmess": userinfoSingle,
+	"anytls":    userinfoSingle,
+	"hysteria2": userinfoRaw, "hy2": userinfoRaw, "socks": userinfoRaw,
+	"socks5": userinfoRaw, "socks5h": userinfoRaw, "http": userinfoRaw,
+	"https":     userinfoRaw,
+	"wireguard": userinfoSingle, "wg": userinfoSingle, "snell": userinfoSingle,
+	"ssh": userinfoPair, "mieru": userinfoPair, "mierus": userinfoPair,
+	"socks5+tls": userinfoPair, "socks+tls": userinfoPair,
+}
+
+// preprocessSubscription removes the envelope (if any) and normalizes
+// every share link in the subscription. Surge, Loon and Quantumult X proxy
+// lines and SIP008 documents are kept as they are. Once ctx is done it
+// stops with ctx's error.
+func preprocessSubscription(ctx context.Context, subscription string) (string, error) {
+	data, _, err := decodeEnvelope(ctx, subscription)
+	if err != nil {
+		return "", err
+	}
+	if isSIP008(data) {
+		return data, nil
+	}
+	lines := strings.Split(data, "\n")
+	for i, line := range lines {
+		if ctx.Err() != nil {
+			return "", ctx.Err()
+		}
+		if isProxyLine(line) {
+			continue
+		}
+		lines[i], _ = normalizeLink(strings.TrimRight(line, " \r"))
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+// normalizeLink fixes the encoding of a share link one URL component at
+// a time, so that mihomo's converter reads the values the link meant.
+// Links whose body is not a URL (ssr, v2rayN vmess) are left unchanged.
+func normalizeLink(line string) (string, []linkRewrite) {
+	var rewrites []linkRewrite
+	rewrite := func(component, before, after string) string {
+		if before != after {
+			rewrites = append(rewrites, linkRewrite{Component: component, Before: before, After: after})
+		}
+		return after
+	}
+
+	// Some exporters percent-encode the whole link, scheme included
+	if !strings.Contains(line, "://") && strings.Contains(strings.ToLower(line), "%3a%2f%2f") {
+		decoded, _ := url.PathUnescape(escapeStrayPercent(line))
+		line = rewrite("link", line, decoded)
+	}
+
+	prefix, rest, found := strings.Cut(line, "://")
+	if !found {
+		return line, rewrites
+	}
+	scheme := strings.ToLower(prefix)
+	if scheme == "ssr" || (scheme == "vmess" && isBase64Body(rest)) {
+		return line, rewrites
+	}
+
+	rest, fragment, hasFragment := strings.Cut(rest, "#")
+	rest, query, hasQuery := strings.Cut(rest, "?")
+	authority, path := rest, ""
+	if i := strings.IndexByte(rest, '/'); i >= 0 {
+		authority, path = rest[:i], rest[i:]
+	}
+	userinfo, host := "", authority
+	hasUserinfo := strings.Contains(authority, "@")
+	if hasUserinfo {
+		i := strings.LastIndexByte(authority, '@')
+		userinfo, host = authority[:i], authority[i+1:]
+		userinfo = rewrite("userinfo", userinfo, normalizeUserinfo(userinfo, userinfoModes[scheme]))
+	}
+	path = rewrite("path", path, escapeStrayPercent(path))
+	// Share links encode query values with encodeURIComponent, so a bare
+	// "+" is a literal plus and not the space url.ParseQuery turns it into
+	query = rewrite("query", query, strings.ReplaceAll(escapeStrayPercent(query), "+", "%2B"))
+	fragment = rewrite("fragment", fragment, escapeStrayPercent(fragment))
+
+	var b strings.Builder
+	b.WriteString(prefix + "://")
+	if hasUserinfo {
+		b.WriteString(userinfo + "@")
+	}
+	b.WriteString(host + path)
+	if hasQuery {
+		b.WriteString("?" + query)
+	}
+	if hasFragment {
+		b.WriteString("#" + fragment)
+	}
+	return b.String(), rewrites
+}
+
+// normalizeUserinfo re-encodes userinfo so that the converter reads the
+// decoded credentials, e.g. v2rayN's "uuid%3Apassword" for tuic. It is
+// left as is when it already reads correctly.
+func normalizeUserinfo(userinfo string, mode userinfoMode) string {
+	decoded, _ := url.PathUnescape(escapeStrayPercent(userinfo))
+	current, err := url.Parse("scheme://" + userinfo + "@host")
+
+	var want *url.Userinfo
+	switch mode {
+	case userinfoPair:
+		if user, password, ok := strings.Cut(decoded, ":"); ok {
+			want = url.UserPassword(user, password)
+		} else {
+			want = url.User(decoded)
+		}
+	case userinfoSingle:
+		want = url.User(decoded)
+	case userinfoRaw:
+		// The converter uses the escaped form, which only reads right
+		// when the credentials need no escaping at all
+		if err == nil && current.User.String() == decoded {
+			return userinfo
+		}
+		if !isPlainUserinfo(decoded) {
+			return userinfo
+		}
+		return decoded
+	default:
+		return userinfo
+	}
+
+	if err == nil && current.User.Username() == want.Username() {
+		currentPassword, currentSet := current.User.Password()
+		wantPassword, wantSet := want.Password()
+		if currentPassword == wantPassword && currentSet == wantSet {
+			return userinfo
+		}
+	}
+	return want.String()
+}
+
+// isPlainUserinfo reports whether s is valid userinfo as it stands
+func isPlainUserinfo(s string) bool {
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' {
+			continue
+		}
+		if !strings.ContainsRune("-._~!$&'()*+,;=:", rune(c)) {
+			return false
+		}
+	}
+	return true
+}
+
+// escapeStrayPercent escapes "%" signs that do not start a %XX sequence,
+// which would otherwise make url.Parse reject the whole link
+func escapeStrayPercent(s string) string {
+	if !strings.Contains(s, "%") {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '%' && (i+2 >= len(s) || !isHex(s[i+1]) || !isHex(s[i+2])) {
+			b.WriteString("%25")
+			continue
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
+
+func isHex(c byte) bool {
+	return '0' <= c && c <= '9' || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F'
+}
+
+// isBase64Body reports whether a link body is base64, as the converter
+// checks for v2rayN vmess links
+func isBase64Body(body string) bool {
+	if _, err := base64.RawStdEncoding.DecodeString(body); err == nil {
+		return true
+	}
+	_, err := base64.StdEncoding.DecodeString(body)
+	return err == nil
+}
@@ -0,0 +1,123 @@
+package main
+
+import "C"
+import (
+	"encoding"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/metacubex/mihomo/adapter/outbound"
+)
+
+// proxyOptionTypes maps every proxy type adapter.ParseProxy accepts to
+// the option struct it decodes the proxy into
+var proxyOptionTypes = map[string]reflect.Type{
+	"ss":        reflect.TypeFor[outbound.ShadowSocksOption](),
+	"ssr":       reflect.TypeFor[outbound.ShadowSocksROption](),
+	"socks5":    reflect.TypeFor[outbound.Socks5Option](),
+	"http":      reflect.TypeFor[outbound.HttpOption](),
+	"vmess":     reflect.TypeFor[outbound.VmessOption](),
+	"vless":     reflect.TypeFor[outbound.VlessOption](),
+	"snell":     reflect.TypeFor[outbound.SnellOption](),
+	"trojan":    reflect.TypeFor[outbound.TrojanOption](),
+	"hysteria":  reflect.TypeFor[outbound.HysteriaOption](),
+	"hysteria2": reflect.TypeFor[outbound.Hysteria2Option](),
+	"wireguard": reflect.TypeFor[outbound.WireGuardOption](),
+	"tuic":      reflect.TypeFor[outbound.TuicOption](),
+	"direct":    reflect.TypeFor[outbound.DirectOption](),
+	"dns":       reflect.TypeFor[outbound.DnsOption](),
+	"reject":    reflect.TypeFor[outbound.RejectOption](),
+	"ssh":       reflect.TypeFor[outbound.SshOption](),
+	"mieru":     reflect.TypeFor[outbound.MieruOption](),
+	"anytls":    reflect.TypeFor[outbound.AnyTLSOption](),
+	"sudoku":    reflect.TypeFor[outbound.SudokuOption](),
+	"masque":    reflect.TypeFor[outbound.MasqueOption](),
+}
+
+// smuxParam is decoded by adapter.ParseProxy for every proxy type
+const smuxParam = "smux"
+
+var textUnmarshalerType = reflect.TypeFor[encoding.TextUnmarshaler]()
+
+// proxyParam is one top-level key of a proxy
+type proxyParam struct {
+	Type     string `json:"type"`     // bool, int, float, string, array or object
+	Optional bool   `json:"optional"` // Tagged omitempty
+	Source   string `json:"source"`   // Option struct that declares the key
+}
+
+// proxyParams reflects over the option structs of the linked mihomo and
+// returns the keys each proxy type accepts, keyed by proxy type
+func proxyParams() map[string]map[string]proxyParam {
+	table := make(map[string]map[string]proxyParam, len(proxyOptionTypes))
+	smux := reflect.TypeFor[outbound.SingMuxOption]()
+	for proxyType, optionType := range proxyOptionTypes {
+		params := make(map[string]proxyParam)
+		collectProxyParams(optionType, params)
+		params[smuxParam] = proxyParam{Type: "object", Optional: true, Source: smux.Name()}
+		table[proxyType] = params
+	}
+	return table
+}
+
+// proxyParamTable is proxyParams computed once, since the option structs
+// cannot change at runtime. The table must not be modified.
+var proxyParamTable = sync.OnceValue(proxyParams)
+
+// collectProxyParams adds the proxy-tagged fields of t to params,
+// flattening embedded structs such as BasicOption
+func collectProxyParams(t reflect.Type, params map[string]proxyParam) {
+	for i := range t.NumField() {
+		field := t.Field(i)
+		tag, ok := field.Tag.Lookup("proxy")
+		if !ok && field.Anonymous && field.Type.Kind() == reflect.Struct {
+			collectProxyParams(field.Type, params)
+			continue
+		}
+		name, flags, _ := strings.Cut(tag, ",")
+		if name == "" || name == "-" {
+			continue
+		}
+		params[name] = proxyParam{
+			Type:     proxyParamType(field.Type),
+			Optional: strings.Contains(","+flags+",", ",omitempty,"),
+			Source:   t.Name(),
+		}
+	}
+}
+
+// proxyParamType names the value a field is decoded from. Types that
+// unmarshal themselves from text (C.DNSPrefer...) are strings.
+func proxyParamType(t reflect.Type) string {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if reflect.PointerTo(t).Implements(textUnmarshalerType) {
+		return "string"
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		return "bool"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "int"
+	case reflect.Float32, reflect.Float64:
+		return "float"
+	case reflect.Slice, reflect.Array:
+		return "array"
+	case reflect.Map, reflect.Struct, reflect.Interface:
+		return "object"
+	}
+	return "string"
+}
+
+// ParamCompat reports the keys every proxy type of the linked mihomo
+// accepts, with their value types, reflected from its option structs
+//
+//export ParamCompat
+func ParamCompat() *C.char {
+	return respond("ParamCompat", func() (any, []string, error) {
+		return proxyParamTable(), nil, nil
+	})
+}
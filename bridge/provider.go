@@ -0,0 +1,266 @@
+package main
+
+import "C"
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/dlclark/regexp2"
+	"github.com/metacubex/mihomo/common/convert"
+	"github.com/metacubex/mihomo/common/structure"
+	"github.com/metacubex/mihomo/common/yaml"
+)
+
+// providerOptions holds the proxy-provider keys that affect which proxies
+// a provider yields. Field tags follow mihomo's proxyProviderSchema.
+type providerOptions struct {
+	Filter        string                 `provider:"filter,omitempty"`
+	ExcludeFilter string                 `provider:"exclude-filter,omitempty"`
+	ExcludeType   string                 `provider:"exclude-type,omitempty"`
+	DialerProxy   string                 `provider:"dialer-proxy,omitempty"`
+	Override      providerOverrideSchema `provider:"override,omitempty"`
+}
+
+// providerOverrideSchema mirrors mihomo's (unexported) overrideSchema
+type providerOverrideSchema struct {
+	TFO            *bool   `provider:"tfo,omitempty"`
+	MPTcp          *bool   `provider:"mptcp,omitempty"`
+	UDP            *bool   `provider:"udp,omitempty"`
+	UDPOverTCP     *bool   `provider:"udp-over-tcp,omitempty"`
+	Up             *string `provider:"up,omitempty"`
+	Down           *string `provider:"down,omitempty"`
+	DialerProxy    *string `provider:"dialer-proxy,omitempty"`
+	SkipCertVerify *bool   `provider:"skip-cert-verify,omitempty"`
+	Interface      *string `provider:"interface-name,omitempty"`
+	RoutingMark    *int    `provider:"routing-mark,omitempty"`
+	IPVersion      *string `provider:"ip-version,omitempty"`
+
+	AdditionalPrefix *string                           `provider:"additional-prefix,omitempty"`
+	AdditionalSuffix *string                           `provider:"additional-suffix,omitempty"`
+	ProxyName        []providerOverrideProxyNameSchema `provider:"proxy-name,omitempty"`
+}
+
+type providerOverrideProxyNameSchema struct {
+	Pattern *regexp2.Regexp `provider:"pattern"`
+	Target  string          `provider:"target"`
+}
+
+func (o *providerOverrideSchema) apply(mapping map[string]any) error {
+	if o.TFO != nil {
+		mapping["tfo"] = *o.TFO
+	}
+	if o.MPTcp != nil {
+		mapping["mptcp"] = *o.MPTcp
+	}
+	if o.UDP != nil {
+		mapping["udp"] = *o.UDP
+	}
+	if o.UDPOverTCP != nil {
+		mapping["udp-over-tcp"] = *o.UDPOverTCP
+	}
+	if o.Up != nil {
+		mapping["up"] = *o.Up
+	}
+	if o.Down != nil {
+		mapping["down"] = *o.Down
+	}
+	if o.DialerProxy != nil {
+		mapping["dialer-proxy"] = *o.DialerProxy
+	}
+	if o.SkipCertVerify != nil {
+		mapping["skip-cert-verify"] = *o.SkipCertVerify
+	}
+	if o.Interface != nil {
+		mapping["interface"] = *o.Interface
+	}
+	if o.RoutingMark != nil {
+		mapping["routing-mark"] = *o.RoutingMark
+	}
+	if o.IPVersion != nil {
+		mapping["ip-version"] = *o.IPVersion
+	}
+
+	for _, expr := range o.ProxyName {
+		name := mapping["name"].(string)
+		newName, err := expr.Pattern.Replace(name, expr.Target, 0, -1)
+		if err != nil {
+			return fmt.Errorf("proxy name replace error: %w", err)
+		}
+		mapping["name"] = newName
+	}
+	if o.AdditionalPrefix != nil {
+		mapping["name"] = fmt.Sprintf("%s%s", *o.AdditionalPrefix, mapping["name"])
+	}
+	if o.AdditionalSuffix != nil {
+		mapping["name"] = fmt.Sprintf("%s%s", mapping["name"], *o.AdditionalSuffix)
+	}
+
+	return nil
+}
+
+// providerResult is what a proxy-provider would load from a payload
+type providerResult struct {
+	Format  string           `json:"format"`
+	Proxies []map[string]any `json:"proxies"`
+}
+
+// decodeProviderOptions reads the optional JSON options of ParseProvider
+// with the same decoder mihomo uses for proxy-providers
+func decodeProviderOptions(data string) (*providerOptions, error) {
+	options := &providerOptions{}
+	if strings.TrimSpace(data) == "" {
+		return options, nil
+	}
+
+	var mapping map[string]any
+	if err := json.Unmarshal([]byte(data), &mapping); err != nil {
+		return nil, fmt.Errorf("invalid provider options: %w", err)
+	}
+	decoder := structure.NewDecoder(structure.Option{TagName: "provider", WeaklyTypedInput: true})
+	if err := decoder.Decode(mapping, options); err != nil {
+		return nil, fmt.Errorf("invalid provider options: %w", err)
+	}
+	return options, nil
+}
+
+// parseProvider follows the parser returned by mihomo's
+// provider.NewProxiesParser step by step, but keeps the proxy maps
+// instead of the instantiated adapters
+func parseProvider(buf []byte, options *providerOptions) (*providerResult, error) {
+	var excludeTypeArray []string
+	if options.ExcludeType != "" {
+		excludeTypeArray = strings.Split(options.ExcludeType, "|")
+	}
+
+	var excludeFilterRegs []*regexp2.Regexp
+	if options.ExcludeFilter != "" {
+		for _, excludeFilter := range strings.Split(options.ExcludeFilter, "`") {
+			excludeFilterReg, err := regexp2.Compile(excludeFilter, regexp2.None)
+			if err != nil {
+				return nil, fmt.Errorf("invalid excludeFilter regex: %w", err)
+			}
+			excludeFilterRegs = append(excludeFilterRegs, excludeFilterReg)
+		}
+	}
+
+	var filterRegs []*regexp2.Regexp
+	for _, filter := range strings.Split(options.Filter, "`") {
+		filterReg, err := regexp2.Compile(filter, regexp2.None)
+		if err != nil {
+			return nil, fmt.Errorf("invalid filter regex: %w", err)
+		}
+		filterRegs = append(filterRegs, filterReg)
+	}
+
+	result := &providerResult{Format: "yaml"}
+	schema := &struct {
+		Proxies []map[string]any `yaml:"proxies"`
+	}{}
+	if err := yaml.Unmarshal(buf, schema); err != nil {
+		proxies, err1 := convert.ConvertsV2Ray(buf)
+		if err1 != nil {
+			return nil, fmt.Errorf("%w, %w", err, err1)
+		}
+		schema.Proxies = proxies
+		result.Format = "links"
+		if !bytes.Equal(convert.DecodeBase64(buf), buf) {
+			result.Format = "base64"
+		}
+	}
+
+	if schema.Proxies == nil {
+		return nil, errors.New("file must have a `proxies` field")
+	}
+
+	result.Proxies = make([]map[string]any, 0, len(schema.Proxies))
+	proxiesSet := map[string]struct{}{}
+	for _, filterReg := range filterRegs {
+	LOOP1:
+		for idx, mapping := range schema.Proxies {
+			if len(excludeTypeArray) > 0 {
+				pType, ok := mapping["type"].(string)
+				if !ok {
+					continue
+				}
+				for _, excludeType := range excludeTypeArray {
+					if strings.EqualFold(pType, excludeType) {
+						continue LOOP1
+					}
+				}
+			}
+			name, ok := mapping["name"].(string)
+			if !ok {
+				continue
+			}
+			for _, excludeFilterReg := range excludeFilterRegs {
+				if mat, _ := excludeFilterReg.MatchString(name); mat {
+					continue LOOP1
+				}
+			}
+			if len(options.Filter) > 0 {
+				if mat, _ := filterReg.MatchString(name); !mat {
+					continue
+				}
+			}
+			if _, ok := proxiesSet[name]; ok {
+				continue
+			}
+
+			if len(options.DialerProxy) > 0 {
+				mapping["dialer-proxy"] = options.DialerProxy
+			}
+
+			if err := options.Override.apply(mapping); err != nil {
+				return nil, fmt.Errorf("proxy %d override error: %w", idx, err)
+			}
+
+			if err := validateProxy(mapping); err != nil {
+				return nil, fmt.Errorf("proxy %d error: %w", idx, err)
+			}
+
+			proxiesSet[name] = struct{}{}
+			result.Proxies = append(result.Proxies, mapping)
+		}
+	}
+
+	if len(result.Proxies) == 0 {
+		if len(options.Filter) > 0 {
+			return nil, errors.New("doesn't match any proxy, please check your filter")
+		}
+		return nil, errors.New("file doesn't have any proxy")
+	}
+
+	return result, nil
+}
+
+// ParseProvider parses a proxy-provider payload (Clash YAML, base64 or
+// plain share links) the way mihomo loads a proxy-provider. options is
+// an optional JSON object with provider keys such as filter,
+// exclude-filter, exclude-type, dialer-proxy and override.
+//
+//export ParseProvider
+func ParseProvider(data *C.char, options *C.char) *C.char {
+	return respond("ParseProvider", func() (any, []string, error) {
+		if data == nil {
+			return nil, nil, errNullInput
+		}
+
+		var optionsJSON string
+		if options != nil {
+			optionsJSON = C.GoString(options)
+		}
+		opts, err := decodeProviderOptions(optionsJSON)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		parsed, err := parseProvider([]byte(C.GoString(data)), opts)
+		if err != nil {
+			return nil, nil, err
+		}
+		return parsed, nil, nil
+	})
+}
@@ -0,0 +1,754 @@
+package main
+
+import (
+	"cmp"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// Proxy line formats recognized by parseProxyLine
+const (
+	formatSurge = "surge"
+	formatLoon  = "loon"
+	formatQuanX = "quanx"
+)
+
+// proxyLineBuilders map the protocol keyword of a Surge or Loon line (the
+// first value) or of a Quantumult X line (the key) to the builder of the
+// mihomo proxy
+var proxyLineBuilders = map[string]func(*proxyLine, map[string]any) error{
+	"ss": buildLineShadowsocks, "shadowsocks": buildLineShadowsocks,
+	"custom": buildLineShadowsocks, "shadowsocksr": buildLineShadowsocksR,
+	"vmess": buildLineVMess, "vless": buildLineVLESS, "trojan": buildLineTrojan,
+	"http": buildLineHTTP, "https": buildLineHTTP,
+	"socks5": buildLineSocks, "socks5-tls": buildLineSocks,
+	"snell": buildLineSnell, "tuic": buildLineTUIC, "tuic-v5": buildLineTUIC,
+	"hysteria2": buildLineHysteria2, "anytls": buildLineAnyTLS,
+	"ssh": buildLineSSH, "wireguard": buildLineWireGuard,
+}
+
+// quanXKinds are the proxy keys of the Quantumult X [server_local] section
+var quanXKinds = map[string]bool{
+	"shadowsocks": true, "vmess": true, "vless": true, "trojan": true,
+	"http": true, "socks5": true,
+}
+
+// loonKinds are protocol keywords only Loon uses
+var loonKinds = map[string]bool{"shadowsocks": true, "shadowsocksr": true, "vless": true}
+
+// lineIPVersions maps Surge ip-version values to mihomo ip-version
+var lineIPVersions = map[string]string{
+	"dual": "dual", "v4-only": "ipv4", "v6-only": "ipv6",
+	"prefer-v4": "ipv4-prefer", "prefer-v6": "ipv6-prefer",
+}
+
+// proxyLine is a Surge, Loon or Quantumult X proxy line split into its
+// parts. Options are key=value pairs with lowercase keys.
+type proxyLine struct {
+	*proxyFields
+
+	format string
+	kind   string // lowercase protocol keyword
+	name   string
+	server string
+	port   int
+	args   []string // positional values after the port (Loon credentials)
+}
+
+// option returns the value of the first of keys that is set
+func (l *proxyLine) option(keys ...string) (string, bool) {
+	for _, key := range keys {
+		if v, ok := l.get(key); ok {
+			return anyToString(v), true
+		}
+	}
+	return "", false
+}
+
+func (l *proxyLine) value(keys ...string) string {
+	v, _ := l.option(keys...)
+	return v
+}
+
+func (l *proxyLine) enabled(keys ...string) bool {
+	v, _ := l.option(keys...)
+	return isTrue(v)
+}
+
+// credential returns an option or, as Loon writes them, the i-th
+// positional value
+func (l *proxyLine) credential(i int, keys ...string) string {
+	if v := l.value(keys...); v != "" {
+		return v
+	}
+	if i < len(l.args) {
+		return l.args[i]
+	}
+	return ""
+}
+
+// parseProxyLine converts a Surge or Loon "Name = type, server, port, ..."
+// line or a Quantumult X "type=server:port, ..., tag=Name" line to a
+// mihomo proxy. handled is false for other lines, including the rest of
+// such configs.
+func parseProxyLine(line string) (proxy map[string]any, l *proxyLine, handled bool, err error) {
+	l, handled, err = splitProxyLine(line)
+	if !handled || err != nil {
+		return nil, l, handled, err
+	}
+
+	proxy = map[string]any{"name": l.name, "server": l.server, "port": l.port}
+	if err := proxyLineBuilders[l.kind](l, proxy); err != nil {
+		return nil, l, true, err
+	}
+
+	if l.enabled("tfo", "fast-open") {
+		proxy["tfo"] = true
+	}
+	if dialer := l.value("underlying-proxy"); dialer != "" {
+		proxy["dialer-proxy"] = dialer
+	}
+	if iface := l.value("interface"); iface != "" {
+		proxy["interface-name"] = iface
+	}
+	if version := lineIPVersions[strings.ToLower(l.value("ip-version"))]; version != "" {
+		proxy["ip-version"] = version
+	}
+	return proxy, l, true, nil
+}
+
+// isProxyLine reports whether line is a Surge, Loon or Quantumult X proxy
+// line, which must not be normalized like a share link
+func isProxyLine(line string) bool {
+	_, handled, _ := splitProxyLine(line)
+	return handled
+}
+
+// splitProxyLine recognizes and splits a proxy line
+func splitProxyLine(line string) (*proxyLine, bool, error) {
+	line = strings.TrimSpace(line)
+	if line == "" || line[0] == '#' || line[0] == ';' || strings.HasPrefix(line, "//") {
+		return nil, false, nil
+	}
+	key, rest, found := strings.Cut(line, "=")
+	if !found {
+		return nil, false, nil
+	}
+	key = strings.TrimSpace(key)
+	values := splitLineValues(rest)
+
+	if keyword := values[0]; proxyLineBuilders[strings.ToLower(keyword)] != nil && len(values) > 1 {
+		l, err := splitSurgeLine(key, keyword, values[1:])
+		return l, true, err
+	}
+	if kind := strings.ToLower(key); quanXKinds[kind] && strings.Contains(values[0], ":") && !isLineOption(values[0]) {
+		l, err := splitQuanXLine(kind, values)
+		return l, true, err
+	}
+	return nil, false, nil
+}
+
+// splitSurgeLine splits the values after the protocol keyword of a Surge
+// or Loon line: server, port, positional values and options
+func splitSurgeLine(name, keyword string, values []string) (*proxyLine, error) {
+	l := &proxyLine{format: formatSurge, kind: strings.ToLower(keyword), name: unquoteLineValue(name)}
+	if keyword != l.kind || loonKinds[l.kind] {
+		l.format = formatLoon
+	}
+
+	// Loon writes WireGuard without a server; its peers carry endpoints
+	if !isLineOption(values[0]) {
+		if len(values) < 2 || isLineOption(values[1]) {
+			return l, newLinkError("missing_port", "proxy line has no port")
+		}
+		var err error
+		if l.server, l.port, err = lineServer(values[0], values[1]); err != nil {
+			return l, err
+		}
+		values = values[2:]
+	} else if l.kind != "wireguard" {
+		return l, newLinkError("missing_server", "proxy line has no server address")
+	}
+
+	options := make(map[string]any)
+	for _, value := range values {
+		if isLineOption(value) {
+			key, v, _ := strings.Cut(value, "=")
+			options[strings.ToLower(strings.TrimSpace(key))] = unquoteLineValue(strings.TrimSpace(v))
+		} else {
+			l.args = append(l.args, unquoteLineValue(value))
+		}
+	}
+	l.proxyFields = newProxyFields(options)
+	switch l.kind {
+	case "http", "https", "socks5", "socks5-tls", "custom":
+	default:
+		if len(l.args) > 0 {
+			l.format = formatLoon
+		}
+	}
+	return l, nil
+}
+
+// splitQuanXLine splits a Quantumult X line, whose values after
+// server:port are all options
+func splitQuanXLine(kind string, values []string) (*proxyLine, error) {
+	l := &proxyLine{format: formatQuanX, kind: kind}
+	host, port, err := net.SplitHostPort(values[0])
+	if err != nil {
+		return l, newLinkError("invalid_format", "bad server address %q", values[0])
+	}
+	if l.server, l.port, err = lineServer(host, port); err != nil {
+		return l, err
+	}
+
+	options := make(map[string]any)
+	for _, value := range values[1:] {
+		key, v, _ := strings.Cut(value, "=")
+		options[strings.ToLower(strings.TrimSpace(key))] = unquoteLineValue(strings.TrimSpace(v))
+	}
+	l.proxyFields = newProxyFields(options)
+	l.name = l.value("tag")
+	return l, nil
+}
+
+func lineServer(server, port string) (string, int, error) {
+	server = strings.Trim(strings.TrimSpace(server), "[]")
+	if server == "" {
+		return "", 0, newLinkError("missing_server", "proxy line has no server address")
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(port))
+	if err != nil || n < 1 || n > 65535 {
+		return "", 0, newLinkError("invalid_port", "bad port %q", port)
+	}
+	return server, n, nil
+}
+
+// splitLineValues splits at commas outside quotes and brackets
+func splitLineValues(s string) []string {
+	var values []string
+	depth, quoted, start := 0, false, 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '"':
+			quoted = !quoted
+		case '[', '{':
+			if !quoted {
+				depth++
+			}
+		case ']', '}':
+			if !quoted && depth > 0 {
+				depth--
+			}
+		case ',':
+			if !quoted && depth == 0 {
+				values = append(values, strings.TrimSpace(s[start:i]))
+				start = i + 1
+			}
+		}
+	}
+	return append(values, strings.TrimSpace(s[start:]))
+}
+
+// isLineOption reports whether a value is a key=value option rather than
+// a positional (possibly quoted) value
+func isLineOption(value string) bool {
+	key, _, found := strings.Cut(value, "=")
+	if !found || key == "" {
+		return false
+	}
+	for _, c := range strings.TrimSpace(key) {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-' || c == '_') {
+			return false
+		}
+	}
+	return true
+}
+
+func unquoteLineValue(value string) string {
+	if len(value) >= 2 && value[0] == '"' && value[len(value)-1] == '"' {
+		return value[1 : len(value)-1]
+	}
+	return value
+}
+
+// lineUDP reads the UDP relay option, which defaults to def
+func lineUDP(l *proxyLine, def bool) bool {
+	if v, ok := l.option("udp-relay", "udp"); ok {
+		return isTrue(v)
+	}
+	return def
+}
+
+// applyLineTLS maps the TLS options. sniKey is the mihomo key of the
+// server name; it is empty for protocols that have none.
+func applyLineTLS(l *proxyLine, proxy map[string]any, sniKey string) {
+	if sni := l.value("sni", "tls-name", "tls-host", "peer", "obfs-host"); sni != "" && sni != "off" && sniKey != "" {
+		proxy[sniKey] = sni
+	}
+	if l.enabled("skip-cert-verify") {
+		proxy["skip-cert-verify"] = true
+	}
+	// Quantumult X spells it the other way round
+	if v, ok := l.option("tls-verification"); ok && !isTrue(v) {
+		proxy["skip-cert-verify"] = true
+	}
+	if fingerprint := l.value("server-cert-fingerprint-sha256", "tls-cert-sha256"); fingerprint != "" {
+		proxy["fingerprint"] = fingerprint
+	}
+	if alpn := l.value("alpn", "tls-alpn"); alpn != "" {
+		proxy["alpn"] = strings.FieldsFunc(alpn, func(r rune) bool { return r == ',' || r == '|' || r == ' ' })
+	}
+}
+
+// applyLineTransport maps the websocket and HTTP transports of vmess,
+// vless and trojan lines: Surge ws=true, Loon transport= and Quantumult X
+// obfs=. tls is set when the transport implies TLS (wss, over-tls).
+func applyLineTransport(l *proxyLine, proxy map[string]any) (tls bool, err error) {
+	network := ""
+	if l.enabled("ws") {
+		network = "ws"
+	} else {
+		switch transport := strings.ToLower(l.value("transport", "obfs")); transport {
+		case "", "tcp", "none":
+		case "ws", "websocket":
+			network = "ws"
+		case "wss":
+			network, tls = "ws", true
+		case "over-tls":
+			tls = true
+		case "http":
+			network = "http"
+		default:
+			return false, newLinkError("invalid_param", "unsupported transport %q", transport)
+		}
+	}
+
+	path := l.value("ws-path", "path", "obfs-uri")
+	host := l.value("host", "obfs-host")
+	switch network {
+	case "ws":
+		proxy["network"] = "ws"
+		opts := make(map[string]any)
+		if path != "" {
+			opts["path"] = path
+		}
+		headers := make(map[string]any)
+		// Surge: ws-headers=Host:example.com|User-Agent:x
+		for _, header := range strings.Split(l.value("ws-headers"), "|") {
+			if key, value, ok := strings.Cut(header, ":"); ok {
+				headers[strings.TrimSpace(key)] = strings.TrimSpace(value)
+			}
+		}
+		if host != "" {
+			headers["Host"] = host
+		}
+		if len(headers) > 0 {
+			opts["headers"] = headers
+		}
+		if len(opts) > 0 {
+			proxy["ws-opts"] = opts
+		}
+	case "http":
+		proxy["network"] = "http"
+		if path == "" {
+			path = "/"
+		}
+		opts := map[string]any{"path": []string{path}}
+		if host != "" {
+			opts["headers"] = map[string]any{"Host": []string{host}}
+		}
+		proxy["http-opts"] = opts
+	}
+	return tls, nil
+}
+
+func buildLineShadowsocks(l *proxyLine, proxy map[string]any) error {
+	if l.has("ssr-protocol") {
+		return buildLineShadowsocksR(l, proxy)
+	}
+	proxy["type"] = "ss"
+	proxy["cipher"] = l.credential(0, "encrypt-method", "method")
+	proxy["password"] = l.credential(1, "password")
+	if proxy["cipher"] == "" || proxy["password"] == "" {
+		return newLinkError("missing_credentials", "ss line needs a cipher and a password")
+	}
+	proxy["udp"] = lineUDP(l, true)
+
+	switch obfs := strings.ToLower(l.value("obfs", "obfs-name")); obfs {
+	case "", "none":
+	case "http", "tls":
+		opts := map[string]any{"mode": obfs}
+		if host := l.value("obfs-host"); host != "" {
+			opts["host"] = host
+		}
+		proxy["plugin"] = "obfs"
+		proxy["plugin-opts"] = opts
+	case "ws", "wss":
+		opts := map[string]any{"mode": "websocket", "tls": obfs == "wss"}
+		if host := l.value("obfs-host"); host != "" {
+			opts["host"] = host
+		}
+		if path := l.value("obfs-uri"); path != "" {
+			opts["path"] = path
+		}
+		proxy["plugin"] = "v2ray-plugin"
+		proxy["plugin-opts"] = opts
+	default:
+		return newLinkError("invalid_param", "unsupported ss obfs %q", obfs)
+	}
+
+	if password := l.value("shadow-tls-password"); password != "" {
+		if proxy["plugin"] != nil {
+			return newLinkError("invalid_param", "ss line combines obfs with shadow-tls")
+		}
+		opts := map[string]any{"host": l.value("shadow-tls-sni"), "password": password}
+		if version := l.value("shadow-tls-version"); version != "" {
+			opts["version"] = anyToInt(version)
+		}
+		proxy["plugin"] = "shadow-tls"
+		proxy["plugin-opts"] = opts
+	}
+	return nil
+}
+
+func buildLineShadowsocksR(l *proxyLine, proxy map[string]any) error {
+	proxy["type"] = "ssr"
+	proxy["cipher"] = l.credential(0, "method", "encrypt-method")
+	proxy["password"] = l.credential(1, "password")
+	if proxy["cipher"] == "" || proxy["password"] == "" {
+		return newLinkError("missing_credentials", "ssr line needs a cipher and a password")
+	}
+	proxy["protocol"] = cmp.Or(l.value("protocol", "ssr-protocol"), "origin")
+	proxy["obfs"] = cmp.Or(l.value("obfs"), "plain")
+	if param := l.value("protocol-param", "ssr-protocol-param"); param != "" {
+		proxy["protocol-param"] = param
+	}
+	if param := l.value("obfs-param", "obfs-host"); param != "" {
+		proxy["obfs-param"] = param
+	}
+	proxy["udp"] = lineUDP(l, true)
+	return nil
+}
+
+func buildLineVMess(l *proxyLine, proxy map[string]any) error {
+	proxy["type"] = "vmess"
+	// Surge: username=uuid; Quantumult X: password=uuid; Loon: cipher, "uuid"
+	uuid := l.value("username", "password")
+	cipher := l.value("method", "encrypt-method")
+	if uuid == "" && len(l.args) > 0 {
+		uuid = l.args[len(l.args)-1]
+		if len(l.args) > 1 && cipher == "" {
+			cipher = l.args[0]
+		}
+	}
+	if uuid == "" {
+		return newLinkError("missing_credentials", "vmess line has no uuid")
+	}
+	if cipher == "chacha20-ietf-poly1305" {
+		cipher = "chacha20-poly1305"
+	}
+	proxy["uuid"] = uuid
+	proxy["cipher"] = cmp.Or(cipher, "auto")
+	proxy["alterId"] = anyToInt(l.value("alterid", "alter-id"))
+	l.use("vmess-aead")
+
+	tls, err := applyLineTransport(l, proxy)
+	if err != nil {
+		return err
+	}
+	if l.enabled("tls", "over-tls") || tls {
+		proxy["tls"] = true
+		applyLineTLS(l, proxy, "servername")
+	}
+	proxy["udp"] = lineUDP(l, true)
+	return nil
+}
+
+func buildLineVLESS(l *proxyLine, proxy map[string]any) error {
+	proxy["type"] = "vless"
+	proxy["uuid"] = l.credential(0, "password", "uuid", "username")
+	if proxy["uuid"] == "" {
+		return newLinkError("missing_credentials", "vless line has no uuid")
+	}
+	l.use("method")
+	if flow := l.value("flow", "vless-flow"); flow != "" {
+		proxy["flow"] = flow
+	}
+
+	tls, err := applyLineTransport(l, proxy)
+	if err != nil {
+		return err
+	}
+	if publicKey := l.value("public-key", "reality-base64-pubkey"); publicKey != "" {
+		opts := map[string]any{"public-key": publicKey}
+		if shortID := l.value("short-id", "reality-hex-shortid"); shortID != "" {
+			opts["short-id"] = shortID
+		}
+		proxy["reality-opts"] = opts
+		tls = true
+	}
+	if l.enabled("tls", "over-tls") || tls {
+		proxy["tls"] = true
+		applyLineTLS(l, proxy, "servername")
+	}
+	proxy["udp"] = lineUDP(l, true)
+	return nil
+}
+
+func buildLineTrojan(l *proxyLine, proxy map[string]any) error {
+	proxy["type"] = "trojan"
+	proxy["password"] = l.credential(0, "password")
+	if proxy["password"] == "" {
+		return newLinkError("missing_credentials", "trojan line has no password")
+	}
+	// mihomo's trojan always runs over TLS
+	l.use("over-tls", "tls")
+	if _, err := applyLineTransport(l, proxy); err != nil {
+		return err
+	}
+	applyLineTLS(l, proxy, "sni")
+	proxy["udp"] = lineUDP(l, true)
+	return nil
+}
+
+func buildLineHTTP(l *proxyLine, proxy map[string]any) error {
+	proxy["type"] = "http"
+	if username := l.credential(0, "username"); username != "" {
+		proxy["username"] = username
+		proxy["password"] = l.credential(1, "password")
+	}
+	if l.kind == "https" || l.enabled("over-tls", "tls") {
+		proxy["tls"] = true
+		applyLineTLS(l, proxy, "sni")
+	}
+	return nil
+}
+
+func buildLineSocks(l *proxyLine, proxy map[string]any) error {
+	proxy["type"] = "socks5"
+	if username := l.credential(0, "username"); username != "" {
+		proxy["username"] = username
+		proxy["password"] = l.credential(1, "password")
+	}
+	if l.kind == "socks5-tls" || l.enabled("over-tls", "tls") {
+		proxy["tls"] = true
+		applyLineTLS(l, proxy, "")
+	}
+	proxy["udp"] = lineUDP(l, true)
+	return nil
+}
+
+func buildLineSnell(l *proxyLine, proxy map[string]any) error {
+	proxy["type"] = "snell"
+	proxy["psk"] = l.credential(0, "psk")
+	if proxy["psk"] == "" {
+		return newLinkError("missing_credentials", "snell line has no psk")
+	}
+	version := 1
+	if value := l.value("version"); value != "" {
+		var err error
+		if version, err = strconv.Atoi(value); err != nil || version < 1 || version > 3 {
+			return newLinkError("invalid_param", "mihomo supports snell versions 1 to 3, not %q", value)
+		}
+		proxy["version"] = version
+	}
+	// Only snell v3 relays UDP
+	if lineUDP(l, false) && version == 3 {
+		proxy["udp"] = true
+	}
+
+	switch obfs := strings.ToLower(l.value("obfs")); obfs {
+	case "", "none", "off":
+	case "http", "tls":
+		opts := map[string]any{"mode": obfs}
+		if host := l.value("obfs-host"); host != "" {
+			opts["host"] = host
+		}
+		proxy["obfs-opts"] = opts
+	default:
+		return newLinkError("invalid_param", "unsupported snell obfs %q", obfs)
+	}
+	return nil
+}
+
+// buildLineTUIC maps Surge tuic (v4, token) and tuic-v5 (uuid, password)
+func buildLineTUIC(l *proxyLine, proxy map[string]any) error {
+	proxy["type"] = "tuic"
+	if token := l.value("token"); token != "" && l.kind == "tuic" {
+		proxy["token"] = token
+	} else {
+		proxy["uuid"] = l.value("uuid", "username")
+		proxy["password"] = l.value("password")
+		if proxy["uuid"] == "" || proxy["password"] == "" {
+			return newLinkError("missing_credentials", "tuic line needs a token, or a uuid and a password")
+		}
+	}
+	applyLineTLS(l, proxy, "sni")
+	if proxy["alpn"] == nil {
+		proxy["alpn"] = []string{"h3"}
+	}
+	proxy["udp"] = lineUDP(l, true)
+	return nil
+}
+
+func buildLineHysteria2(l *proxyLine, proxy map[string]any) error {
+	proxy["type"] = "hysteria2"
+	proxy["password"] = l.credential(0, "password", "auth")
+	if proxy["password"] == "" {
+		return newLinkError("missing_credentials", "hysteria2 line has no password")
+	}
+	applyLineTLS(l, proxy, "sni")
+
+	if down := l.value("download-bandwidth"); down != "" {
+		proxy["down"] = down + " Mbps"
+	}
+	if up := l.value("upload-bandwidth"); up != "" {
+		proxy["up"] = up + " Mbps"
+	}
+	// Surge separates port ranges with ";", mihomo with ","
+	if ports := l.value("port-hopping"); ports != "" {
+		proxy["ports"] = strings.ReplaceAll(ports, ";", ",")
+		if interval := l.value("port-hopping-interval"); interval != "" {
+			proxy["hop-interval"] = anyToInt(interval)
+		}
+	}
+	if password := l.value("salamander-password", "obfs-password"); password != "" {
+		proxy["obfs"] = "salamander"
+		proxy["obfs-password"] = password
+		l.use("obfs")
+	}
+	proxy["udp"] = lineUDP(l, true)
+	return nil
+}
+
+func buildLineAnyTLS(l *proxyLine, proxy map[string]any) error {
+	proxy["type"] = "anytls"
+	proxy["password"] = l.credential(0, "password")
+	if proxy["password"] == "" {
+		return newLinkError("missing_credentials", "anytls line has no password")
+	}
+	applyLineTLS(l, proxy, "sni")
+	proxy["udp"] = lineUDP(l, true)
+	return nil
+}
+
+func buildLineSSH(l *proxyLine, proxy map[string]any) error {
+	proxy["type"] = "ssh"
+	proxy["username"] = l.credential(0, "username")
+	if proxy["username"] == "" {
+		return newLinkError("missing_credentials", "ssh line has no username")
+	}
+	if password := l.credential(1, "password"); password != "" {
+		proxy["password"] = password
+	} else if l.has("private-key") {
+		return newLinkError("unsupported_format", "ssh private-key refers to the Surge keystore")
+	} else {
+		return newLinkError("missing_credentials", "ssh line has no password")
+	}
+	if hostKey := l.value("server-fingerprint"); hostKey != "" {
+		proxy["host-key"] = []string{hostKey}
+	}
+	return nil
+}
+
+// buildLineWireGuard maps the inline Loon WireGuard format. Surge keeps
+// WireGuard settings in a separate section, which a single line cannot
+// reach.
+func buildLineWireGuard(l *proxyLine, proxy map[string]any) error {
+	if section := l.value("section-name"); section != "" {
+		return newLinkError("unsupported_format", "wireguard settings are in the [WireGuard %s] section", section)
+	}
+	l.format = formatLoon
+	proxy["type"] = "wireguard"
+	proxy["private-key"] = l.value("private-key")
+	if err := checkWireGuardKey("private key", anyToString(proxy["private-key"])); err != nil {
+		return err
+	}
+	var addresses []string
+	for _, address := range []string{l.value("interface-ip"), l.value("interface-ipv6")} {
+		if address != "" {
+			addresses = append(addresses, address)
+		}
+	}
+	if _, err := setWireGuardAddresses(proxy, addresses); err != nil {
+		return err
+	}
+	if mtu := l.value("mtu"); mtu != "" {
+		proxy["mtu"] = anyToInt(mtu)
+	}
+	if keepalive := l.value("keepalive"); keepalive != "" {
+		proxy["persistent-keepalive"] = anyToInt(keepalive)
+	}
+	if dns := splitLineValues(l.value("dns")); dns[0] != "" {
+		proxy["dns"] = dns
+		proxy["remote-dns-resolve"] = true
+	}
+	proxy["udp"] = true
+
+	// peers=[{public-key=...,allowed-ips="0.0.0.0/0",endpoint=host:port}]
+	peersValue := strings.TrimSpace(l.value("peers"))
+	peersValue = strings.TrimSuffix(strings.TrimPrefix(peersValue, "["), "]")
+	var peers []any
+	for _, item := range splitLineValues(peersValue) {
+		item = strings.TrimSuffix(strings.TrimPrefix(strings.TrimSpace(item), "{"), "}")
+		if item == "" {
+			continue
+		}
+		peer, err := wireGuardLinePeer(item)
+		if err != nil {
+			return err
+		}
+		peers = append(peers, peer)
+	}
+	switch len(peers) {
+	case 0:
+		return newLinkError("missing_param", "wireguard line has no peers")
+	case 1:
+		for key, value := range peers[0].(map[string]any) {
+			proxy[key] = value
+		}
+	default:
+		proxy["peers"] = peers
+		first := peers[0].(map[string]any)
+		proxy["server"], proxy["port"] = first["server"], first["port"]
+	}
+	return nil
+}
+
+// wireGuardLinePeer reads one peer object of a Loon WireGuard line
+func wireGuardLinePeer(item string) (map[string]any, error) {
+	options := make(map[string]string)
+	for _, value := range splitLineValues(item) {
+		key, v, _ := strings.Cut(value, "=")
+		options[strings.ToLower(strings.TrimSpace(key))] = unquoteLineValue(strings.TrimSpace(v))
+	}
+
+	host, portStr, err := net.SplitHostPort(options["endpoint"])
+	if err != nil {
+		return nil, newLinkError("invalid_param", "bad wireguard peer endpoint %q", options["endpoint"])
+	}
+	server, port, err := lineServer(host, portStr)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkWireGuardKey("public key", options["public-key"]); err != nil {
+		return nil, err
+	}
+	peer := map[string]any{"server": server, "port": port, "public-key": options["public-key"]}
+	if psk := options["preshared-key"]; psk != "" {
+		peer["pre-shared-key"] = psk
+	}
+	if allowed := splitLineValues(options["allowed-ips"]); allowed[0] != "" {
+		peer["allowed-ips"] = allowed
+	}
+	if value := strings.Trim(options["reserved"], "[]"); value != "" {
+		reserved, err := parseReserved(value)
+		if err != nil {
+			return nil, err
+		}
+		peer["reserved"] = reserved
+	}
+	return peer, nil
+}
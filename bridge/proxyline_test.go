@@ -0,0 +1,161 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestParseProxyLine(t *testing.T) {
+	tests := []struct {
+		name   string
+		line   string
+		format string
+		want   map[string]any // Fields checked on the parsed proxy
+		code   string         // linkError code if the line is rejected
+	}{
+		{
+			name:   "surge trojan",
+			line:   "HK = trojan, a.com, 443, password=pw, sni=b.com, skip-cert-verify=true",
+			format: formatSurge,
+			want: map[string]any{
+				"name": "HK", "type": "trojan", "server": "a.com", "port": 443,
+				"password": "pw", "sni": "b.com", "skip-cert-verify": true,
+			},
+		},
+		{
+			name:   "surge ss with obfs",
+			line:   "JP = ss, a.com, 8388, encrypt-method=aes-128-gcm, password=pw, obfs=http, obfs-host=x.com",
+			format: formatSurge,
+			want: map[string]any{
+				"name": "JP", "type": "ss", "server": "a.com", "port": 8388,
+				"cipher": "aes-128-gcm", "password": "pw", "plugin": "obfs",
+			},
+		},
+		{
+			name:   "surge vmess over websocket",
+			line:   "SG = vmess, a.com, 443, username=b1b625b6-8226-4a6b-ad4e-4908c3d2edba, ws=true, ws-path=/p, tls=true",
+			format: formatSurge,
+			want: map[string]any{
+				"name": "SG", "type": "vmess", "uuid": "b1b625b6-8226-4a6b-ad4e-4908c3d2edba",
+				"network": "ws", "tls": true,
+			},
+		},
+		{
+			name:   "surge hysteria2",
+			line:   "US = hysteria2, a.com, 443, password=pw, sni=a.com",
+			format: formatSurge,
+			want:   map[string]any{"name": "US", "type": "hysteria2", "password": "pw", "sni": "a.com"},
+		},
+		{
+			name:   "loon trojan",
+			line:   `LN = Trojan, a.com, 443, "pw", over-tls=true, tls-name=b.com`,
+			format: formatLoon,
+			want:   map[string]any{"name": "LN", "type": "trojan", "password": "pw", "sni": "b.com"},
+		},
+		{
+			name:   "quantumult x trojan",
+			line:   "trojan=a.com:443, password=pw, over-tls=true, tls-host=b.com, tag=QX",
+			format: formatQuanX,
+			want:   map[string]any{"name": "QX", "type": "trojan", "server": "a.com", "port": 443, "sni": "b.com"},
+		},
+		{
+			name:   "quantumult x shadowsocks",
+			line:   "shadowsocks=a.com:8388, method=aes-128-gcm, password=pw, tag=QS",
+			format: formatQuanX,
+			want:   map[string]any{"name": "QS", "type": "ss", "cipher": "aes-128-gcm", "password": "pw"},
+		},
+		{
+			name:   "bad port",
+			line:   "Bad = trojan, a.com, notaport, password=pw",
+			format: formatSurge,
+			code:   "invalid_port",
+		},
+		{
+			name:   "unknown obfs",
+			line:   "Bad = ss, a.com, 8388, encrypt-method=aes-128-gcm, password=pw, obfs=foo",
+			format: formatSurge,
+			code:   "invalid_param",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			proxy, l, handled, err := parseProxyLine(tt.line)
+			if !handled {
+				t.Fatalf("parseProxyLine(%q) did not handle the line", tt.line)
+			}
+			if l.format != tt.format {
+				t.Errorf("format = %q, want %q", l.format, tt.format)
+			}
+			if tt.code != "" {
+				var linkErr *linkError
+				if !errors.As(err, &linkErr) || linkErr.Code != tt.code {
+					t.Errorf("error = %v, want code %q", err, tt.code)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseProxyLine(%q): %v", tt.line, err)
+			}
+			for key, want := range tt.want {
+				if proxy[key] != want {
+					t.Errorf("%s = %#v, want %#v", key, proxy[key], want)
+				}
+			}
+		})
+	}
+}
+
+func TestProxyLineShareLinkRoundTrip(t *testing.T) {
+	lines := []string{
+		"HK = trojan, a.com, 443, password=pw, sni=b.com, skip-cert-verify=true",
+		"JP = ss, a.com, 8388, encrypt-method=aes-128-gcm, password=pw, obfs=http, obfs-host=x.com",
+		"SG = vmess, a.com, 443, username=b1b625b6-8226-4a6b-ad4e-4908c3d2edba, ws=true, ws-path=/p, tls=true",
+		"US = hysteria2, a.com, 443, password=pw, sni=a.com",
+		"trojan=a.com:443, password=pw, over-tls=true, tls-host=b.com, tag=QX",
+	}
+	keys := []string{"name", "type", "server", "port", "password", "uuid", "cipher", "sni", "network"}
+
+	for _, line := range lines {
+		t.Run(line, func(t *testing.T) {
+			proxy, _, _, err := parseProxyLine(line)
+			if err != nil {
+				t.Fatalf("parseProxyLine: %v", err)
+			}
+			exported := exportShareLink(proxy)
+			if exported.Error != "" {
+				t.Fatalf("exportShareLink: %s", exported.Error)
+			}
+			proxies, _, _, err := convertLinks(context.Background(), exported.Link)
+			if err != nil || len(proxies) != 1 {
+				t.Fatalf("convertLinks(%q) = %d proxies, %v", exported.Link, len(proxies), err)
+			}
+			for _, key := range keys {
+				if got, want := anyToString(proxies[0][key]), anyToString(proxy[key]); got != want {
+					t.Errorf("%s = %q after %q, want %q", key, got, exported.Link, want)
+				}
+			}
+		})
+	}
+}
+
+func TestIsProxyLine(t *testing.T) {
+	tests := []struct {
+		line string
+		want bool
+	}{
+		{"HK = trojan, a.com, 443, password=pw", true},
+		{"trojan=a.com:443, password=pw, tag=QX", true},
+		{"trojan://pw@a.com:443#A", false},
+		{"DOMAIN-SUFFIX,google.com,Proxy", false},
+		{"Auto = url-test, HK, JP, url=http://www.gstatic.com/generate_204", false},
+		{"", false},
+	}
+
+	for _, tt := range tests {
+		if got := isProxyLine(tt.line); got != tt.want {
+			t.Errorf("isProxyLine(%q) = %v, want %v", tt.line, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,244 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// decodeProxyList accepts mihomo proxies as a JSON array, a JSON/YAML
+// document with a top-level "proxies" list, or a bare YAML list
+func decodeProxyList(data []byte) ([]map[string]any, error) {
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 {
+		return nil, errors.New("empty input")
+	}
+
+	var list []map[string]any
+	if data[0] == '[' && json.Unmarshal(data, &list) == nil {
+		return list, nil
+	}
+
+	// YAML is a superset of JSON, so this also covers {"proxies": [...]}
+	var doc any
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("input is neither JSON nor YAML: %w", err)
+	}
+	if m, ok := doc.(map[string]any); ok {
+		if proxies, ok := m["proxies"]; ok {
+			doc = proxies
+		} else {
+			// A single proxy mapping
+			doc = []any{m}
+		}
+	}
+
+	items, ok := doc.([]any)
+	if !ok {
+		return nil, errors.New("no proxy list found in input")
+	}
+	for i, item := range items {
+		m, ok := item.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("proxy #%d is not a mapping", i+1)
+		}
+		list = append(list, m)
+	}
+	return list, nil
+}
+
+// proxyFields wraps a mihomo proxy map and records which keys were read,
+// so that callers can report what was left over
+type proxyFields struct {
+	m    map[string]any
+	used map[string]bool
+}
+
+func newProxyFields(m map[string]any) *proxyFields {
+	return &proxyFields{m: m, used: make(map[string]bool)}
+}
+
+// lookup resolves a dotted path such as "ws-opts.headers.Host"
+func (p *proxyFields) lookup(path string) (any, bool) {
+	var cur any = p.m
+	for _, key := range strings.Split(path, ".") {
+		m, ok := cur.(map[string]any)
+		if !ok {
+			return nil, false
+		}
+		if cur, ok = m[key]; !ok {
+			return nil, false
+		}
+	}
+	return cur, true
+}
+
+// get returns the value at path and marks it as consumed
+func (p *proxyFields) get(path string) (any, bool) {
+	v, ok := p.lookup(path)
+	if ok {
+		p.used[path] = true
+	}
+	return v, ok
+}
+
+// use marks paths as consumed without reading them
+func (p *proxyFields) use(paths ...string) {
+	for _, path := range paths {
+		p.used[path] = true
+	}
+}
+
+func (p *proxyFields) has(path string) bool {
+	v, ok := p.lookup(path)
+	return ok && !isZeroValue(v)
+}
+
+func (p *proxyFields) str(path string) string {
+	v, _ := p.get(path)
+	return anyToString(v)
+}
+
+func (p *proxyFields) boolean(path string) bool {
+	v, _ := p.get(path)
+	return anyToBool(v)
+}
+
+func (p *proxyFields) integer(path string) int {
+	v, _ := p.get(path)
+	return anyToInt(v)
+}
+
+// strs reads a list of strings, accepting a single scalar as a one-element list
+func (p *proxyFields) strs(path string) []string {
+	v, _ := p.get(path)
+	return anyToStrings(v)
+}
+
+// unused returns the dotted paths of all non-empty leaf values that were
+// never consumed, sorted for stable output
+func (p *proxyFields) unused() []string {
+	var result []string
+	var walk func(prefix string, v any)
+	walk = func(prefix string, v any) {
+		if p.used[prefix] {
+			return
+		}
+		if m, ok := v.(map[string]any); ok {
+			for k, child := range m {
+				walk(prefix+"."+k, child)
+			}
+			return
+		}
+		if !isZeroValue(v) {
+			result = append(result, prefix)
+		}
+	}
+	for k, v := range p.m {
+		walk(k, v)
+	}
+	sort.Strings(result)
+	return result
+}
+
+func isZeroValue(v any) bool {
+	switch val := v.(type) {
+	case nil:
+		return true
+	case string:
+		return val == ""
+	case bool:
+		return !val
+	case int:
+		return val == 0
+	case float64:
+		return val == 0
+	case []any:
+		return len(val) == 0
+	case []string:
+		return len(val) == 0
+	case map[string]any:
+		for _, child := range val {
+			if !isZeroValue(child) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+func anyToString(v any) string {
+	switch val := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return val
+	case float64:
+		return strconv.FormatFloat(val, 'f', -1, 64)
+	case []any:
+		return strings.Join(anyToStrings(val), ",")
+	case []string:
+		return strings.Join(val, ",")
+	}
+	return fmt.Sprint(v)
+}
+
+func anyToBool(v any) bool {
+	switch val := v.(type) {
+	case bool:
+		return val
+	case string:
+		b, _ := strconv.ParseBool(val)
+		return b
+	case int:
+		return val != 0
+	case float64:
+		return val != 0
+	}
+	return false
+}
+
+func anyToInt(v any) int {
+	switch val := v.(type) {
+	case int:
+		return val
+	case int64:
+		return int(val)
+	case uint64:
+		return int(val)
+	case float64:
+		return int(val)
+	case string:
+		n, _ := strconv.Atoi(strings.TrimSpace(val))
+		return n
+	}
+	return 0
+}
+
+func anyToStrings(v any) []string {
+	switch val := v.(type) {
+	case nil:
+		return nil
+	case []string:
+		return val
+	case []any:
+		result := make([]string, 0, len(val))
+		for _, item := range val {
+			if s := anyToString(item); s != "" {
+				result = append(result, s)
+			}
+		}
+		return result
+	}
+	if s := anyToString(v); s != "" {
+		return []string{s}
+	}
+	return nil
+}
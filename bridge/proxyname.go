@@ -0,0 +1,96 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// Fixes reported by cleanProxyName
+const (
+	nameTranscoded = "transcoded" // name bytes were GBK/Big5, not UTF-8
+	nameInvisible  = "invisible"  // zero-width or bidi characters removed
+	nameControl    = "control"    // control characters removed
+	nameNFC        = "nfc"        // name was not in Unicode NFC form
+)
+
+// invisibleRunes are zero-width and bidi formatting characters that
+// clients render as nothing, or use to reorder the rest of the name
+var invisibleRunes = map[rune]bool{
+	'\u00ad': true, '\u061c': true, '\u180e': true, '\u200b': true,
+	'\u200c': true, '\u200e': true, '\u200f': true, '\u202a': true,
+	'\u202b': true, '\u202c': true, '\u202d': true, '\u202e': true,
+	'\u2060': true, '\u2061': true, '\u2062': true, '\u2063': true,
+	'\u2064': true, '\u2066': true, '\u2067': true, '\u2068': true,
+	'\u2069': true, '\ufeff': true,
+}
+
+// cleanProxyName returns name as clean NFC UTF-8 and the fixes applied
+func cleanProxyName(name string) (string, []string) {
+	var fixes []string
+	if !utf8.ValidString(name) {
+		name, _ = transcodeLegacy(name)
+		fixes = append(fixes, nameTranscoded)
+	}
+
+	runes := []rune(name)
+	var b strings.Builder
+	invisible, control := false, false
+	for i, r := range runes {
+		switch {
+		case r == '\u200d':
+			// Zero-width joiners are part of emoji sequences (e.g. flags)
+			if i > 0 && i+1 < len(runes) && isEmojiPart(runes[i-1]) && isEmojiPart(runes[i+1]) {
+				b.WriteRune(r)
+				continue
+			}
+			invisible = true
+		case invisibleRunes[r]:
+			invisible = true
+		case unicode.IsControl(r):
+			control = true
+		default:
+			b.WriteRune(r)
+		}
+	}
+	if invisible {
+		fixes = append(fixes, nameInvisible)
+	}
+	if control {
+		fixes = append(fixes, nameControl)
+	}
+
+	cleaned := b.String()
+	if !norm.NFC.IsNormalString(cleaned) {
+		cleaned = norm.NFC.String(cleaned)
+		fixes = append(fixes, nameNFC)
+	}
+	return strings.TrimSpace(cleaned), fixes
+}
+
+func isEmojiPart(r rune) bool {
+	return unicode.Is(unicode.So, r) || r == '\ufe0f'
+}
+
+// cleanProxyMapName cleans the name of a converted proxy. Names left
+// empty fall back to server:port.
+func cleanProxyMapName(proxy map[string]any) (string, []string) {
+	name, fixes := cleanProxyName(anyToString(proxy["name"]))
+	if name == "" {
+		name = fmt.Sprintf("%s:%s", anyToString(proxy["server"]), anyToString(proxy["port"]))
+	}
+	return name, fixes
+}
+
+// sanitizeProxyNames cleans the names of converted proxies and keeps them
+// unique
+func sanitizeProxyNames(proxies []map[string]any) {
+	names := make(map[string]int)
+	for _, proxy := range proxies {
+		name, _ := cleanProxyMapName(proxy)
+		proxy["name"] = uniqueProxyName(names, name)
+	}
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"context"
+	"slices"
+	"testing"
+)
+
+func TestCleanProxyName(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+		fixes []string
+	}{
+		{name: "clean", input: "HK 01", want: "HK 01"},
+		{name: "surrounding spaces", input: " HK ", want: "HK"},
+		{name: "GBK bytes", input: "\xc4\xe3\xba\xc3 HK", want: "你好 HK", fixes: []string{nameTranscoded}},
+		{name: "zero-width space", input: "H\u200bK", want: "HK", fixes: []string{nameInvisible}},
+		{name: "stray zero-width joiner", input: "a\u200db", want: "ab", fixes: []string{nameInvisible}},
+		{name: "control character", input: "H\x07K", want: "HK", fixes: []string{nameControl}},
+		{name: "decomposed accent", input: "Cafe\u0301", want: "Café", fixes: []string{nameNFC}},
+		{name: "emoji sequence is kept", input: "🏳\ufe0f\u200d🌈 X", want: "🏳\ufe0f\u200d🌈 X"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, fixes := cleanProxyName(tt.input)
+			if got != tt.want || !slices.Equal(fixes, tt.fixes) {
+				t.Errorf("cleanProxyName(%q) = %q, %v, want %q, %v", tt.input, got, fixes, tt.want, tt.fixes)
+			}
+
+			// A clean name needs no further fixes
+			again, fixes := cleanProxyName(got)
+			if again != got || len(fixes) > 0 {
+				t.Errorf("cleanProxyName(%q) = %q, %v, want it unchanged", got, again, fixes)
+			}
+		})
+	}
+}
+
+func TestSanitizeProxyNames(t *testing.T) {
+	tests := []struct {
+		name    string
+		proxies []map[string]any
+		want    []string
+	}{
+		{
+			name: "empty names fall back to the address",
+			proxies: []map[string]any{
+				{"name": "", "server": "a.com", "port": 443},
+				{"name": "a.com:443"},
+				{"name": "\u200bB"},
+				{"name": "B"},
+			},
+			want: []string{"a.com:443", "a.com:443-01", "B", "B-01"},
+		},
+		{
+			name: "suffixes do not collide with existing names",
+			proxies: []map[string]any{
+				{"name": "A"}, {"name": "A-01"}, {"name": "A"}, {"name": "A-01"},
+			},
+			want: []string{"A", "A-01", "A-02", "A-01-01"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sanitizeProxyNames(tt.proxies)
+			if got := proxyNames(tt.proxies); !slices.Equal(got, tt.want) {
+				t.Errorf("sanitizeProxyNames = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSanitizeProxyNamesAcrossTypes(t *testing.T) {
+	// Links of different types convert independently, so their names can
+	// collide with each other and with suffixes added within one type
+	const links = "trojan://pw@a.com:443#A\n" +
+		"trojan://pw@b.com:443#A\n" +
+		"ssh://u:p@c.com:22#A\n" +
+		"trojan://pw@d.com:443#A\n" +
+		"trojan://pw@e.com:443#A-01"
+
+	proxies, _, _, err := convertLinks(context.Background(), links)
+	if err != nil {
+		t.Fatalf("convertLinks: %v", err)
+	}
+	sanitizeProxyNames(proxies)
+
+	got := proxyNames(proxies)
+	want := []string{"A", "A-01", "A-02", "A-03", "A-01-01"}
+	if !slices.Equal(got, want) {
+		t.Errorf("names = %q, want %q", got, want)
+	}
+	for i, proxy := range proxies {
+		if server := []string{"a.com", "b.com", "c.com", "d.com", "e.com"}[i]; proxy["server"] != server {
+			t.Errorf("proxy %d server = %v, want %s", i, proxy["server"], server)
+		}
+	}
+}
+
+func proxyNames(proxies []map[string]any) []string {
+	names := make([]string, 0, len(proxies))
+	for _, proxy := range proxies {
+		names = append(names, anyToString(proxy["name"]))
+	}
+	return names
+}
@@ -0,0 +1,114 @@
+package main
+
+import "C"
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"runtime/debug"
+)
+
+// responseVersion is bumped whenever the response envelope changes shape
+const responseVersion = 1
+
+// Error codes reported in the response envelope. Errors from the link
+// parsers keep their own diagnostic codes.
+const (
+	codeNullInput      = "null_input"
+	codeInvalidInput   = "invalid_input"
+	codeUnknownSession = "unknown_session"
+	codeUnknownHandle  = "unknown_handle"
+	codeTooLarge       = "payload_too_large"
+	codeUnrecognized   = "unrecognized_format"
+	codeMarshalFailed  = "marshal_failed"
+	codeInternalPanic  = "internal_panic"
+)
+
+// bridgeError is an error with the code it reports in the response
+// envelope and optional structured details
+type bridgeError struct {
+	Code    string
+	Message string
+	Details any
+}
+
+func (e *bridgeError) Error() string { return e.Message }
+
+func newBridgeError(code, format string, args ...any) *bridgeError {
+	return &bridgeError{Code: code, Message: fmt.Sprintf(format, args...)}
+}
+
+var (
+	errNullInput      = newBridgeError(codeNullInput, "null input")
+	errUnknownSession = newBridgeError(codeUnknownSession, "unknown session")
+)
+
+// response is the envelope every exported function returns. Data holds
+// the payload on success; Code and Message explain a failure.
+type response struct {
+	Version  int      `json:"version"`
+	OK       bool     `json:"ok"`
+	Code     string   `json:"code,omitempty"`
+	Message  string   `json:"message,omitempty"`
+	Details  any      `json:"details,omitempty"`
+	Warnings []string `json:"warnings,omitempty"`
+	Data     any      `json:"data,omitempty"`
+}
+
+// respond runs the body of an exported function and wraps its outcome in
+// the response envelope. A panic is reported as an internal_panic error
+// instead of unwinding into the C++ process.
+func respond(name string, fn func() (data any, warnings []string, err error)) (out *C.char) {
+	defer func() {
+		if r := recover(); r != nil {
+			out = encodeResponse(response{
+				Code:    codeInternalPanic,
+				Message: fmt.Sprintf("%s panicked: %v", name, r),
+				Details: map[string]string{"function": name, "stack": string(debug.Stack())},
+			})
+		}
+	}()
+
+	data, warnings, err := fn()
+	if err != nil {
+		resp := errorResponse(err)
+		resp.Warnings = warnings
+		return encodeResponse(resp)
+	}
+	return encodeResponse(response{OK: true, Warnings: warnings, Data: data})
+}
+
+// errorResponse maps an error to its envelope code
+func errorResponse(err error) response {
+	resp := response{Code: codeInvalidInput, Message: err.Error()}
+	var bridgeErr *bridgeError
+	var linkErr *linkError
+	switch {
+	case errors.As(err, &bridgeErr):
+		resp.Code, resp.Details = bridgeErr.Code, bridgeErr.Details
+	case errors.As(err, &linkErr):
+		resp.Code = linkErr.Code
+	case errors.Is(err, errDecompressedTooLarge):
+		resp.Code = codeTooLarge
+	}
+	return resp
+}
+
+func encodeResponse(resp response) *C.char {
+	resp.Version = responseVersion
+	result, err := json.Marshal(resp)
+	if err != nil {
+		result, _ = json.Marshal(response{
+			Version: responseVersion,
+			Code:    codeMarshalFailed,
+			Message: "failed to marshal result: " + err.Error(),
+		})
+	}
+	return C.CString(string(result))
+}
+
+// recoverPanic keeps a panic in an export without a result from unwinding
+// into the C++ process
+func recoverPanic() {
+	recover()
+}
@@ -0,0 +1,240 @@
+package main
+
+import "C"
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/netip"
+	"strings"
+
+	CT "github.com/metacubex/mihomo/constant"
+	RC "github.com/metacubex/mihomo/rules/common"
+	"github.com/metacubex/mihomo/rules/logic"
+)
+
+// ruleCheckOptions are the optional settings of ValidateRules
+type ruleCheckOptions struct {
+	Mode     string `json:"mode"`     // rules (default) or payload
+	Behavior string `json:"behavior"` // payload behavior; detected if empty
+}
+
+// ruleCheck is the verdict for one rule line
+type ruleCheck struct {
+	Index      int    `json:"index"`
+	Rule       string `json:"rule"`
+	Type       string `json:"type,omitempty"`
+	Normalized string `json:"normalized,omitempty"`
+	Error      string `json:"error,omitempty"`
+}
+
+// ruleValidation is the result of ValidateRules
+type ruleValidation struct {
+	Mode     string         `json:"mode"`
+	Behavior string         `json:"behavior,omitempty"`
+	Rules    []ruleCheck    `json:"rules"`
+	Counts   map[string]int `json:"counts"` // valid rules per type
+	Valid    int            `json:"valid"`
+	Invalid  int            `json:"invalid"`
+}
+
+// parseNormalizedRule parses a rule offline and rebuilds it in canonical
+// form. Logic and SUB-RULE rules are rebuilt from their normalized
+// sub-rules.
+func parseNormalizedRule(tp, payload, target string, params []string) (CT.Rule, string, error) {
+	var children []string
+	parse := func(tp, payload, target string, params []string, _ map[string][]CT.Rule) (CT.Rule, error) {
+		rule, normalized, err := parseNormalizedRule(tp, payload, target, params)
+		if err == nil {
+			children = append(children, "("+normalized+")")
+		}
+		return rule, err
+	}
+
+	var (
+		rule CT.Rule
+		err  error
+	)
+	switch tp {
+	case "SUB-RULE":
+		rule, err = logic.NewSubRule(payload, target, nil, parse)
+	case "AND":
+		rule, err = logic.NewAND(payload, target, parse)
+	case "OR":
+		rule, err = logic.NewOR(payload, target, parse)
+	case "NOT":
+		rule, err = logic.NewNOT(payload, target, parse)
+	default:
+		rule, err = parseRuleOffline(tp, payload, target, params, nil)
+	}
+	if err != nil {
+		return nil, "", err
+	}
+
+	fields := []string{tp}
+	switch tp {
+	case "MATCH":
+	case "SUB-RULE":
+		fields = append(fields, strings.Join(children, ","))
+	case "AND", "OR", "NOT":
+		fields = append(fields, "("+strings.Join(children, ",")+")")
+	default:
+		fields = append(fields, rule.Payload())
+	}
+	if target != "" {
+		fields = append(fields, target)
+	}
+	fields = append(fields, params...)
+	return rule, strings.Join(fields, ","), nil
+}
+
+// checkRuleLine validates a config rule (needTarget) or a classical
+// rule-provider entry with mihomo's rule parser
+func checkRuleLine(line string, needTarget bool) ruleCheck {
+	check := ruleCheck{Rule: line}
+	tp, payload, target, params := RC.ParseRulePayload(line, needTarget)
+	check.Type = tp
+
+	switch {
+	case needTarget && target == "":
+		check.Error = "format invalid"
+		return check
+	case !needTarget && (tp == "MATCH" || tp == "RULE-SET" || tp == "SUB-RULE"):
+		check.Error = fmt.Sprintf("unsupported rule type on classical rule-set: %s", tp)
+		return check
+	}
+
+	_, normalized, err := parseNormalizedRule(tp, payload, target, params)
+	if err != nil {
+		check.Error = err.Error()
+		return check
+	}
+	check.Normalized = normalized
+	return check
+}
+
+// checkPayloadEntry validates an entry of a domain or ipcidr rule-provider
+func checkPayloadEntry(entry, behavior string) ruleCheck {
+	check := ruleCheck{Rule: entry}
+	normalized, ok := normalizeRuleSetEntry(entry, behavior)
+	if !ok {
+		check.Error = fmt.Sprintf("invalid %s entry", behavior)
+		return check
+	}
+	check.Normalized = normalized
+
+	switch behavior {
+	case "domain":
+		switch {
+		case strings.HasPrefix(normalized, "+.") || strings.HasPrefix(normalized, "."):
+			check.Type = "DOMAIN-SUFFIX"
+		case strings.Contains(normalized, "*"):
+			check.Type = "DOMAIN-WILDCARD"
+		default:
+			check.Type = "DOMAIN"
+		}
+	case "ipcidr":
+		check.Type = "IP-CIDR"
+		if prefix, err := netip.ParsePrefix(normalized); err == nil && !prefix.Addr().Is4() {
+			check.Type = "IP-CIDR6"
+		}
+	}
+	return check
+}
+
+// decodeRuleLines reads a JSON array of rules, or a YAML/text rule list.
+// Entries of a JSON array are kept one to one so indices match the input.
+func decodeRuleLines(buf []byte) ([]string, error) {
+	var lines []string
+	if json.Unmarshal(buf, &lines) == nil {
+		for i := range lines {
+			lines[i] = strings.TrimSpace(lines[i])
+		}
+		return lines, nil
+	}
+	entries, _, err := readRuleSetEntries(buf, "")
+	return entries, err
+}
+
+// validateRules checks rule lines one by one
+func validateRules(buf []byte, options ruleCheckOptions) (*ruleValidation, error) {
+	lines, err := decodeRuleLines(buf)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &ruleValidation{
+		Mode:     options.Mode,
+		Behavior: options.Behavior,
+		Rules:    make([]ruleCheck, 0, len(lines)),
+		Counts:   map[string]int{},
+	}
+	switch result.Mode {
+	case "":
+		result.Mode = "rules"
+		result.Behavior = ""
+	case "rules":
+		result.Behavior = ""
+	case "payload":
+		if result.Behavior == "" {
+			result.Behavior = inferRuleSetBehavior(lines)
+		}
+		if result.Behavior != "domain" && result.Behavior != "ipcidr" && result.Behavior != "classical" {
+			return nil, fmt.Errorf("unsupported behavior: %s", result.Behavior)
+		}
+	default:
+		return nil, errors.New("mode must be rules or payload")
+	}
+
+	for idx, line := range lines {
+		var check ruleCheck
+		switch result.Behavior {
+		case "domain", "ipcidr":
+			check = checkPayloadEntry(line, result.Behavior)
+		default:
+			check = checkRuleLine(line, result.Mode == "rules")
+		}
+		check.Index = idx
+
+		if check.Error != "" {
+			result.Invalid++
+		} else {
+			result.Valid++
+			result.Counts[check.Type]++
+		}
+		result.Rules = append(result.Rules, check)
+	}
+
+	return result, nil
+}
+
+// ValidateRules parses rule lines with mihomo's rule parser and reports
+// per-line errors, the normalized rule and counts per rule type. data is
+// a JSON array of rules or a YAML/text rule list. options is an optional
+// JSON object: "mode" is "rules" for config rules (TYPE,payload,target)
+// or "payload" for rule-provider entries, whose "behavior" is detected
+// when omitted.
+//
+//export ValidateRules
+func ValidateRules(data *C.char, options *C.char) *C.char {
+	return respond("ValidateRules", func() (any, []string, error) {
+		if data == nil {
+			return nil, nil, errNullInput
+		}
+
+		var opts ruleCheckOptions
+		if options != nil {
+			if optionsJSON := strings.TrimSpace(C.GoString(options)); optionsJSON != "" {
+				if err := json.Unmarshal([]byte(optionsJSON), &opts); err != nil {
+					return nil, nil, fmt.Errorf("invalid rule options: %w", err)
+				}
+			}
+		}
+
+		validation, err := validateRules([]byte(C.GoString(data)), opts)
+		if err != nil {
+			return nil, nil, err
+		}
+		return validation, nil, nil
+	})
+}
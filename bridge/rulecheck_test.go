@@ -0,0 +1,104 @@
+package main
+
+import (
+	"testing"
+
+	R "github.com/metacubex/mihomo/rules"
+	RC "github.com/metacubex/mihomo/rules/common"
+)
+
+func TestCheckRuleLineMatchesMihomo(t *testing.T) {
+	rules := []string{
+		"DOMAIN-SUFFIX,google.com,Proxy",
+		"DOMAIN,example.com,DIRECT",
+		"IP-CIDR,10.0.0.0/8,DIRECT,no-resolve",
+		"IP-CIDR,10.0.0.300/8,DIRECT",
+		"DST-PORT,80-443,Proxy",
+		"DST-PORT,http,Proxy",
+		"DOMAIN-REGEX,[,Proxy",
+		"NETWORK,udp,REJECT",
+		"MATCH,Proxy",
+		"UNKNOWN,x,Proxy",
+		"AND,((DOMAIN,a.com),(NETWORK,tcp)),Proxy",
+		"OR,((DOMAIN,a.com),(DST-PORT,x)),Proxy",
+		"NOT,((DOMAIN,a.com)),Proxy",
+		"AND,(DOMAIN,a.com),Proxy",
+		"OR,(NETWORK,udp),Proxy",
+	}
+
+	for _, line := range rules {
+		t.Run(line, func(t *testing.T) {
+			tp, payload, target, params := RC.ParseRulePayload(line, true)
+			_, mihomoErr := R.ParseRule(tp, payload, target, params, nil)
+
+			check := checkRuleLine(line, true)
+			if (check.Error == "") != (mihomoErr == nil) {
+				t.Errorf("checkRuleLine error = %q, mihomo error = %v", check.Error, mihomoErr)
+			}
+			if check.Error != "" {
+				return
+			}
+
+			// The normalized rule parses to the same rule
+			tp, payload, target, params = RC.ParseRulePayload(check.Normalized, true)
+			if _, err := R.ParseRule(tp, payload, target, params, nil); err != nil {
+				t.Errorf("normalized rule %q: %v", check.Normalized, err)
+			}
+		})
+	}
+}
+
+func TestValidateRulesPayload(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		options  ruleCheckOptions
+		behavior string
+		types    []string // Type of each entry; empty when rejected
+	}{
+		{
+			name:     "domain",
+			input:    `["+.google.com", "example.com", "*.a.com", "a.com/path"]`,
+			options:  ruleCheckOptions{Mode: "payload"},
+			behavior: "domain",
+			types:    []string{"DOMAIN-SUFFIX", "DOMAIN", "DOMAIN-WILDCARD", ""},
+		},
+		{
+			name:     "ipcidr",
+			input:    "payload:\n  - 10.0.0.0/8\n  - 2001:db8::/32\n  - 300.0.0.0/8\n",
+			options:  ruleCheckOptions{Mode: "payload", Behavior: "ipcidr"},
+			behavior: "ipcidr",
+			types:    []string{"IP-CIDR", "IP-CIDR6", ""},
+		},
+		{
+			name:     "classical",
+			input:    "DOMAIN,a.com\nMATCH\nIP-CIDR,10.0.0.0/8,no-resolve\n",
+			options:  ruleCheckOptions{Mode: "payload"},
+			behavior: "classical",
+			types:    []string{"DOMAIN", "", "IP-CIDR"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := validateRules([]byte(tt.input), tt.options)
+			if err != nil {
+				t.Fatalf("validateRules: %v", err)
+			}
+			if result.Behavior != tt.behavior {
+				t.Errorf("behavior = %q, want %q", result.Behavior, tt.behavior)
+			}
+			if len(result.Rules) != len(tt.types) {
+				t.Fatalf("got %d rules, want %d", len(result.Rules), len(tt.types))
+			}
+			for i, check := range result.Rules {
+				if check.Error != "" {
+					check.Type = ""
+				}
+				if check.Type != tt.types[i] {
+					t.Errorf("rule %d (%q) type = %q, error %q, want %q", i, check.Rule, check.Type, check.Error, tt.types[i])
+				}
+			}
+		})
+	}
+}
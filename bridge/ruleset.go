@@ -0,0 +1,336 @@
+package main
+
+import "C"
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/netip"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/metacubex/mihomo/component/trie"
+	P "github.com/metacubex/mihomo/constant/provider"
+	RP "github.com/metacubex/mihomo/rules/provider"
+	"gopkg.in/yaml.v3"
+)
+
+// Rule types that map onto the domain and ipcidr behaviors
+var (
+	domainRuleTypes = map[string]string{"DOMAIN": "", "DOMAIN-SUFFIX": "+."}
+	ipcidrRuleTypes = map[string]bool{"IP-CIDR": true, "IP-CIDR6": true}
+)
+
+// ruleSetOptions are the optional settings of ConvertRuleSetToMrs. Empty
+// values are detected from the payload.
+type ruleSetOptions struct {
+	Behavior string `json:"behavior"` // domain, ipcidr or classical
+	Format   string `json:"format"`   // yaml or text
+}
+
+// mrsRuleSet is a rule payload compiled to MRS
+type mrsRuleSet struct {
+	Behavior string   `json:"behavior"`
+	Format   string   `json:"format"`
+	Count    int      `json:"count"`
+	Invalid  []string `json:"invalid,omitempty"`
+	MRS      string   `json:"mrs"` // base64 of the MRS bytes
+}
+
+// ruleSetPayload is the text form of a decoded MRS file
+type ruleSetPayload struct {
+	Behavior string   `json:"behavior"`
+	Count    int      `json:"count"`
+	Payload  []string `json:"payload"`
+}
+
+// readRuleSetEntries splits a YAML (payload:/rules:) or text rule list
+// into entries, skipping blank lines and comments
+func readRuleSetEntries(buf []byte, format string) ([]string, string, error) {
+	if format == "" {
+		format = "text"
+		schema := &RP.RulePayload{}
+		if yaml.Unmarshal(buf, schema) == nil && (schema.Payload != nil || schema.Rules != nil) {
+			format = "yaml"
+		}
+	}
+
+	var lines []string
+	switch format {
+	case "yaml":
+		schema := &RP.RulePayload{}
+		if err := yaml.Unmarshal(buf, schema); err != nil {
+			return nil, format, fmt.Errorf("invalid YAML rule payload: %w", err)
+		}
+		if schema.Payload == nil && schema.Rules == nil {
+			return nil, format, RP.ErrNoPayload
+		}
+		lines = append(schema.Payload, schema.Rules...)
+	case "text":
+		lines = strings.Split(string(buf), "\n")
+	default:
+		return nil, format, fmt.Errorf("unsupported format type: %s", format)
+	}
+
+	entries := make([]string, 0, len(lines))
+	for _, line := range lines {
+		line = strings.TrimSpace(line)
+		if line == "" || line[0] == '#' || line[0] == ';' || strings.HasPrefix(line, "//") {
+			continue
+		}
+		entries = append(entries, line)
+	}
+	return entries, format, nil
+}
+
+// splitClassicalRule splits "TYPE,value[,options]". ok is false for
+// entries that are not classical rules.
+func splitClassicalRule(entry string) (ruleType, value string, ok bool) {
+	ruleType, rest, found := strings.Cut(entry, ",")
+	if !found || ruleType == "" || strings.ToUpper(ruleType) != ruleType || strings.ContainsAny(ruleType, " .") {
+		return "", "", false
+	}
+	value, _, _ = strings.Cut(rest, ",")
+	return ruleType, strings.TrimSpace(value), true
+}
+
+// inferRuleSetBehavior picks the narrowest behavior able to hold all
+// entries. Classical lists made only of DOMAIN/DOMAIN-SUFFIX or
+// IP-CIDR/IP-CIDR6 rules are reported as domain or ipcidr.
+func inferRuleSetBehavior(entries []string) string {
+	domains, cidrs, classical := 0, 0, 0
+	for _, entry := range entries {
+		ruleType, _, ok := splitClassicalRule(entry)
+		_, isDomainRule := domainRuleTypes[ruleType]
+		switch {
+		case !ok && isIPCidrEntry(entry):
+			cidrs++
+		case !ok:
+			domains++
+		case ipcidrRuleTypes[ruleType]:
+			cidrs++
+		case isDomainRule:
+			domains++
+		default:
+			classical++
+		}
+	}
+
+	switch {
+	case classical > 0 || (domains > 0 && cidrs > 0):
+		return "classical"
+	case cidrs > 0:
+		return "ipcidr"
+	}
+	return "domain"
+}
+
+func isIPCidrEntry(entry string) bool {
+	if _, err := netip.ParsePrefix(entry); err == nil {
+		return true
+	}
+	_, err := netip.ParseAddr(entry)
+	return err == nil
+}
+
+// normalizeRuleSetEntry rewrites an entry to the payload syntax of the
+// behavior. ok is false for entries mihomo would drop.
+func normalizeRuleSetEntry(entry, behavior string) (string, bool) {
+	ruleType, value, classical := splitClassicalRule(entry)
+
+	switch behavior {
+	case "domain":
+		if classical {
+			prefix, ok := domainRuleTypes[ruleType]
+			if !ok {
+				return entry, false
+			}
+			entry = prefix + value
+		}
+		if _, ok := trie.ValidAndSplitDomain(strings.TrimPrefix(entry, "+.")); !ok || strings.ContainsRune(entry, '/') {
+			return entry, false
+		}
+	case "ipcidr":
+		if classical {
+			if !ipcidrRuleTypes[ruleType] {
+				return entry, false
+			}
+			entry = value
+		}
+		if addr, err := netip.ParseAddr(entry); err == nil {
+			entry = netip.PrefixFrom(addr, addr.BitLen()).String()
+		}
+		if _, err := netip.ParsePrefix(entry); err != nil {
+			return entry, false
+		}
+	}
+	return entry, true
+}
+
+// normalizeRuleSetEntries rewrites entries to the payload syntax of the
+// behavior and returns the ones mihomo would drop
+func normalizeRuleSetEntries(entries []string, behavior string) (payload, invalid []string) {
+	for _, entry := range entries {
+		normalized, ok := normalizeRuleSetEntry(entry, behavior)
+		if !ok {
+			invalid = append(invalid, normalized)
+			continue
+		}
+		payload = append(payload, normalized)
+	}
+	return payload, invalid
+}
+
+// compileRuleSetMrs converts a text or YAML rule payload to MRS with
+// mihomo's rule-provider converter
+func compileRuleSetMrs(buf []byte, options ruleSetOptions) (*mrsRuleSet, error) {
+	entries, format, err := readRuleSetEntries(buf, options.Format)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &mrsRuleSet{Behavior: options.Behavior, Format: format}
+	if result.Behavior == "" {
+		result.Behavior = inferRuleSetBehavior(entries)
+	}
+	behavior, err := P.ParseBehavior(result.Behavior)
+	if err != nil {
+		return nil, err
+	}
+	if behavior == P.Classical {
+		return nil, errors.New("classical rule-sets cannot be stored as MRS, only domain and ipcidr can")
+	}
+
+	payload, invalid := normalizeRuleSetEntries(entries, result.Behavior)
+	result.Invalid = invalid
+	if len(payload) == 0 {
+		return nil, errors.New("empty rule")
+	}
+
+	var out bytes.Buffer
+	if err := RP.ConvertToMrs([]byte(strings.Join(payload, "\n")), behavior, P.TextRule, &out); err != nil {
+		return nil, err
+	}
+	result.Count = len(payload)
+	result.MRS = base64.StdEncoding.EncodeToString(out.Bytes())
+	return result, nil
+}
+
+// readMrsHeader returns the behavior and rule count stored in the header
+// of an MRS file
+func readMrsHeader(buf []byte) (P.RuleBehavior, int, error) {
+	reader, err := zstd.NewReader(bytes.NewReader(buf))
+	if err != nil {
+		return 0, 0, err
+	}
+	defer reader.Close()
+
+	// magic (4) + behavior (1) + count (8)
+	var header [13]byte
+	if _, err := io.ReadFull(reader, header[:]); err != nil {
+		return 0, 0, fmt.Errorf("invalid MRS data: %w", err)
+	}
+	if [4]byte(header[:4]) != RP.MrsMagicBytes {
+		return 0, 0, errors.New("invalid MrsMagic bytes")
+	}
+
+	var behavior P.RuleBehavior
+	switch header[4] {
+	case P.Domain.Byte():
+		behavior = P.Domain
+	case P.IPCIDR.Byte():
+		behavior = P.IPCIDR
+	default:
+		return 0, 0, errors.New("invalid behavior")
+	}
+
+	var count int64
+	for _, b := range header[5:] {
+		count = count<<8 | int64(b)
+	}
+	return behavior, int(count), nil
+}
+
+// decodeRuleSetMrs expands MRS bytes back to a text payload
+func decodeRuleSetMrs(buf []byte) (*ruleSetPayload, error) {
+	behavior, count, err := readMrsHeader(buf)
+	if err != nil {
+		return nil, err
+	}
+
+	var out bytes.Buffer
+	if err := RP.ConvertToMrs(buf, behavior, P.MrsRule, &out); err != nil {
+		return nil, err
+	}
+
+	result := &ruleSetPayload{
+		Behavior: strings.ToLower(behavior.String()),
+		Count:    count,
+		Payload:  make([]string, 0, count),
+	}
+	for _, line := range strings.Split(strings.TrimRight(out.String(), "\n"), "\n") {
+		if line != "" {
+			result.Payload = append(result.Payload, line)
+		}
+	}
+	return result, nil
+}
+
+// decodeRuleSetMrsBase64 expands base64-encoded MRS bytes, as returned by
+// ConvertRuleSetToMrs, back to a text payload
+func decodeRuleSetMrsBase64(data string) (*ruleSetPayload, error) {
+	buf, err := base64.StdEncoding.DecodeString(strings.TrimSpace(data))
+	if err != nil {
+		return nil, fmt.Errorf("MRS data is not valid base64: %w", err)
+	}
+	return decodeRuleSetMrs(buf)
+}
+
+// ConvertRuleSetToMrs compiles a text or YAML rule payload (including
+// Surge/classical lists) to MRS. options is an optional JSON object with
+// "behavior" and "format"; both are detected when omitted. The MRS bytes
+// are returned base64-encoded.
+//
+//export ConvertRuleSetToMrs
+func ConvertRuleSetToMrs(data *C.char, options *C.char) *C.char {
+	return respond("ConvertRuleSetToMrs", func() (any, []string, error) {
+		if data == nil {
+			return nil, nil, errNullInput
+		}
+
+		var opts ruleSetOptions
+		if options != nil {
+			if optionsJSON := strings.TrimSpace(C.GoString(options)); optionsJSON != "" {
+				if err := json.Unmarshal([]byte(optionsJSON), &opts); err != nil {
+					return nil, nil, fmt.Errorf("invalid rule-set options: %w", err)
+				}
+			}
+		}
+
+		compiled, err := compileRuleSetMrs([]byte(C.GoString(data)), opts)
+		if err != nil {
+			return nil, nil, err
+		}
+		return compiled, nil, nil
+	})
+}
+
+// ConvertMrsToRuleSet decodes base64-encoded MRS bytes into a text payload
+//
+//export ConvertMrsToRuleSet
+func ConvertMrsToRuleSet(data *C.char) *C.char {
+	return respond("ConvertMrsToRuleSet", func() (any, []string, error) {
+		if data == nil {
+			return nil, nil, errNullInput
+		}
+
+		decoded, err := decodeRuleSetMrsBase64(C.GoString(data))
+		if err != nil {
+			return nil, nil, err
+		}
+		return decoded, nil, nil
+	})
+}
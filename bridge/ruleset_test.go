@@ -0,0 +1,160 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+func TestRuleSetMrsRoundTrip(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		options  ruleSetOptions
+		behavior string
+		format   string
+		payload  []string // Decoded payload, sorted
+		invalid  []string
+	}{
+		{
+			name:     "domain text",
+			input:    "# comment\n+.google.com\nexample.com\n\nDOMAIN-SUFFIX,github.com\nDOMAIN,a.org\n",
+			behavior: "domain",
+			format:   "text",
+			payload:  []string{"+.github.com", "+.google.com", "a.org", "example.com"},
+		},
+		{
+			name:     "domain yaml with invalid entries",
+			input:    "payload:\n  - '+.google.com'\n  - a.com/path\n  - DOMAIN-KEYWORD,google\n",
+			options:  ruleSetOptions{Behavior: "domain"},
+			behavior: "domain",
+			format:   "yaml",
+			payload:  []string{"+.google.com"},
+			invalid:  []string{"a.com/path", "DOMAIN-KEYWORD,google"},
+		},
+		{
+			name:     "ipcidr",
+			input:    "10.0.0.0/8\nIP-CIDR,192.168.0.0/16,no-resolve\nIP-CIDR6,2001:db8::/32\n1.1.1.1\n",
+			behavior: "ipcidr",
+			format:   "text",
+			payload:  []string{"1.1.1.1/32", "10.0.0.0/8", "192.168.0.0/16", "2001:db8::/32"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			compiled, err := compileRuleSetMrs([]byte(tt.input), tt.options)
+			if err != nil {
+				t.Fatalf("compileRuleSetMrs: %v", err)
+			}
+			if compiled.Behavior != tt.behavior || compiled.Format != tt.format || compiled.Count != len(tt.payload) {
+				t.Errorf("compiled %s %s, %d rules, want %s %s, %d rules",
+					compiled.Behavior, compiled.Format, compiled.Count, tt.behavior, tt.format, len(tt.payload))
+			}
+			if !slices.Equal(compiled.Invalid, tt.invalid) {
+				t.Errorf("invalid = %q, want %q", compiled.Invalid, tt.invalid)
+			}
+
+			decoded, err := decodeRuleSetMrsBase64(compiled.MRS)
+			if err != nil {
+				t.Fatalf("decodeRuleSetMrsBase64: %v", err)
+			}
+			slices.Sort(decoded.Payload)
+			if decoded.Behavior != tt.behavior || decoded.Count != len(tt.payload) || !slices.Equal(decoded.Payload, tt.payload) {
+				t.Errorf("decoded %s, %d rules %q, want %s, %d rules %q",
+					decoded.Behavior, decoded.Count, decoded.Payload, tt.behavior, len(tt.payload), tt.payload)
+			}
+
+			// Compiling the decoded payload again keeps every rule
+			again, err := compileRuleSetMrs([]byte(strings.Join(decoded.Payload, "\n")), ruleSetOptions{Behavior: tt.behavior})
+			if err != nil {
+				t.Fatalf("compileRuleSetMrs of the decoded payload: %v", err)
+			}
+			redecoded, err := decodeRuleSetMrsBase64(again.MRS)
+			if err != nil {
+				t.Fatalf("decodeRuleSetMrsBase64: %v", err)
+			}
+			slices.Sort(redecoded.Payload)
+			if !slices.Equal(redecoded.Payload, tt.payload) {
+				t.Errorf("second round trip = %q, want %q", redecoded.Payload, tt.payload)
+			}
+		})
+	}
+}
+
+func TestRuleSetMrsErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		options ruleSetOptions
+		want    string
+	}{
+		{
+			name:  "classical",
+			input: "DOMAIN,a.com\nPROCESS-NAME,curl\n",
+			want:  "classical rule-sets cannot be stored as MRS, only domain and ipcidr can",
+		},
+		{
+			name:    "unknown behavior",
+			input:   "a.com\n",
+			options: ruleSetOptions{Behavior: "foo"},
+			want:    "unsupported behavior type: foo",
+		},
+		{
+			name:    "unknown format",
+			input:   "a.com\n",
+			options: ruleSetOptions{Format: "json"},
+			want:    "unsupported format type: json",
+		},
+		{
+			name:    "no valid entry",
+			input:   "a.com/path\n",
+			options: ruleSetOptions{Behavior: "domain"},
+			want:    "empty rule",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := compileRuleSetMrs([]byte(tt.input), tt.options); err == nil || err.Error() != tt.want {
+				t.Errorf("compileRuleSetMrs = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecodeRuleSetMrsErrors(t *testing.T) {
+	compress := func(data []byte) string {
+		var buf bytes.Buffer
+		w, err := zstd.NewWriter(&buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write(data)
+		w.Close()
+		return base64.StdEncoding.EncodeToString(buf.Bytes())
+	}
+
+	tests := []struct {
+		name string
+		data string
+		want string
+	}{
+		{name: "not base64", data: "not base64!", want: "MRS data is not valid base64"},
+		{name: "not zstd", data: base64.StdEncoding.EncodeToString([]byte("MRS!")), want: "invalid MRS data"},
+		{name: "bad magic", data: compress([]byte("XXXX\x00\x00\x00\x00\x00\x00\x00\x00\x00")), want: "invalid MrsMagic bytes"},
+		{name: "bad behavior", data: compress([]byte("MRS\x01\x07\x00\x00\x00\x00\x00\x00\x00\x00")), want: "invalid behavior"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := decodeRuleSetMrsBase64(tt.data); err == nil || !strings.HasPrefix(err.Error(), tt.want) {
+				t.Errorf("decodeRuleSetMrsBase64 = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,222 @@
+package main
+
+import "C"
+import (
+	"fmt"
+	"sync"
+)
+
+// conversionSession accumulates subscription inputs across FeedSession
+// calls so that they cross the cgo boundary once when finalized
+type conversionSession struct {
+	mu      sync.Mutex
+	opts    convertOptions // max_nodes and dedup apply across all sources
+	sources []*sessionSource
+}
+
+// sessionSource is one fed input with the proxies parsed from it
+type sessionSource struct {
+	ID        string           `json:"id"`
+	Group     int              `json:"group"`
+	Total     int              `json:"total"`
+	Parsed    int              `json:"parsed"`
+	Failed    int              `json:"failed"`
+	Errors    []lineDiagnostic `json:"errors"`
+	Truncated bool             `json:"truncated,omitempty"` // The conversion timeout passed before the last line
+	proxies   []map[string]any
+}
+
+// sessionProxy is a proxy tagged with the source it came from
+type sessionProxy struct {
+	Source       string         `json:"source"`
+	Group        int            `json:"group"`
+	OriginalName string         `json:"original_name,omitempty"` // set if renamed by de-duplication
+	Proxy        map[string]any `json:"proxy"`
+}
+
+// sessionResult is returned by FinalizeSession
+type sessionResult struct {
+	Proxies   []sessionProxy   `json:"proxies"`
+	Sources   []*sessionSource `json:"sources"`
+	Truncated bool             `json:"truncated,omitempty"` // max_nodes was reached
+}
+
+var (
+	sessionsMu    sync.Mutex
+	sessions      = map[int64]*conversionSession{}
+	nextSessionID int64
+)
+
+func lookupSession(handle int64) *conversionSession {
+	sessionsMu.Lock()
+	defer sessionsMu.Unlock()
+	return sessions[handle]
+}
+
+func removeSession(handle int64) *conversionSession {
+	sessionsMu.Lock()
+	defer sessionsMu.Unlock()
+	session := sessions[handle]
+	delete(sessions, handle)
+	return session
+}
+
+// feed parses one input under the session options and records its
+// per-line errors
+func (s *conversionSession) feed(id string, group int, content string) (*sessionSource, []string, error) {
+	ctx, cancel, err := s.opts.context()
+	if err != nil {
+		return nil, nil, err
+	}
+	defer cancel()
+
+	// Names and the node limit are settled across sources by finalize
+	opts := s.opts
+	opts.MaxNodes = 0
+	opts.Dedup = dedupKeep
+	diagnostics, warnings, err := convertSubscriptionEx(ctx, content, opts)
+	if err != nil {
+		return nil, nil, err
+	}
+	source := &sessionSource{
+		ID:        id,
+		Group:     group,
+		Total:     diagnostics.Summary.Total,
+		Parsed:    diagnostics.Summary.Parsed,
+		Errors:    make([]lineDiagnostic, 0),
+		Truncated: diagnostics.Truncated,
+		proxies:   diagnostics.Proxies,
+	}
+	for _, line := range diagnostics.Lines {
+		if line.Status == lineParsed || line.Code == "empty_line" {
+			continue
+		}
+		if line.Status == lineFailed {
+			source.Failed++
+		}
+		source.Errors = append(source.Errors, line)
+	}
+
+	s.mu.Lock()
+	s.sources = append(s.sources, source)
+	s.mu.Unlock()
+	return source, warnings, nil
+}
+
+// finalize merges all sources in feed order, de-duplicates proxy names
+// across sources and stops at max_nodes
+func (s *conversionSession) finalize() (*sessionResult, []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	result := &sessionResult{
+		Proxies: make([]sessionProxy, 0),
+		Sources: make([]*sessionSource, 0, len(s.sources)),
+	}
+	var warnings []string
+	var left int
+	names := make(map[string]int)
+	for _, source := range s.sources {
+		for _, proxy := range source.proxies {
+			if s.opts.MaxNodes > 0 && len(result.Proxies) >= s.opts.MaxNodes {
+				result.Truncated = true
+				left++
+				continue
+			}
+			entry := sessionProxy{Source: source.ID, Group: source.Group, Proxy: proxy}
+			name := anyToString(proxy["name"])
+			unique, ok := s.opts.proxyName(names, name)
+			if !ok {
+				warnings = append(warnings, fmt.Sprintf("proxy '%s' from %s left out: the name is already taken", name, source.ID))
+				continue
+			}
+			if unique != name {
+				entry.OriginalName = name
+				proxy["name"] = unique
+			}
+			result.Proxies = append(result.Proxies, entry)
+		}
+		result.Sources = append(result.Sources, source)
+	}
+	if left > 0 {
+		warnings = append(warnings, maxNodesWarning(s.opts.MaxNodes, left))
+	}
+	return result, warnings
+}
+
+// newSession registers a session converting under opts
+func newSession(opts convertOptions) int64 {
+	sessionsMu.Lock()
+	defer sessionsMu.Unlock()
+	nextSessionID++
+	sessions[nextSessionID] = &conversionSession{opts: opts}
+	return nextSessionID
+}
+
+// CreateSession starts a batch conversion session and returns its handle
+//
+//export CreateSession
+func CreateSession() C.longlong {
+	defer recoverPanic()
+	return C.longlong(newSession(convertOptions{}))
+}
+
+// CreateSessionEx starts a session whose inputs are converted under the
+// JSON options of ConvertSubscriptionEx. max_nodes and dedup apply to the
+// finalized proxies of all sources, the other options to each input.
+//
+//export CreateSessionEx
+func CreateSessionEx(options *C.char) *C.char {
+	return respond("CreateSessionEx", func() (any, []string, error) {
+		var optionsJSON string
+		if options != nil {
+			optionsJSON = C.GoString(options)
+		}
+		opts, err := decodeConvertOptions(optionsJSON)
+		if err != nil {
+			return nil, nil, err
+		}
+		return map[string]int64{"handle": newSession(opts)}, nil, nil
+	})
+}
+
+// FeedSession parses subscription content into a session, tagged with a
+// source id and group id. It returns the per-source summary and errors.
+//
+//export FeedSession
+func FeedSession(handle C.longlong, source *C.char, group C.int, data *C.char) *C.char {
+	return respond("FeedSession", func() (any, []string, error) {
+		if source == nil || data == nil {
+			return nil, nil, errNullInput
+		}
+		session := lookupSession(int64(handle))
+		if session == nil {
+			return nil, nil, errUnknownSession
+		}
+
+		return session.feed(C.GoString(source), int(group), C.GoString(data))
+	})
+}
+
+// FinalizeSession returns every proxy fed into the session with its
+// source attribution, plus the per-source reports, and closes the session
+//
+//export FinalizeSession
+func FinalizeSession(handle C.longlong) *C.char {
+	return respond("FinalizeSession", func() (any, []string, error) {
+		session := removeSession(int64(handle))
+		if session == nil {
+			return nil, nil, errUnknownSession
+		}
+		result, warnings := session.finalize()
+		return result, warnings, nil
+	})
+}
+
+// CloseSession discards a session without finalizing it
+//
+//export CloseSession
+func CloseSession(handle C.longlong) {
+	defer recoverPanic()
+	removeSession(int64(handle))
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestSessionFinalizeUniqueNames(t *testing.T) {
+	tests := []struct {
+		name    string
+		sources []string
+		want    []string
+	}{
+		{
+			name:    "repeated across sources",
+			sources: []string{"trojan://pw@a.com:443#HK", "trojan://pw@b.com:443#HK"},
+			want:    []string{"HK", "HK-01"},
+		},
+		{
+			name: "input collides with a generated name",
+			sources: []string{
+				"trojan://pw@a.com:443#HK",
+				"trojan://pw@b.com:443#HK",
+				"trojan://pw@c.com:443#HK-01",
+			},
+			want: []string{"HK", "HK-01", "HK-01-01"},
+		},
+		{
+			name: "generated name collides with an input name",
+			sources: []string{
+				"trojan://pw@a.com:443#HK-01",
+				"trojan://pw@b.com:443#HK",
+				"trojan://pw@c.com:443#HK",
+			},
+			want: []string{"HK-01", "HK", "HK-02"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			session := &conversionSession{}
+			for i, content := range tt.sources {
+				if _, _, err := session.feed(string(rune('a'+i)), 0, content); err != nil {
+					t.Fatalf("feed %q: %v", content, err)
+				}
+			}
+
+			var got []string
+			result, _ := session.finalize()
+			for _, proxy := range result.Proxies {
+				got = append(got, anyToString(proxy.Proxy["name"]))
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("names = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSessionOptionsApplyAcrossSources(t *testing.T) {
+	sources := []string{
+		"trojan://pw@a.com:443#HK",
+		"vmess://" + "eyJhZGQiOiJiLmNvbSIsInBvcnQiOiI0NDMiLCJpZCI6ImIxYjYyNWI2LTgyMjYtNGE2Yi1hZDRlLTQ5MDhjM2QyZWRiYSIsInBzIjoiSksifQ==",
+		"trojan://pw@c.com:443#HK",
+		"trojan://pw@d.com:443#SG",
+	}
+	tests := []struct {
+		name      string
+		opts      convertOptions
+		want      []string
+		truncated bool
+	}{
+		{name: "defaults", want: []string{"HK", "JK", "HK-01", "SG"}},
+		{name: "drop repeated names", opts: convertOptions{Dedup: dedupDrop}, want: []string{"HK", "JK", "SG"}},
+		{name: "allowed schemes", opts: convertOptions{AllowedSchemes: []string{"trojan"}}, want: []string{"HK", "HK-01", "SG"}},
+		{name: "max nodes", opts: convertOptions{MaxNodes: 2}, want: []string{"HK", "JK"}, truncated: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			session := &conversionSession{opts: tt.opts}
+			for i, content := range sources {
+				if _, _, err := session.feed(string(rune('a'+i)), 0, content); err != nil {
+					t.Fatalf("feed %q: %v", content, err)
+				}
+			}
+
+			result, _ := session.finalize()
+			var got []string
+			for _, proxy := range result.Proxies {
+				got = append(got, anyToString(proxy.Proxy["name"]))
+			}
+			if !slices.Equal(got, tt.want) || result.Truncated != tt.truncated {
+				t.Errorf("names = %q, truncated = %v, want %q, %v", got, result.Truncated, tt.want, tt.truncated)
+			}
+		})
+	}
+}
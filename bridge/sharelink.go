@@ -0,0 +1,696 @@
+package main
+
+import "C"
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// shareLink is the export result for a single proxy
+type shareLink struct {
+	Name        string   `json:"name"`
+	Type        string   `json:"type"`
+	Link        string   `json:"link,omitempty"`
+	Unsupported []string `json:"unsupported,omitempty"`
+	Error       string   `json:"error,omitempty"`
+}
+
+// exportShareLink converts one mihomo proxy map back to a share link.
+// Fields that the link format cannot carry are listed in Unsupported.
+func exportShareLink(proxy map[string]any) shareLink {
+	p := newProxyFields(proxy)
+	result := shareLink{Name: p.str("name"), Type: p.str("type")}
+
+	var (
+		link string
+		err  error
+	)
+	switch result.Type {
+	case "vless":
+		link, err = vlessShareLink(p)
+	case "vmess":
+		link, err = vmessShareLink(p)
+	case "trojan":
+		link, err = trojanShareLink(p)
+	case "ss":
+		link, err = ssShareLink(p)
+	case "ssr":
+		link, err = ssrShareLink(p)
+	case "hysteria":
+		link, err = hysteriaShareLink(p)
+	case "hysteria2":
+		link, err = hysteria2ShareLink(p)
+	case "tuic":
+		link, err = tuicShareLink(p)
+	case "anytls":
+		link, err = anytlsShareLink(p)
+	case "socks5", "http":
+		link, err = socksHTTPShareLink(p)
+	case "wireguard":
+		link, err = wireGuardShareLink(p)
+	case "ssh":
+		link, err = sshShareLink(p)
+	case "snell":
+		link, err = snellShareLink(p)
+	case "mieru":
+		link, err = mieruShareLink(p)
+	default:
+		err = fmt.Errorf("proxy type %q has no share link format", result.Type)
+	}
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+
+	result.Link = link
+	result.Unsupported = p.unused()
+	return result
+}
+
+// hostPort reads server/port and joins them, bracketing IPv6 literals
+func hostPort(p *proxyFields) (string, error) {
+	server, port := p.str("server"), p.str("port")
+	if server == "" {
+		return "", fmt.Errorf("missing server")
+	}
+	if port == "" {
+		return "", fmt.Errorf("missing port")
+	}
+	return net.JoinHostPort(server, port), nil
+}
+
+// buildLink assembles scheme://userinfo@host?query#name
+func buildLink(scheme string, user *url.Userinfo, host string, query url.Values, name string) string {
+	u := url.URL{
+		Scheme:   scheme,
+		User:     user,
+		Host:     host,
+		RawQuery: query.Encode(),
+		Fragment: name,
+	}
+	return u.String()
+}
+
+func setIfNotEmpty(query url.Values, key, value string) {
+	if value != "" {
+		query.Set(key, value)
+	}
+}
+
+// applyTransportQuery writes Xray-style transport parameters shared by
+// vless and trojan links
+func applyTransportQuery(p *proxyFields, query url.Values) {
+	network := p.str("network")
+	switch network {
+	case "", "tcp":
+		query.Set("type", "tcp")
+	case "http":
+		// mihomo's "http" network is tcp with an HTTP header disguise
+		query.Set("type", "tcp")
+		query.Set("headerType", "http")
+		setIfNotEmpty(query, "path", strings.Join(p.strs("http-opts.path"), ","))
+		setIfNotEmpty(query, "host", strings.Join(p.strs("http-opts.headers.Host"), ","))
+		setIfNotEmpty(query, "method", p.str("http-opts.method"))
+	case "h2":
+		query.Set("type", "http")
+		setIfNotEmpty(query, "path", p.str("h2-opts.path"))
+		setIfNotEmpty(query, "host", strings.Join(p.strs("h2-opts.host"), ","))
+	case "ws", "httpupgrade":
+		query.Set("type", network)
+		setIfNotEmpty(query, "path", p.str("ws-opts.path"))
+		setIfNotEmpty(query, "host", p.str("ws-opts.headers.Host"))
+		// ConvertsV2Ray injects a random User-Agent on import
+		p.use("ws-opts.headers.User-Agent")
+		if ed := p.integer("ws-opts.max-early-data"); ed > 0 {
+			query.Set("ed", strconv.Itoa(ed))
+		}
+		if eh := p.str("ws-opts.early-data-header-name"); eh != "" && eh != "Sec-WebSocket-Protocol" {
+			query.Set("eh", eh)
+		}
+		if network == "httpupgrade" && p.boolean("ws-opts.v2ray-http-upgrade-fast-open") && query.Get("ed") == "" {
+			query.Set("ed", "2048")
+		}
+	case "grpc":
+		query.Set("type", "grpc")
+		setIfNotEmpty(query, "serviceName", p.str("grpc-opts.grpc-service-name"))
+	case "xhttp":
+		query.Set("type", "xhttp")
+		setIfNotEmpty(query, "path", p.str("xhttp-opts.path"))
+		setIfNotEmpty(query, "host", p.str("xhttp-opts.host"))
+		setIfNotEmpty(query, "mode", p.str("xhttp-opts.mode"))
+	default:
+		query.Set("type", network)
+	}
+}
+
+// applyTLSQuery writes security/sni/fingerprint/reality parameters
+func applyTLSQuery(p *proxyFields, query url.Values, sniKey string) {
+	publicKey := p.str("reality-opts.public-key")
+	switch {
+	case publicKey != "":
+		query.Set("security", "reality")
+		query.Set("pbk", publicKey)
+		setIfNotEmpty(query, "sid", p.str("reality-opts.short-id"))
+		p.use("tls")
+	case p.boolean("tls"):
+		query.Set("security", "tls")
+	default:
+		query.Set("security", "none")
+		return
+	}
+	setIfNotEmpty(query, "sni", p.str(sniKey))
+	setIfNotEmpty(query, "fp", p.str("client-fingerprint"))
+	setIfNotEmpty(query, "alpn", strings.Join(p.strs("alpn"), ","))
+	setIfNotEmpty(query, "pcs", p.str("fingerprint"))
+}
+
+func vlessShareLink(p *proxyFields) (string, error) {
+	host, err := hostPort(p)
+	if err != nil {
+		return "", err
+	}
+	uuid := p.str("uuid")
+	if uuid == "" {
+		return "", fmt.Errorf("missing uuid")
+	}
+
+	query := url.Values{}
+	encryption := p.str("encryption")
+	if encryption == "" {
+		encryption = "none"
+	}
+	query.Set("encryption", encryption)
+	setIfNotEmpty(query, "flow", p.str("flow"))
+	applyTLSQuery(p, query, "servername")
+	if p.boolean("skip-cert-verify") {
+		query.Set("allowInsecure", "1")
+	}
+	applyTransportQuery(p, query)
+	switch {
+	case p.boolean("packet-addr"):
+		query.Set("packetEncoding", "packet")
+	case !p.boolean("xudp"):
+		query.Set("packetEncoding", "none")
+	}
+	p.use("udp")
+
+	return buildLink("vless", url.User(uuid), host, query, p.str("name")), nil
+}
+
+func vmessShareLink(p *proxyFields) (string, error) {
+	if _, err := hostPort(p); err != nil {
+		return "", err
+	}
+	uuid := p.str("uuid")
+	if uuid == "" {
+		return "", fmt.Errorf("missing uuid")
+	}
+
+	// v2rayN JSON format, the one every vmess client understands
+	values := map[string]any{
+		"v":    "2",
+		"ps":   p.str("name"),
+		"add":  p.str("server"),
+		"port": p.str("port"),
+		"id":   uuid,
+		"aid":  strconv.Itoa(p.integer("alterId")),
+		"scy":  p.str("cipher"),
+		"net":  "tcp",
+		"type": "none",
+	}
+	if values["scy"] == "" {
+		values["scy"] = "auto"
+	}
+
+	if p.boolean("tls") {
+		values["tls"] = "tls"
+		setIfNotEmptyValue(values, "sni", p.str("servername"))
+		setIfNotEmptyValue(values, "fp", p.str("client-fingerprint"))
+		setIfNotEmptyValue(values, "alpn", strings.Join(p.strs("alpn"), ","))
+	}
+
+	switch network := p.str("network"); network {
+	case "http":
+		values["type"] = "http"
+		values["host"] = strings.Join(p.strs("http-opts.headers.Host"), ",")
+		values["path"] = strings.Join(p.strs("http-opts.path"), ",")
+	case "h2":
+		values["net"] = "h2"
+		values["host"] = strings.Join(p.strs("h2-opts.headers.Host"), ",")
+		if hosts := p.strs("h2-opts.host"); len(hosts) > 0 {
+			values["host"] = strings.Join(hosts, ",")
+		}
+		values["path"] = p.str("h2-opts.path")
+	case "ws", "httpupgrade":
+		values["net"] = network
+		values["host"] = p.str("ws-opts.headers.Host")
+		p.use("ws-opts.headers.User-Agent")
+		path := p.str("ws-opts.path")
+		if ed := p.integer("ws-opts.max-early-data"); ed > 0 {
+			path = appendPathQuery(path, "ed", strconv.Itoa(ed))
+		}
+		if eh := p.str("ws-opts.early-data-header-name"); eh != "" && eh != "Sec-WebSocket-Protocol" {
+			path = appendPathQuery(path, "eh", eh)
+		}
+		values["path"] = path
+	case "grpc":
+		values["net"] = "grpc"
+		values["path"] = p.str("grpc-opts.grpc-service-name")
+	case "", "tcp":
+	default:
+		values["net"] = network
+	}
+	p.use("udp", "xudp")
+
+	data, err := json.Marshal(values)
+	if err != nil {
+		return "", err
+	}
+	return "vmess://" + base64.StdEncoding.EncodeToString(data), nil
+}
+
+func setIfNotEmptyValue(values map[string]any, key, value string) {
+	if value != "" {
+		values[key] = value
+	}
+}
+
+func appendPathQuery(path, key, value string) string {
+	sep := "?"
+	if strings.Contains(path, "?") {
+		sep = "&"
+	}
+	return path + sep + key + "=" + url.QueryEscape(value)
+}
+
+func trojanShareLink(p *proxyFields) (string, error) {
+	host, err := hostPort(p)
+	if err != nil {
+		return "", err
+	}
+	password := p.str("password")
+	if password == "" {
+		return "", fmt.Errorf("missing password")
+	}
+
+	query := url.Values{}
+	if publicKey := p.str("reality-opts.public-key"); publicKey != "" {
+		query.Set("security", "reality")
+		query.Set("pbk", publicKey)
+		setIfNotEmpty(query, "sid", p.str("reality-opts.short-id"))
+	}
+	setIfNotEmpty(query, "sni", p.str("sni"))
+	setIfNotEmpty(query, "alpn", strings.Join(p.strs("alpn"), ","))
+	setIfNotEmpty(query, "fp", p.str("client-fingerprint"))
+	setIfNotEmpty(query, "pcs", p.str("fingerprint"))
+	if p.boolean("skip-cert-verify") {
+		query.Set("allowInsecure", "1")
+	}
+	if network := p.str("network"); network != "" && network != "tcp" {
+		applyTransportQuery(p, query)
+	} else {
+		p.use("network")
+	}
+	p.use("udp")
+
+	return buildLink("trojan", url.User(password), host, query, p.str("name")), nil
+}
+
+func ssShareLink(p *proxyFields) (string, error) {
+	host, err := hostPort(p)
+	if err != nil {
+		return "", err
+	}
+	cipher, password := p.str("cipher"), p.str("password")
+	if cipher == "" {
+		return "", fmt.Errorf("missing cipher")
+	}
+
+	// SIP002: 2022 ciphers use plain userinfo, everything else base64url
+	var user *url.Userinfo
+	if strings.HasPrefix(cipher, "2022-") {
+		user = url.UserPassword(cipher, password)
+	} else {
+		user = url.User(base64.RawURLEncoding.EncodeToString([]byte(cipher + ":" + password)))
+	}
+
+	query := url.Values{}
+	switch plugin := p.str("plugin"); plugin {
+	case "":
+	case "obfs":
+		opts := "obfs-local;obfs=" + p.str("plugin-opts.mode")
+		if obfsHost := p.str("plugin-opts.host"); obfsHost != "" {
+			opts += ";obfs-host=" + obfsHost
+		}
+		query.Set("plugin", opts)
+	case "v2ray-plugin":
+		opts := "v2ray-plugin;mode=" + p.str("plugin-opts.mode")
+		if pluginHost := p.str("plugin-opts.host"); pluginHost != "" {
+			opts += ";host=" + pluginHost
+		}
+		if path := p.str("plugin-opts.path"); path != "" {
+			opts += ";path=" + path
+		}
+		if p.boolean("plugin-opts.tls") {
+			opts += ";tls"
+		}
+		query.Set("plugin", opts)
+	default:
+		return "", fmt.Errorf("plugin %q has no share link format", plugin)
+	}
+	if p.boolean("udp-over-tcp") {
+		query.Set("uot", "1")
+	}
+	p.use("udp")
+
+	return buildLink("ss", user, host, query, p.str("name")), nil
+}
+
+func ssrShareLink(p *proxyFields) (string, error) {
+	if _, err := hostPort(p); err != nil {
+		return "", err
+	}
+	enc := base64.RawURLEncoding.EncodeToString
+
+	head := strings.Join([]string{
+		p.str("server"),
+		p.str("port"),
+		p.str("protocol"),
+		p.str("cipher"),
+		p.str("obfs"),
+		enc([]byte(p.str("password"))),
+	}, ":")
+	params := []string{"remarks=" + enc([]byte(p.str("name")))}
+	if obfsParam := p.str("obfs-param"); obfsParam != "" {
+		params = append(params, "obfsparam="+enc([]byte(obfsParam)))
+	}
+	if protocolParam := p.str("protocol-param"); protocolParam != "" {
+		params = append(params, "protoparam="+enc([]byte(protocolParam)))
+	}
+	p.use("udp")
+
+	body := head + "/?" + strings.Join(params, "&")
+	return "ssr://" + enc([]byte(body)), nil
+}
+
+func hysteriaShareLink(p *proxyFields) (string, error) {
+	host, err := hostPort(p)
+	if err != nil {
+		return "", err
+	}
+
+	query := url.Values{}
+	setIfNotEmpty(query, "protocol", p.str("protocol"))
+	auth := p.str("auth-str")
+	if auth == "" {
+		auth = p.str("auth_str")
+	}
+	setIfNotEmpty(query, "auth", auth)
+	setIfNotEmpty(query, "peer", p.str("sni"))
+	setIfNotEmpty(query, "obfs", p.str("obfs"))
+	setIfNotEmpty(query, "alpn", strings.Join(p.strs("alpn"), ","))
+	setIfNotEmpty(query, "upmbps", p.str("up"))
+	setIfNotEmpty(query, "downmbps", p.str("down"))
+	if p.boolean("skip-cert-verify") {
+		query.Set("insecure", "1")
+	}
+
+	return buildLink("hysteria", nil, host, query, p.str("name")), nil
+}
+
+func hysteria2ShareLink(p *proxyFields) (string, error) {
+	host, err := hostPort(p)
+	if err != nil {
+		return "", err
+	}
+
+	var user *url.Userinfo
+	if password := p.str("password"); password != "" {
+		user = url.User(password)
+	}
+	query := url.Values{}
+	setIfNotEmpty(query, "sni", p.str("sni"))
+	if obfs := p.str("obfs"); obfs != "" {
+		query.Set("obfs", obfs)
+		setIfNotEmpty(query, "obfs-password", p.str("obfs-password"))
+	}
+	setIfNotEmpty(query, "alpn", strings.Join(p.strs("alpn"), ","))
+	setIfNotEmpty(query, "pinSHA256", p.str("fingerprint"))
+	setIfNotEmpty(query, "up", p.str("up"))
+	setIfNotEmpty(query, "down", p.str("down"))
+	if p.boolean("skip-cert-verify") {
+		query.Set("insecure", "1")
+	}
+
+	return buildLink("hysteria2", user, host, query, p.str("name")), nil
+}
+
+func tuicShareLink(p *proxyFields) (string, error) {
+	host, err := hostPort(p)
+	if err != nil {
+		return "", err
+	}
+
+	var user *url.Userinfo
+	if token := p.str("token"); token != "" {
+		user = url.User(token)
+	} else {
+		user = url.UserPassword(p.str("uuid"), p.str("password"))
+	}
+	query := url.Values{}
+	setIfNotEmpty(query, "congestion_control", p.str("congestion-controller"))
+	setIfNotEmpty(query, "alpn", strings.Join(p.strs("alpn"), ","))
+	setIfNotEmpty(query, "sni", p.str("sni"))
+	setIfNotEmpty(query, "udp_relay_mode", p.str("udp-relay-mode"))
+	if p.boolean("disable-sni") {
+		query.Set("disable_sni", "1")
+	}
+	p.use("udp")
+
+	return buildLink("tuic", user, host, query, p.str("name")), nil
+}
+
+func anytlsShareLink(p *proxyFields) (string, error) {
+	host, err := hostPort(p)
+	if err != nil {
+		return "", err
+	}
+
+	query := url.Values{}
+	setIfNotEmpty(query, "sni", p.str("sni"))
+	setIfNotEmpty(query, "hpkp", p.str("fingerprint"))
+	if p.boolean("skip-cert-verify") {
+		query.Set("insecure", "1")
+	}
+	// ConvertsV2Ray copies the password into username when only one is given
+	p.use("username", "udp")
+
+	return buildLink("anytls", url.User(p.str("password")), host, query, p.str("name")), nil
+}
+
+func socksHTTPShareLink(p *proxyFields) (string, error) {
+	host, err := hostPort(p)
+	if err != nil {
+		return "", err
+	}
+
+	scheme := "socks5"
+	query := url.Values{}
+	if p.str("type") == "http" {
+		scheme = "http"
+		if p.boolean("tls") {
+			scheme = "https"
+		}
+	} else if p.boolean("tls") {
+		// Parsed by the bridge, ConvertsV2Ray has no socks TLS
+		scheme = "socks5+tls"
+		if p.boolean("skip-cert-verify") {
+			query.Set("insecure", "1")
+		}
+	}
+
+	var user *url.Userinfo
+	if username := p.str("username"); username != "" {
+		user = url.UserPassword(username, p.str("password"))
+	} else {
+		p.use("password")
+	}
+	// ConvertsV2Ray always enables skip-cert-verify for these schemes
+	p.use("skip-cert-verify", "udp")
+
+	return buildLink(scheme, user, host, query, p.str("name")), nil
+}
+
+// wireGuardShareLink writes the wireguard:// link of v2rayN and sing-box
+// clients that parseWireGuardLink reads. Only single-peer proxies fit.
+func wireGuardShareLink(p *proxyFields) (string, error) {
+	host, err := hostPort(p)
+	if err != nil {
+		return "", err
+	}
+	privateKey, publicKey := p.str("private-key"), p.str("public-key")
+	if privateKey == "" || publicKey == "" {
+		return "", fmt.Errorf("missing private or public key")
+	}
+
+	query := url.Values{}
+	query.Set("publickey", publicKey)
+	var addresses []string
+	for _, key := range []string{"ip", "ipv6"} {
+		if address := p.str(key); address != "" {
+			addresses = append(addresses, address)
+		}
+	}
+	setIfNotEmpty(query, "address", strings.Join(addresses, ","))
+	setIfNotEmpty(query, "presharedkey", p.str("pre-shared-key"))
+	if v, ok := p.get("reserved"); ok {
+		setIfNotEmpty(query, "reserved", strings.Join(intStrings(v), ","))
+	}
+	setIfNotEmpty(query, "allowedips", strings.Join(p.strs("allowed-ips"), ","))
+	setIfNotEmpty(query, "dns", strings.Join(p.strs("dns"), ","))
+	for _, key := range []string{"mtu", "persistent-keepalive", "workers"} {
+		if n := p.integer(key); n > 0 {
+			query.Set(key, strconv.Itoa(n))
+		}
+	}
+	for key := range amneziaKeys {
+		setIfNotEmpty(query, key, p.str("amnezia-wg-option."+key))
+	}
+	p.use("remote-dns-resolve", "udp")
+
+	return buildLink("wireguard", url.User(privateKey), host, query, p.str("name")), nil
+}
+
+// intStrings formats a list of numbers such as WireGuard reserved bytes
+func intStrings(v any) []string {
+	if ints, ok := v.([]int); ok {
+		result := make([]string, 0, len(ints))
+		for _, n := range ints {
+			result = append(result, strconv.Itoa(n))
+		}
+		return result
+	}
+	return anyToStrings(v)
+}
+
+func sshShareLink(p *proxyFields) (string, error) {
+	host, err := hostPort(p)
+	if err != nil {
+		return "", err
+	}
+	username := p.str("username")
+	if username == "" {
+		return "", fmt.Errorf("missing username")
+	}
+
+	password, privateKey := p.str("password"), p.str("private-key")
+	if password == "" && privateKey == "" {
+		return "", fmt.Errorf("missing password or private key")
+	}
+
+	user := url.User(username)
+	if password != "" {
+		user = url.UserPassword(username, password)
+	}
+	query := url.Values{}
+	setIfNotEmpty(query, "private-key", privateKey)
+	setIfNotEmpty(query, "private-key-passphrase", p.str("private-key-passphrase"))
+	setIfNotEmpty(query, "host-key", strings.Join(p.strs("host-key"), ","))
+	setIfNotEmpty(query, "host-key-algorithms", strings.Join(p.strs("host-key-algorithms"), ","))
+	p.use("udp")
+
+	return buildLink("ssh", user, host, query, p.str("name")), nil
+}
+
+func snellShareLink(p *proxyFields) (string, error) {
+	host, err := hostPort(p)
+	if err != nil {
+		return "", err
+	}
+	psk := p.str("psk")
+	if psk == "" {
+		return "", fmt.Errorf("missing psk")
+	}
+
+	query := url.Values{}
+	if version := p.integer("version"); version > 0 {
+		query.Set("version", strconv.Itoa(version))
+	}
+	if p.boolean("udp") {
+		query.Set("udp", "1")
+	}
+	if mode := p.str("obfs-opts.mode"); mode != "" && mode != "none" {
+		query.Set("obfs", mode)
+		setIfNotEmpty(query, "obfs-host", p.str("obfs-opts.host"))
+	}
+
+	return buildLink("snell", url.User(psk), host, query, p.str("name")), nil
+}
+
+// mieruShareLink writes the simple mierus:// link of the mieru client,
+// with the port (or range) and transport as query parameters
+func mieruShareLink(p *proxyFields) (string, error) {
+	server := p.str("server")
+	if server == "" {
+		return "", fmt.Errorf("missing server")
+	}
+	port := p.str("port-range")
+	if port == "" {
+		port = p.str("port")
+	}
+	if port == "" {
+		return "", fmt.Errorf("missing port")
+	}
+	username, password := p.str("username"), p.str("password")
+	if username == "" || password == "" {
+		return "", fmt.Errorf("missing username or password")
+	}
+
+	transport := strings.ToUpper(p.str("transport"))
+	if transport == "" {
+		transport = "TCP"
+	}
+	query := url.Values{}
+	query.Set("port", port)
+	query.Set("protocol", transport)
+	setIfNotEmpty(query, "multiplexing", p.str("multiplexing"))
+	setIfNotEmpty(query, "handshake-mode", p.str("handshake-mode"))
+	if transport == "TCP" && p.boolean("udp") {
+		query.Set("udp", "1")
+	}
+	p.use("udp")
+
+	host := server
+	if strings.Contains(server, ":") {
+		host = "[" + server + "]"
+	}
+	return buildLink("mierus", url.UserPassword(username, password), host, query, p.str("name")), nil
+}
+
+// ExportShareLinks converts mihomo proxies (JSON array, or JSON/YAML with a
+// "proxies" list) back to share links, reporting fields that were lost
+//
+//export ExportShareLinks
+func ExportShareLinks(data *C.char) *C.char {
+	return respond("ExportShareLinks", func() (any, []string, error) {
+		if data == nil {
+			return nil, nil, errNullInput
+		}
+
+		proxies, err := decodeProxyList([]byte(C.GoString(data)))
+		if err != nil {
+			return nil, nil, err
+		}
+
+		links := make([]shareLink, 0, len(proxies))
+		for _, proxy := range proxies {
+			links = append(links, exportShareLink(proxy))
+		}
+		return links, nil, nil
+	})
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// flattenFields writes the scalar fields of v as "a.b" paths, lists
+// joined by commas, so that values compare the same whether they came
+// from YAML, JSON or a link parser
+func flattenFields(prefix string, v any, out map[string]string) {
+	switch v := v.(type) {
+	case map[string]any:
+		for key, value := range v {
+			flattenFields(prefix+"."+key, value, out)
+		}
+	case []any, []string, []int:
+		out[prefix] = strings.Join(intStrings(v), ",")
+	default:
+		out[prefix] = fmt.Sprint(v)
+	}
+}
+
+func TestShareLinkRoundTrip(t *testing.T) {
+	const uuid = "b1b625b6-8226-4a6b-ad4e-4908c3d2edba"
+	tests := []struct {
+		name  string
+		proxy map[string]any
+		query string // Expected in the exported link
+	}{
+		{
+			name: "vless reality",
+			proxy: map[string]any{
+				"name": "vless", "type": "vless", "server": "a.com", "port": 443, "uuid": uuid,
+				"tls": true, "servername": "b.com", "flow": "xtls-rprx-vision", "client-fingerprint": "chrome",
+				"reality-opts": map[string]any{"public-key": "pbk", "short-id": "01"},
+				"network":      "tcp",
+			},
+		},
+		{
+			name: "vless websocket",
+			proxy: map[string]any{
+				"name": "vless ws", "type": "vless", "server": "a.com", "port": 443, "uuid": uuid,
+				"tls": true, "servername": "b.com", "network": "ws", "skip-cert-verify": true,
+				"ws-opts": map[string]any{"path": "/ws?ed=2048", "headers": map[string]any{"Host": "b.com"}},
+			},
+			query: "allowInsecure=1",
+		},
+		{
+			name: "vmess grpc",
+			proxy: map[string]any{
+				"name": "vmess", "type": "vmess", "server": "a.com", "port": 443, "uuid": uuid,
+				"alterId": 0, "cipher": "auto", "tls": true, "servername": "b.com", "network": "grpc",
+				"grpc-opts": map[string]any{"grpc-service-name": "svc"},
+			},
+		},
+		{
+			name: "trojan",
+			proxy: map[string]any{
+				"name": "trojan #1", "type": "trojan", "server": "a.com", "port": 443, "password": "p@ss",
+				"sni": "b.com", "skip-cert-verify": true, "alpn": []string{"h2", "http/1.1"},
+			},
+		},
+		{
+			name: "ss with plugin",
+			proxy: map[string]any{
+				"name": "ss", "type": "ss", "server": "a.com", "port": 8388, "cipher": "aes-128-gcm",
+				"password": "pw", "plugin": "obfs", "plugin-opts": map[string]any{"mode": "http", "host": "x.com"},
+			},
+		},
+		{
+			name: "ssr",
+			proxy: map[string]any{
+				"name": "ssr", "type": "ssr", "server": "a.com", "port": 8388, "cipher": "aes-256-cfb",
+				"password": "pw", "protocol": "origin", "obfs": "plain",
+			},
+		},
+		{
+			name: "hysteria2",
+			proxy: map[string]any{
+				"name": "hy2", "type": "hysteria2", "server": "a.com", "port": 443, "password": "pw",
+				"sni": "b.com", "obfs": "salamander", "obfs-password": "op", "skip-cert-verify": true,
+			},
+		},
+		{
+			name: "tuic",
+			proxy: map[string]any{
+				"name": "tuic", "type": "tuic", "server": "a.com", "port": 443, "uuid": uuid,
+				"password": "pw", "sni": "b.com", "congestion-controller": "bbr",
+			},
+		},
+		{
+			name: "anytls",
+			proxy: map[string]any{
+				"name": "anytls", "type": "anytls", "server": "a.com", "port": 443, "password": "pw",
+				"sni": "b.com", "skip-cert-verify": true,
+			},
+		},
+		{
+			name: "socks5",
+			proxy: map[string]any{
+				"name": "socks", "type": "socks5", "server": "a.com", "port": 1080,
+				"username": "u", "password": "p",
+			},
+		},
+		{
+			name: "ssh",
+			proxy: map[string]any{
+				"name": "ssh", "type": "ssh", "server": "a.com", "port": 22,
+				"username": "u", "password": "p",
+			},
+		},
+		{
+			name: "snell",
+			proxy: map[string]any{
+				"name": "snell", "type": "snell", "server": "a.com", "port": 443, "psk": "psk",
+				"version": 3, "obfs-opts": map[string]any{"mode": "tls", "host": "b.com"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			exported := exportShareLink(tt.proxy)
+			if exported.Error != "" || len(exported.Unsupported) > 0 {
+				t.Fatalf("exportShareLink = %q, unsupported %v", exported.Error, exported.Unsupported)
+			}
+			if tt.query != "" && !strings.Contains(exported.Link, tt.query) {
+				t.Errorf("link %q does not contain %q", exported.Link, tt.query)
+			}
+
+			proxies, _, _, err := convertLinks(context.Background(), exported.Link)
+			if err != nil || len(proxies) != 1 {
+				t.Fatalf("convertLinks(%q) = %d proxies, %v", exported.Link, len(proxies), err)
+			}
+			want, got := map[string]string{}, map[string]string{}
+			flattenFields("", tt.proxy, want)
+			flattenFields("", proxies[0], got)
+			for key, value := range want {
+				// ConvertsV2Ray does not read allowInsecure on vless links
+				if key == ".skip-cert-verify" && tt.proxy["type"] == "vless" {
+					continue
+				}
+				if got[key] != value {
+					t.Errorf("%s = %q after %q, want %q", key[1:], got[key], exported.Link, value)
+				}
+			}
+		})
+	}
+}
+
+func TestShareLinkErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		proxy map[string]any
+		want  string
+	}{
+		{
+			name:  "vless without uuid",
+			proxy: map[string]any{"type": "vless", "server": "a.com", "port": 443},
+			want:  "missing uuid",
+		},
+		{
+			name:  "vmess without uuid",
+			proxy: map[string]any{"type": "vmess", "server": "a.com", "port": 443, "uuid": ""},
+			want:  "missing uuid",
+		},
+		{
+			name:  "trojan without password",
+			proxy: map[string]any{"type": "trojan", "server": "a.com", "port": 443},
+			want:  "missing password",
+		},
+		{
+			name:  "no server",
+			proxy: map[string]any{"type": "vmess", "port": 443, "uuid": "x"},
+			want:  "missing server",
+		},
+		{
+			name:  "no link format",
+			proxy: map[string]any{"type": "direct"},
+			want:  `proxy type "direct" has no share link format`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := exportShareLink(tt.proxy); got.Error != tt.want || got.Link != "" {
+				t.Errorf("exportShareLink = %q, %q, want error %q", got.Link, got.Error, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,685 @@
+package main
+
+import "C"
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// singBoxDomainStrategies maps mihomo ip-version to sing-box domain_strategy
+var singBoxDomainStrategies = map[string]string{
+	"ipv4-prefer": "prefer_ipv4",
+	"ipv6-prefer": "prefer_ipv6",
+	"ipv4":        "ipv4_only",
+	"ipv6":        "ipv6_only",
+}
+
+// rateRegexp matches the rate strings accepted by mihomo's StringToBps
+var rateRegexp = regexp.MustCompile(`^(\d+)\s*([KMGT]?)([Bb])ps$`)
+
+// singBoxOutboundExport is the export result for a single proxy. Outbounds
+// holds the proxy outbound followed by any outbound it depends on.
+type singBoxOutboundExport struct {
+	Name        string           `json:"name"`
+	Type        string           `json:"type"`
+	Outbounds   []map[string]any `json:"outbounds,omitempty"`
+	Unsupported []string         `json:"unsupported,omitempty"`
+	Error       string           `json:"error,omitempty"`
+}
+
+// exportSingBoxOutbound converts one mihomo proxy map to sing-box
+// outbounds. Fields that sing-box cannot carry are listed in Unsupported.
+func exportSingBoxOutbound(proxy map[string]any) singBoxOutboundExport {
+	p := newProxyFields(proxy)
+	result := singBoxOutboundExport{Name: p.str("name"), Type: p.str("type")}
+
+	outbound := map[string]any{
+		"tag":         result.Name,
+		"server":      p.str("server"),
+		"server_port": p.integer("port"),
+	}
+	var (
+		extra []map[string]any
+		err   error
+	)
+	switch result.Type {
+	case "ss":
+		extra, err = shadowsocksSingBox(p, outbound)
+	case "vmess":
+		err = vmessSingBox(p, outbound)
+	case "vless":
+		err = vlessSingBox(p, outbound)
+	case "trojan":
+		err = trojanSingBox(p, outbound)
+	case "hysteria":
+		err = hysteriaSingBox(p, outbound)
+	case "hysteria2":
+		err = hysteria2SingBox(p, outbound)
+	case "tuic":
+		err = tuicSingBox(p, outbound)
+	case "anytls":
+		err = anytlsSingBox(p, outbound)
+	case "socks5":
+		err = socksSingBox(p, outbound)
+	case "http":
+		err = httpSingBox(p, outbound)
+	case "wireguard":
+		err = wireguardSingBox(p, outbound)
+	case "ssh":
+		err = sshSingBox(p, outbound)
+	default:
+		err = fmt.Errorf("proxy type %q has no sing-box outbound", result.Type)
+	}
+	if err == nil && outbound["server"] == "" {
+		err = errors.New("missing server")
+	}
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+
+	applySingBoxDial(p, outbound)
+	result.Outbounds = append([]map[string]any{outbound}, extra...)
+	result.Unsupported = p.unused()
+	return result
+}
+
+// applySingBoxDial writes the dial fields shared by all outbounds
+func applySingBoxDial(p *proxyFields, outbound map[string]any) {
+	if p.has("dialer-proxy") {
+		outbound["detour"] = p.str("dialer-proxy")
+	}
+	if p.boolean("tfo") {
+		outbound["tcp_fast_open"] = true
+	}
+	if p.boolean("mptcp") {
+		outbound["tcp_multi_path"] = true
+	}
+	if p.has("interface-name") {
+		outbound["bind_interface"] = p.str("interface-name")
+	}
+	if p.has("routing-mark") {
+		outbound["routing_mark"] = p.integer("routing-mark")
+	}
+	if v, ok := singBoxDomainStrategies[anyToString(p.m["ip-version"])]; ok {
+		p.use("ip-version")
+		outbound["domain_strategy"] = v
+	}
+}
+
+// applySingBoxNetwork limits the outbound to TCP when UDP is off, which
+// is the mihomo default
+func applySingBoxNetwork(p *proxyFields, outbound map[string]any) {
+	if !p.boolean("udp") {
+		outbound["network"] = "tcp"
+	}
+}
+
+// buildSingBoxTLS assembles the tls block. enabled forces TLS for
+// protocols that always run over it.
+func buildSingBoxTLS(p *proxyFields, sniKey string, enabled bool) map[string]any {
+	publicKey := p.str("reality-opts.public-key")
+	if !enabled && !p.boolean("tls") && publicKey == "" {
+		return nil
+	}
+	p.use("tls")
+
+	tls := map[string]any{"enabled": true}
+	if sniKey != "" {
+		setIfNotEmptyValue(tls, "server_name", p.str(sniKey))
+	}
+	if p.boolean("skip-cert-verify") {
+		tls["insecure"] = true
+	}
+	if alpn := p.strs("alpn"); len(alpn) > 0 {
+		tls["alpn"] = alpn
+	}
+	fingerprint := p.str("client-fingerprint")
+	if publicKey != "" {
+		tls["reality"] = map[string]any{
+			"enabled":    true,
+			"public_key": publicKey,
+			"short_id":   p.str("reality-opts.short-id"),
+		}
+		// sing-box refuses reality without uTLS
+		if fingerprint == "" {
+			fingerprint = "chrome"
+		}
+	}
+	if fingerprint != "" {
+		tls["utls"] = map[string]any{"enabled": true, "fingerprint": fingerprint}
+	}
+	if p.boolean("ech-opts.enable") {
+		ech := map[string]any{"enabled": true}
+		if config := p.str("ech-opts.config"); config != "" {
+			ech["config"] = []string{
+				"-----BEGIN ECH CONFIGS-----", config, "-----END ECH CONFIGS-----",
+			}
+		}
+		tls["ech"] = ech
+	}
+	return tls
+}
+
+// buildSingBoxTransport assembles the V2Ray transport block shared by
+// vmess, vless and trojan
+func buildSingBoxTransport(p *proxyFields) (map[string]any, error) {
+	network := p.str("network")
+	if network == "ws" && p.boolean("ws-opts.v2ray-http-upgrade") {
+		network = "httpupgrade"
+	}
+
+	switch network {
+	case "", "tcp":
+		return nil, nil
+	case "ws":
+		transport := map[string]any{"type": "ws"}
+		setIfNotEmptyValue(transport, "path", p.str("ws-opts.path"))
+		if headers := singBoxHeaders(p, "ws-opts.headers"); len(headers) > 0 {
+			transport["headers"] = headers
+		}
+		if ed := p.integer("ws-opts.max-early-data"); ed > 0 {
+			transport["max_early_data"] = ed
+		}
+		setIfNotEmptyValue(transport, "early_data_header_name", p.str("ws-opts.early-data-header-name"))
+		return transport, nil
+	case "httpupgrade":
+		transport := map[string]any{"type": "httpupgrade"}
+		setIfNotEmptyValue(transport, "path", p.str("ws-opts.path"))
+		headers := singBoxHeaders(p, "ws-opts.headers")
+		if host, ok := headers["Host"]; ok {
+			transport["host"] = host
+			delete(headers, "Host")
+		}
+		if len(headers) > 0 {
+			transport["headers"] = headers
+		}
+		// sing-box has no early data for httpupgrade
+		p.use("ws-opts.v2ray-http-upgrade-fast-open")
+		return transport, nil
+	case "http":
+		transport := map[string]any{"type": "http"}
+		if paths := p.strs("http-opts.path"); len(paths) > 0 {
+			transport["path"] = paths[0]
+		}
+		if hosts := p.strs("http-opts.headers.Host"); len(hosts) > 0 {
+			transport["host"] = hosts
+		}
+		setIfNotEmptyValue(transport, "method", p.str("http-opts.method"))
+		return transport, nil
+	case "h2":
+		transport := map[string]any{"type": "http"}
+		setIfNotEmptyValue(transport, "path", p.str("h2-opts.path"))
+		if hosts := p.strs("h2-opts.host"); len(hosts) > 0 {
+			transport["host"] = hosts
+		}
+		return transport, nil
+	case "grpc":
+		transport := map[string]any{"type": "grpc"}
+		setIfNotEmptyValue(transport, "service_name", p.str("grpc-opts.grpc-service-name"))
+		return transport, nil
+	}
+	return nil, fmt.Errorf("network %q has no sing-box transport", network)
+}
+
+// singBoxHeaders flattens a mihomo header map to single string values
+func singBoxHeaders(p *proxyFields, path string) map[string]any {
+	headers := map[string]any{}
+	v, _ := p.get(path)
+	m, _ := v.(map[string]any)
+	for k, value := range m {
+		if values := anyToStrings(value); len(values) > 0 {
+			headers[k] = values[0]
+		}
+	}
+	return headers
+}
+
+// buildSingBoxMultiplex maps mihomo smux to the sing-box multiplex block
+func buildSingBoxMultiplex(p *proxyFields) map[string]any {
+	if !p.boolean("smux.enabled") {
+		return nil
+	}
+	multiplex := map[string]any{"enabled": true}
+	setIfNotEmptyValue(multiplex, "protocol", p.str("smux.protocol"))
+	for key, target := range map[string]string{
+		"smux.max-connections": "max_connections",
+		"smux.min-streams":     "min_streams",
+		"smux.max-streams":     "max_streams",
+	} {
+		if n := p.integer(key); n > 0 {
+			multiplex[target] = n
+		}
+	}
+	if p.boolean("smux.padding") {
+		multiplex["padding"] = true
+	}
+	if p.boolean("smux.brutal-opts.enabled") {
+		up, _ := rateToMbps(p.str("smux.brutal-opts.up"))
+		down, _ := rateToMbps(p.str("smux.brutal-opts.down"))
+		multiplex["brutal"] = map[string]any{
+			"enabled":   true,
+			"up_mbps":   up,
+			"down_mbps": down,
+		}
+	}
+	return multiplex
+}
+
+// applySingBoxStream adds the tls, transport and multiplex blocks used by
+// the V2Ray-family protocols
+func applySingBoxStream(p *proxyFields, outbound map[string]any, sniKey string, tlsAlways bool) error {
+	if tls := buildSingBoxTLS(p, sniKey, tlsAlways); tls != nil {
+		outbound["tls"] = tls
+	}
+	transport, err := buildSingBoxTransport(p)
+	if err != nil {
+		return err
+	}
+	if transport != nil {
+		outbound["transport"] = transport
+	}
+	if multiplex := buildSingBoxMultiplex(p); multiplex != nil {
+		outbound["multiplex"] = multiplex
+	}
+	return nil
+}
+
+// rateToMbps converts a mihomo rate string ("100", "100 Mbps", "10 MBps")
+// to whole megabits per second
+func rateToMbps(rate string) (int, bool) {
+	rate = strings.TrimSpace(rate)
+	if n, err := strconv.Atoi(rate); err == nil {
+		return n, true
+	}
+	m := rateRegexp.FindStringSubmatch(rate)
+	if m == nil {
+		return 0, false
+	}
+	n, _ := strconv.ParseFloat(m[1], 64)
+	switch m[2] {
+	case "":
+		n /= 1e6
+	case "K":
+		n /= 1e3
+	case "G":
+		n *= 1e3
+	case "T":
+		n *= 1e6
+	}
+	if m[3] == "B" {
+		n *= 8
+	}
+	return int(n), true
+}
+
+// applySingBoxBandwidth writes up/down as *_mbps integers
+func applySingBoxBandwidth(p *proxyFields, outbound map[string]any) error {
+	for _, dir := range []string{"up", "down"} {
+		if !p.has(dir) {
+			continue
+		}
+		mbps, ok := rateToMbps(p.str(dir))
+		if !ok {
+			return fmt.Errorf("invalid %s rate %q", dir, p.str(dir))
+		}
+		outbound[dir+"_mbps"] = mbps
+	}
+	return nil
+}
+
+// applySingBoxPorts maps the port hopping range and interval
+func applySingBoxPorts(p *proxyFields, outbound map[string]any) {
+	if ports := p.str("ports"); ports != "" {
+		var ranges []string
+		for _, r := range strings.Split(ports, ",") {
+			if r = strings.TrimSpace(r); r != "" {
+				ranges = append(ranges, strings.ReplaceAll(r, "-", ":"))
+			}
+		}
+		outbound["server_ports"] = ranges
+	}
+	if interval := p.integer("hop-interval"); interval > 0 {
+		outbound["hop_interval"] = strconv.Itoa(interval) + "s"
+	}
+}
+
+func shadowsocksSingBox(p *proxyFields, outbound map[string]any) ([]map[string]any, error) {
+	outbound["type"] = "shadowsocks"
+	outbound["method"] = p.str("cipher")
+	outbound["password"] = p.str("password")
+	applySingBoxNetwork(p, outbound)
+	if p.boolean("udp-over-tcp") {
+		uot := map[string]any{"enabled": true}
+		if version := p.integer("udp-over-tcp-version"); version > 0 {
+			uot["version"] = version
+		}
+		outbound["udp_over_tcp"] = uot
+	}
+	if multiplex := buildSingBoxMultiplex(p); multiplex != nil {
+		outbound["multiplex"] = multiplex
+	}
+
+	switch plugin := p.str("plugin"); plugin {
+	case "":
+	case "obfs":
+		opts := "obfs=" + p.str("plugin-opts.mode")
+		if host := p.str("plugin-opts.host"); host != "" {
+			opts += ";obfs-host=" + host
+		}
+		outbound["plugin"] = "obfs-local"
+		outbound["plugin_opts"] = opts
+	case "v2ray-plugin":
+		opts := []string{"mode=" + p.str("plugin-opts.mode")}
+		if host := p.str("plugin-opts.host"); host != "" {
+			opts = append(opts, "host="+host)
+		}
+		if path := p.str("plugin-opts.path"); path != "" {
+			opts = append(opts, "path="+path)
+		}
+		if p.boolean("plugin-opts.tls") {
+			opts = append(opts, "tls")
+		}
+		if p.boolean("plugin-opts.mux") {
+			opts = append(opts, "mux=1")
+		}
+		outbound["plugin"] = "v2ray-plugin"
+		outbound["plugin_opts"] = strings.Join(opts, ";")
+	case "shadow-tls":
+		// sing-box runs shadow-tls as a separate outbound used as detour
+		tag := outbound["tag"].(string) + " shadowtls"
+		shadowTLS := map[string]any{
+			"type":        "shadowtls",
+			"tag":         tag,
+			"server":      outbound["server"],
+			"server_port": outbound["server_port"],
+			"version":     p.integer("plugin-opts.version"),
+			"password":    p.str("plugin-opts.password"),
+		}
+		if shadowTLS["version"] == 0 {
+			shadowTLS["version"] = 2
+		}
+		tls := map[string]any{"enabled": true, "server_name": p.str("plugin-opts.host")}
+		if fingerprint := p.str("client-fingerprint"); fingerprint != "" {
+			tls["utls"] = map[string]any{"enabled": true, "fingerprint": fingerprint}
+		}
+		shadowTLS["tls"] = tls
+		outbound["detour"] = tag
+		// Traffic goes through the detour, which carries the real address
+		outbound["server"] = "127.0.0.1"
+		outbound["network"] = "tcp"
+		return []map[string]any{shadowTLS}, nil
+	default:
+		return nil, fmt.Errorf("plugin %q has no sing-box equivalent", plugin)
+	}
+	return nil, nil
+}
+
+// applySingBoxPacketEncoding maps the UDP packet encoding of vmess and vless
+func applySingBoxPacketEncoding(p *proxyFields, outbound map[string]any) {
+	switch {
+	case p.has("packet-encoding"):
+		outbound["packet_encoding"] = p.str("packet-encoding")
+	case p.boolean("xudp"):
+		outbound["packet_encoding"] = "xudp"
+	case p.boolean("packet-addr"):
+		outbound["packet_encoding"] = "packetaddr"
+	}
+}
+
+func vmessSingBox(p *proxyFields, outbound map[string]any) error {
+	outbound["type"] = "vmess"
+	outbound["uuid"] = p.str("uuid")
+	outbound["alter_id"] = p.integer("alterId")
+	security := p.str("cipher")
+	if security == "" {
+		security = "auto"
+	}
+	outbound["security"] = security
+	if p.boolean("global-padding") {
+		outbound["global_padding"] = true
+	}
+	if p.boolean("authenticated-length") {
+		outbound["authenticated_length"] = true
+	}
+	applySingBoxNetwork(p, outbound)
+	applySingBoxPacketEncoding(p, outbound)
+	return applySingBoxStream(p, outbound, "servername", false)
+}
+
+func vlessSingBox(p *proxyFields, outbound map[string]any) error {
+	if encryption := p.str("encryption"); encryption != "" && encryption != "none" {
+		return fmt.Errorf("vless encryption %q is not supported by sing-box", encryption)
+	}
+	outbound["type"] = "vless"
+	outbound["uuid"] = p.str("uuid")
+	setIfNotEmptyValue(outbound, "flow", p.str("flow"))
+	applySingBoxNetwork(p, outbound)
+	applySingBoxPacketEncoding(p, outbound)
+	return applySingBoxStream(p, outbound, "servername", false)
+}
+
+func trojanSingBox(p *proxyFields, outbound map[string]any) error {
+	outbound["type"] = "trojan"
+	outbound["password"] = p.str("password")
+	applySingBoxNetwork(p, outbound)
+	return applySingBoxStream(p, outbound, "sni", true)
+}
+
+func hysteriaSingBox(p *proxyFields, outbound map[string]any) error {
+	if protocol := p.str("protocol"); protocol != "" && protocol != "udp" {
+		return fmt.Errorf("hysteria protocol %q is not supported by sing-box", protocol)
+	}
+	outbound["type"] = "hysteria"
+	if err := applySingBoxBandwidth(p, outbound); err != nil {
+		return err
+	}
+	setIfNotEmptyValue(outbound, "obfs", p.str("obfs"))
+	setIfNotEmptyValue(outbound, "auth", p.str("auth"))
+	// ConvertsV2Ray writes auth_str, mihomo itself reads auth-str
+	authStr := p.str("auth-str")
+	if authStr == "" {
+		authStr = p.str("auth_str")
+	}
+	setIfNotEmptyValue(outbound, "auth_str", authStr)
+	if n := p.integer("recv-window-conn"); n > 0 {
+		outbound["recv_window_conn"] = n
+	}
+	if n := p.integer("recv-window"); n > 0 {
+		outbound["recv_window"] = n
+	}
+	if p.boolean("disable-mtu-discovery") {
+		outbound["disable_mtu_discovery"] = true
+	}
+	p.use("udp")
+	applySingBoxPorts(p, outbound)
+	outbound["tls"] = buildSingBoxTLS(p, "sni", true)
+	return nil
+}
+
+func hysteria2SingBox(p *proxyFields, outbound map[string]any) error {
+	outbound["type"] = "hysteria2"
+	outbound["password"] = p.str("password")
+	if err := applySingBoxBandwidth(p, outbound); err != nil {
+		return err
+	}
+	if obfs := p.str("obfs"); obfs != "" {
+		outbound["obfs"] = map[string]any{
+			"type":     obfs,
+			"password": p.str("obfs-password"),
+		}
+	}
+	p.use("udp")
+	applySingBoxPorts(p, outbound)
+	outbound["tls"] = buildSingBoxTLS(p, "sni", true)
+	return nil
+}
+
+func tuicSingBox(p *proxyFields, outbound map[string]any) error {
+	if p.has("token") {
+		return errors.New("TUIC v4 is not supported by sing-box")
+	}
+	outbound["type"] = "tuic"
+	outbound["uuid"] = p.str("uuid")
+	outbound["password"] = p.str("password")
+	setIfNotEmptyValue(outbound, "congestion_control", p.str("congestion-controller"))
+	setIfNotEmptyValue(outbound, "udp_relay_mode", p.str("udp-relay-mode"))
+	if p.boolean("udp-over-stream") {
+		outbound["udp_over_stream"] = true
+	}
+	if p.boolean("reduce-rtt") {
+		outbound["zero_rtt_handshake"] = true
+	}
+	if n := p.integer("heartbeat-interval"); n > 0 {
+		outbound["heartbeat"] = strconv.Itoa(n) + "ms"
+	}
+	p.use("udp")
+	tls := buildSingBoxTLS(p, "sni", true)
+	if p.boolean("disable-sni") {
+		tls["disable_sni"] = true
+	}
+	outbound["tls"] = tls
+	return nil
+}
+
+func anytlsSingBox(p *proxyFields, outbound map[string]any) error {
+	outbound["type"] = "anytls"
+	outbound["password"] = p.str("password")
+	p.use("udp")
+	if n := p.integer("idle-session-check-interval"); n > 0 {
+		outbound["idle_session_check_interval"] = strconv.Itoa(n) + "s"
+	}
+	if n := p.integer("idle-session-timeout"); n > 0 {
+		outbound["idle_session_timeout"] = strconv.Itoa(n) + "s"
+	}
+	if n := p.integer("min-idle-session"); n > 0 {
+		outbound["min_idle_session"] = n
+	}
+	outbound["tls"] = buildSingBoxTLS(p, "sni", true)
+	return nil
+}
+
+func socksSingBox(p *proxyFields, outbound map[string]any) error {
+	outbound["type"] = "socks"
+	outbound["version"] = "5"
+	setIfNotEmptyValue(outbound, "username", p.str("username"))
+	setIfNotEmptyValue(outbound, "password", p.str("password"))
+	applySingBoxNetwork(p, outbound)
+	return nil
+}
+
+func httpSingBox(p *proxyFields, outbound map[string]any) error {
+	outbound["type"] = "http"
+	setIfNotEmptyValue(outbound, "username", p.str("username"))
+	setIfNotEmptyValue(outbound, "password", p.str("password"))
+	if headers := singBoxHeaders(p, "headers"); len(headers) > 0 {
+		outbound["headers"] = headers
+	}
+	if tls := buildSingBoxTLS(p, "sni", false); tls != nil {
+		outbound["tls"] = tls
+	}
+	return nil
+}
+
+// wireguardSingBox emits the wireguard outbound with an explicit peers
+// list, the same layout proxyToSingBox uses
+func wireguardSingBox(p *proxyFields, outbound map[string]any) error {
+	outbound["type"] = "wireguard"
+	p.use("udp")
+	var addresses []string
+	if ip := p.str("ip"); ip != "" {
+		addresses = append(addresses, ip+"/32")
+	}
+	if ip := p.str("ipv6"); ip != "" {
+		addresses = append(addresses, ip+"/128")
+	}
+	outbound["local_address"] = addresses
+	outbound["private_key"] = p.str("private-key")
+	if mtu := p.integer("mtu"); mtu > 0 {
+		outbound["mtu"] = mtu
+	}
+	if workers := p.integer("workers"); workers > 0 {
+		outbound["workers"] = workers
+	}
+
+	var peers []any
+	if v, ok := p.get("peers"); ok {
+		items, _ := v.([]any)
+		for _, item := range items {
+			m, ok := item.(map[string]any)
+			if !ok {
+				return errors.New("wireguard peer is not a mapping")
+			}
+			peer := map[string]any{
+				"server":      anyToString(m["server"]),
+				"server_port": anyToInt(m["port"]),
+				"public_key":  anyToString(m["public-key"]),
+			}
+			setIfNotEmptyValue(peer, "pre_shared_key", anyToString(m["pre-shared-key"]))
+			if ips := anyToStrings(m["allowed-ips"]); len(ips) > 0 {
+				peer["allowed_ips"] = ips
+			}
+			if reserved, ok := m["reserved"]; ok {
+				peer["reserved"] = reserved
+			}
+			peers = append(peers, peer)
+		}
+	} else {
+		peer := map[string]any{
+			"server":      outbound["server"],
+			"server_port": outbound["server_port"],
+			"public_key":  p.str("public-key"),
+		}
+		setIfNotEmptyValue(peer, "pre_shared_key", p.str("pre-shared-key"))
+		if ips := p.strs("allowed-ips"); len(ips) > 0 {
+			peer["allowed_ips"] = ips
+		}
+		if reserved, ok := p.get("reserved"); ok {
+			peer["reserved"] = reserved
+		}
+		peers = append(peers, peer)
+	}
+	outbound["peers"] = peers
+	return nil
+}
+
+func sshSingBox(p *proxyFields, outbound map[string]any) error {
+	outbound["type"] = "ssh"
+	setIfNotEmptyValue(outbound, "user", p.str("username"))
+	setIfNotEmptyValue(outbound, "password", p.str("password"))
+	setIfNotEmptyValue(outbound, "private_key", p.str("private-key"))
+	setIfNotEmptyValue(outbound, "private_key_passphrase", p.str("private-key-passphrase"))
+	if keys := p.strs("host-key"); len(keys) > 0 {
+		outbound["host_key"] = keys
+	}
+	if algorithms := p.strs("host-key-algorithms"); len(algorithms) > 0 {
+		outbound["host_key_algorithms"] = algorithms
+	}
+	return nil
+}
+
+// ExportSingBox converts mihomo proxies (JSON array, or JSON/YAML with a
+// "proxies" list) to sing-box outbounds
+//
+//export ExportSingBox
+func ExportSingBox(data *C.char) *C.char {
+	return respond("ExportSingBox", func() (any, []string, error) {
+		if data == nil {
+			return nil, nil, errNullInput
+		}
+
+		proxies, err := decodeProxyList([]byte(C.GoString(data)))
+		if err != nil {
+			return nil, nil, err
+		}
+
+		results := make([]singBoxOutboundExport, 0, len(proxies))
+		for _, proxy := range proxies {
+			results = append(results, exportSingBoxOutbound(proxy))
+		}
+		return results, nil, nil
+	})
+}
@@ -0,0 +1,878 @@
+package main
+
+import "C"
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Outbound status values reported by ImportSingBox
+const (
+	outboundConverted = "converted"
+	outboundSkipped   = "skipped"
+	outboundFailed    = "failed"
+)
+
+// singBoxNonProxyTypes are outbound types that route traffic locally or
+// group other outbounds, so they have no mihomo proxy counterpart
+var singBoxNonProxyTypes = map[string]bool{
+	"direct": true, "block": true, "dns": true, "selector": true, "urltest": true,
+}
+
+// singBoxIPVersions maps sing-box domain_strategy to mihomo ip-version
+var singBoxIPVersions = map[string]string{
+	"prefer_ipv4": "ipv4-prefer",
+	"prefer_ipv6": "ipv6-prefer",
+	"ipv4_only":   "ipv4",
+	"ipv6_only":   "ipv6",
+}
+
+// singBoxOutbound describes what happened to one sing-box outbound
+type singBoxOutbound struct {
+	Index       int      `json:"index"`
+	Tag         string   `json:"tag"`
+	Type        string   `json:"type"`
+	Status      string   `json:"status"`
+	Name        string   `json:"name,omitempty"`
+	Unsupported []string `json:"unsupported,omitempty"`
+	Error       string   `json:"error,omitempty"`
+}
+
+type singBoxImport struct {
+	Proxies   []map[string]any  `json:"proxies"`
+	Outbounds []singBoxOutbound `json:"outbounds"`
+}
+
+// warnings describes the outbounds that were rejected or lost fields
+func (r singBoxImport) warnings() []string {
+	var warnings []string
+	for _, outbound := range r.Outbounds {
+		warnings = append(warnings, outboundWarnings("sing-box", outbound.Tag, outbound.Type,
+			outbound.Status, outbound.Error, outbound.Unsupported)...)
+	}
+	return warnings
+}
+
+// singBoxTLSTarget says which TLS settings a mihomo proxy type accepts
+type singBoxTLSTarget struct {
+	flag    bool   // mihomo needs an explicit "tls: true"
+	sni     string // key that carries the server name, if any
+	alpn    bool
+	utls    bool
+	reality bool
+	ech     bool
+}
+
+// decodeSingBoxOutbounds accepts a full sing-box config, a bare array of
+// outbounds or a single outbound object
+func decodeSingBoxOutbounds(data []byte) ([]map[string]any, error) {
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 {
+		return nil, errors.New("empty input")
+	}
+
+	var doc any
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("input is not valid JSON: %w", err)
+	}
+	if m, ok := doc.(map[string]any); ok {
+		if outbounds, ok := m["outbounds"]; ok {
+			doc = outbounds
+		} else if _, ok := m["type"]; ok {
+			doc = []any{m}
+		} else {
+			return nil, errors.New("no outbounds found in input")
+		}
+	}
+
+	items, ok := doc.([]any)
+	if !ok {
+		return nil, errors.New("outbounds is not a list")
+	}
+	outbounds := make([]map[string]any, 0, len(items))
+	for i, item := range items {
+		m, ok := item.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("outbound #%d is not an object", i+1)
+		}
+		outbounds = append(outbounds, m)
+	}
+	return outbounds, nil
+}
+
+// importSingBox converts sing-box outbounds to mihomo proxy maps. A
+// shadowsocks outbound whose detour is a shadowtls outbound becomes a
+// single mihomo proxy with the shadow-tls plugin.
+func importSingBox(outbounds []map[string]any) singBoxImport {
+	result := singBoxImport{
+		Proxies:   make([]map[string]any, 0),
+		Outbounds: make([]singBoxOutbound, 0, len(outbounds)),
+	}
+
+	byTag := make(map[string]map[string]any)
+	for _, outbound := range outbounds {
+		if tag := anyToString(outbound["tag"]); tag != "" {
+			byTag[tag] = outbound
+		}
+	}
+	folded := make(map[string]bool)
+	for _, outbound := range outbounds {
+		if anyToString(outbound["type"]) != "shadowsocks" {
+			continue
+		}
+		if detour := byTag[anyToString(outbound["detour"])]; detour != nil && anyToString(detour["type"]) == "shadowtls" {
+			folded[anyToString(detour["tag"])] = true
+		}
+	}
+
+	names := make(map[string]int)
+	for i, outbound := range outbounds {
+		entry := singBoxOutbound{
+			Index: i,
+			Tag:   anyToString(outbound["tag"]),
+			Type:  anyToString(outbound["type"]),
+		}
+
+		switch {
+		case singBoxNonProxyTypes[entry.Type]:
+			entry.Status = outboundSkipped
+			entry.Error = fmt.Sprintf("%s outbound is not a proxy", entry.Type)
+		case entry.Type == "shadowtls" && folded[entry.Tag]:
+			entry.Status = outboundSkipped
+			entry.Error = "merged into the shadowsocks outbound that uses it as detour"
+		default:
+			proxy, unsupported, err := importSingBoxOutbound(outbound, byTag)
+			entry.Unsupported = unsupported
+			if err != nil {
+				entry.Status = outboundFailed
+				entry.Error = err.Error()
+				break
+			}
+			name := anyToString(proxy["name"])
+			if name == "" {
+				name = fmt.Sprintf("%s:%v", proxy["server"], proxy["port"])
+			}
+			entry.Status = outboundConverted
+			entry.Name = uniqueProxyName(names, name)
+			proxy["name"] = entry.Name
+			result.Proxies = append(result.Proxies, proxy)
+		}
+
+		result.Outbounds = append(result.Outbounds, entry)
+	}
+
+	return result
+}
+
+// importSingBoxOutbound maps one proxy outbound and returns the sing-box
+// fields that have no mihomo equivalent
+func importSingBoxOutbound(outbound map[string]any, byTag map[string]map[string]any) (map[string]any, []string, error) {
+	s := newProxyFields(outbound)
+	s.use("type", "tag")
+
+	proxy := map[string]any{
+		"name":   anyToString(outbound["tag"]),
+		"server": s.str("server"),
+		"port":   s.integer("server_port"),
+	}
+
+	var err error
+	switch t := anyToString(outbound["type"]); t {
+	case "shadowsocks":
+		err = importSingBoxShadowsocks(s, proxy, byTag)
+	case "vmess":
+		err = importSingBoxVMess(s, proxy)
+	case "vless":
+		err = importSingBoxVLESS(s, proxy)
+	case "trojan":
+		err = importSingBoxTrojan(s, proxy)
+	case "hysteria":
+		err = importSingBoxHysteria(s, proxy)
+	case "hysteria2":
+		err = importSingBoxHysteria2(s, proxy)
+	case "tuic":
+		err = importSingBoxTUIC(s, proxy)
+	case "anytls":
+		err = importSingBoxAnyTLS(s, proxy)
+	case "socks":
+		err = importSingBoxSocks(s, proxy)
+	case "http":
+		err = importSingBoxHTTP(s, proxy)
+	case "wireguard":
+		err = importSingBoxWireGuard(s, proxy)
+	case "ssh":
+		err = importSingBoxSSH(s, proxy)
+	case "shadowtls":
+		err = errors.New("shadowtls outbound is only supported as the detour of a shadowsocks outbound")
+	case "":
+		err = errors.New("outbound has no type")
+	default:
+		err = fmt.Errorf("outbound type %q is not supported by mihomo", t)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	importSingBoxDial(s, proxy)
+	if proxy["server"] == "" {
+		return nil, s.unused(), errors.New("outbound has no server")
+	}
+
+	return proxy, s.unused(), nil
+}
+
+// importSingBoxDial maps the dial fields shared by all outbounds
+func importSingBoxDial(s *proxyFields, proxy map[string]any) {
+	if s.has("detour") && !s.used["detour"] {
+		proxy["dialer-proxy"] = s.str("detour")
+	}
+	if s.boolean("tcp_fast_open") {
+		proxy["tfo"] = true
+	}
+	if s.boolean("tcp_multi_path") {
+		proxy["mptcp"] = true
+	}
+	if s.has("bind_interface") {
+		proxy["interface-name"] = s.str("bind_interface")
+	}
+	if s.has("routing_mark") {
+		proxy["routing-mark"] = s.integer("routing_mark")
+	}
+	if v, ok := singBoxIPVersions[anyToString(s.m["domain_strategy"])]; ok {
+		s.use("domain_strategy")
+		proxy["ip-version"] = v
+	}
+}
+
+// importSingBoxUDP enables UDP unless the outbound is limited to TCP,
+// which is the sing-box default
+func importSingBoxUDP(s *proxyFields, proxy map[string]any) {
+	if s.str("network") != "tcp" {
+		proxy["udp"] = true
+	}
+}
+
+func importSingBoxTLS(s *proxyFields, proxy map[string]any, target singBoxTLSTarget) {
+	if !s.boolean("tls.enabled") {
+		return
+	}
+	if target.flag {
+		proxy["tls"] = true
+	}
+	if target.sni != "" && s.has("tls.server_name") {
+		proxy[target.sni] = s.str("tls.server_name")
+	}
+	if s.boolean("tls.insecure") {
+		proxy["skip-cert-verify"] = true
+	}
+	if target.alpn && s.has("tls.alpn") {
+		proxy["alpn"] = s.strs("tls.alpn")
+	}
+	if target.utls && s.boolean("tls.utls.enabled") {
+		fingerprint := s.str("tls.utls.fingerprint")
+		if fingerprint == "" {
+			fingerprint = "chrome"
+		}
+		proxy["client-fingerprint"] = fingerprint
+	}
+	if target.reality && s.boolean("tls.reality.enabled") {
+		proxy["reality-opts"] = map[string]any{
+			"public-key": s.str("tls.reality.public_key"),
+			"short-id":   s.str("tls.reality.short_id"),
+		}
+	}
+	if target.ech && s.boolean("tls.ech.enabled") {
+		ech := map[string]any{"enable": true}
+		if config := importSingBoxECHConfig(s.strs("tls.ech.config")); config != "" {
+			ech["config"] = config
+		}
+		proxy["ech-opts"] = ech
+	}
+}
+
+// importSingBoxECHConfig turns the PEM lines sing-box stores into the
+// base64 config list mihomo expects
+func importSingBoxECHConfig(lines []string) string {
+	var b strings.Builder
+	for _, line := range lines {
+		for _, part := range strings.Split(line, "\n") {
+			part = strings.TrimSpace(part)
+			if part != "" && !strings.HasPrefix(part, "-----") {
+				b.WriteString(part)
+			}
+		}
+	}
+	return b.String()
+}
+
+func importSingBoxTransport(s *proxyFields, proxy map[string]any) error {
+	if !s.has("transport") {
+		return nil
+	}
+
+	switch t := s.str("transport.type"); t {
+	case "http":
+		hosts := s.strs("transport.host")
+		path := s.str("transport.path")
+		if proxy["tls"] == true {
+			proxy["network"] = "h2"
+			opts := map[string]any{}
+			if len(hosts) > 0 {
+				opts["host"] = hosts
+			}
+			if path != "" {
+				opts["path"] = path
+			}
+			proxy["h2-opts"] = opts
+			return nil
+		}
+		proxy["network"] = "http"
+		opts := map[string]any{}
+		if path != "" {
+			opts["path"] = []string{path}
+		}
+		if s.has("transport.method") {
+			opts["method"] = s.str("transport.method")
+		}
+		if len(hosts) > 0 {
+			opts["headers"] = map[string]any{"Host": hosts}
+		}
+		proxy["http-opts"] = opts
+	case "ws", "httpupgrade":
+		proxy["network"] = "ws"
+		opts := map[string]any{}
+		if s.has("transport.path") {
+			opts["path"] = s.str("transport.path")
+		}
+		headers := importSingBoxHeaders(s, "transport.headers")
+		if t == "httpupgrade" {
+			opts["v2ray-http-upgrade"] = true
+			if s.has("transport.host") {
+				headers["Host"] = s.str("transport.host")
+			}
+		}
+		if len(headers) > 0 {
+			opts["headers"] = headers
+		}
+		if s.has("transport.max_early_data") {
+			opts["max-early-data"] = s.integer("transport.max_early_data")
+		}
+		if s.has("transport.early_data_header_name") {
+			opts["early-data-header-name"] = s.str("transport.early_data_header_name")
+		}
+		proxy["ws-opts"] = opts
+	case "grpc":
+		proxy["network"] = "grpc"
+		proxy["grpc-opts"] = map[string]any{
+			"grpc-service-name": s.str("transport.service_name"),
+		}
+	default:
+		return fmt.Errorf("transport %q is not supported by mihomo", t)
+	}
+	return nil
+}
+
+// importSingBoxHeaders reads a sing-box header map, whose values may be
+// a string or a list of strings
+func importSingBoxHeaders(s *proxyFields, path string) map[string]any {
+	headers := map[string]any{}
+	v, _ := s.get(path)
+	m, _ := v.(map[string]any)
+	for k, value := range m {
+		if values := anyToStrings(value); len(values) > 0 {
+			headers[k] = values[0]
+		}
+	}
+	return headers
+}
+
+func importSingBoxMultiplex(s *proxyFields, proxy map[string]any) {
+	if !s.boolean("multiplex.enabled") {
+		return
+	}
+	smux := map[string]any{"enabled": true}
+	if s.has("multiplex.protocol") {
+		smux["protocol"] = s.str("multiplex.protocol")
+	}
+	if s.has("multiplex.max_connections") {
+		smux["max-connections"] = s.integer("multiplex.max_connections")
+	}
+	if s.has("multiplex.min_streams") {
+		smux["min-streams"] = s.integer("multiplex.min_streams")
+	}
+	if s.has("multiplex.max_streams") {
+		smux["max-streams"] = s.integer("multiplex.max_streams")
+	}
+	if s.boolean("multiplex.padding") {
+		smux["padding"] = true
+	}
+	if s.boolean("multiplex.brutal.enabled") {
+		smux["brutal-opts"] = map[string]any{
+			"enabled": true,
+			"up":      strconv.Itoa(s.integer("multiplex.brutal.up_mbps")),
+			"down":    strconv.Itoa(s.integer("multiplex.brutal.down_mbps")),
+		}
+	}
+	proxy["smux"] = smux
+}
+
+// importSingBoxPacketEncoding maps packet_encoding of vmess and vless
+func importSingBoxPacketEncoding(s *proxyFields, proxy map[string]any) {
+	switch s.str("packet_encoding") {
+	case "xudp":
+		proxy["xudp"] = true
+	case "packetaddr":
+		proxy["packet-addr"] = true
+	}
+}
+
+// importSingBoxDuration reads a sing-box duration string ("30s", "1m")
+// in the given unit
+func importSingBoxDuration(s *proxyFields, path string, unit time.Duration) (int, error) {
+	value := s.str(path)
+	if n, err := strconv.Atoi(value); err == nil {
+		return n, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s %q", path, value)
+	}
+	return int(d / unit), nil
+}
+
+// parsePluginOpts splits a SIP003 plugin option string ("a=b;c")
+func parsePluginOpts(opts string) map[string]string {
+	result := make(map[string]string)
+	for _, item := range strings.Split(opts, ";") {
+		if item = strings.TrimSpace(item); item == "" {
+			continue
+		}
+		k, v, _ := strings.Cut(item, "=")
+		result[k] = v
+	}
+	return result
+}
+
+// applySIP003Plugin maps a SIP003 plugin and its option string onto the
+// plugin and plugin-opts of a mihomo ss proxy
+func applySIP003Plugin(proxy map[string]any, plugin, pluginOpts string) error {
+	opts := parsePluginOpts(pluginOpts)
+	switch plugin {
+	case "obfs-local", "simple-obfs":
+		// mihomo rejects an obfs plugin without a mode when it dials
+		mode := opts["obfs"]
+		if mode != "http" && mode != "tls" {
+			return fmt.Errorf("obfs plugin needs obfs=http or obfs=tls, got %q", mode)
+		}
+		proxy["plugin"] = "obfs"
+		pluginOpts := map[string]any{"mode": mode}
+		if host := opts["obfs-host"]; host != "" {
+			pluginOpts["host"] = host
+		}
+		proxy["plugin-opts"] = pluginOpts
+	case "v2ray-plugin":
+		proxy["plugin"] = "v2ray-plugin"
+		mode := opts["mode"]
+		if mode == "" {
+			mode = "websocket"
+		}
+		pluginOpts := map[string]any{"mode": mode}
+		if host := opts["host"]; host != "" {
+			pluginOpts["host"] = host
+		}
+		if path := opts["path"]; path != "" {
+			pluginOpts["path"] = path
+		}
+		if _, ok := opts["tls"]; ok {
+			pluginOpts["tls"] = true
+		}
+		if _, ok := opts["mux"]; ok {
+			pluginOpts["mux"] = true
+		}
+		proxy["plugin-opts"] = pluginOpts
+	default:
+		return fmt.Errorf("shadowsocks plugin %q is not supported by mihomo", plugin)
+	}
+	return nil
+}
+
+func importSingBoxShadowsocks(s *proxyFields, proxy map[string]any, byTag map[string]map[string]any) error {
+	proxy["type"] = "ss"
+	proxy["cipher"] = s.str("method")
+	proxy["password"] = s.str("password")
+	importSingBoxUDP(s, proxy)
+
+	switch v, _ := s.get("udp_over_tcp"); uot := v.(type) {
+	case bool:
+		if uot {
+			proxy["udp-over-tcp"] = true
+		}
+	case map[string]any:
+		if anyToBool(uot["enabled"]) {
+			proxy["udp-over-tcp"] = true
+			if version := anyToInt(uot["version"]); version != 0 {
+				proxy["udp-over-tcp-version"] = version
+			}
+		}
+	}
+
+	if s.has("plugin") {
+		if err := applySIP003Plugin(proxy, s.str("plugin"), s.str("plugin_opts")); err != nil {
+			return err
+		}
+	}
+
+	if detour := byTag[anyToString(s.m["detour"])]; detour != nil && anyToString(detour["type"]) == "shadowtls" {
+		s.use("detour")
+		if err := importSingBoxShadowTLS(detour, proxy); err != nil {
+			return err
+		}
+	}
+
+	importSingBoxMultiplex(s, proxy)
+	return nil
+}
+
+// importSingBoxShadowTLS folds a shadowtls detour into the shadow-tls
+// plugin of the shadowsocks proxy that uses it
+func importSingBoxShadowTLS(outbound map[string]any, proxy map[string]any) error {
+	if proxy["plugin"] != nil {
+		return errors.New("shadowsocks outbound has both a plugin and a shadowtls detour")
+	}
+
+	s := newProxyFields(outbound)
+	proxy["server"] = s.str("server")
+	proxy["port"] = s.integer("server_port")
+	proxy["plugin"] = "shadow-tls"
+	pluginOpts := map[string]any{
+		"host":     s.str("tls.server_name"),
+		"password": s.str("password"),
+	}
+	if version := s.integer("version"); version != 0 {
+		pluginOpts["version"] = version
+	}
+	if s.boolean("tls.utls.enabled") {
+		fingerprint := s.str("tls.utls.fingerprint")
+		if fingerprint == "" {
+			fingerprint = "chrome"
+		}
+		proxy["client-fingerprint"] = fingerprint
+	}
+	proxy["plugin-opts"] = pluginOpts
+	return nil
+}
+
+func importSingBoxVMess(s *proxyFields, proxy map[string]any) error {
+	proxy["type"] = "vmess"
+	proxy["uuid"] = s.str("uuid")
+	proxy["alterId"] = s.integer("alter_id")
+	cipher := s.str("security")
+	if cipher == "" {
+		cipher = "auto"
+	}
+	proxy["cipher"] = cipher
+	if s.boolean("global_padding") {
+		proxy["global-padding"] = true
+	}
+	if s.boolean("authenticated_length") {
+		proxy["authenticated-length"] = true
+	}
+	importSingBoxUDP(s, proxy)
+	importSingBoxPacketEncoding(s, proxy)
+	importSingBoxTLS(s, proxy, singBoxTLSTarget{
+		flag: true, sni: "servername", alpn: true, utls: true, reality: true, ech: true,
+	})
+	importSingBoxMultiplex(s, proxy)
+	return importSingBoxTransport(s, proxy)
+}
+
+func importSingBoxVLESS(s *proxyFields, proxy map[string]any) error {
+	proxy["type"] = "vless"
+	proxy["uuid"] = s.str("uuid")
+	if s.has("flow") {
+		proxy["flow"] = s.str("flow")
+	}
+	importSingBoxUDP(s, proxy)
+	importSingBoxPacketEncoding(s, proxy)
+	importSingBoxTLS(s, proxy, singBoxTLSTarget{
+		flag: true, sni: "servername", alpn: true, utls: true, reality: true, ech: true,
+	})
+	importSingBoxMultiplex(s, proxy)
+	return importSingBoxTransport(s, proxy)
+}
+
+func importSingBoxTrojan(s *proxyFields, proxy map[string]any) error {
+	proxy["type"] = "trojan"
+	proxy["password"] = s.str("password")
+	importSingBoxUDP(s, proxy)
+	importSingBoxTLS(s, proxy, singBoxTLSTarget{
+		sni: "sni", alpn: true, utls: true, reality: true, ech: true,
+	})
+	importSingBoxMultiplex(s, proxy)
+	if t, ok := s.lookup("transport.type"); ok && t != "ws" && t != "httpupgrade" && t != "grpc" {
+		return fmt.Errorf("transport %q is not supported by mihomo trojan", t)
+	}
+	return importSingBoxTransport(s, proxy)
+}
+
+// importSingBoxBandwidth reads up/down, which sing-box spells either as
+// a rate string or as *_mbps integers
+func importSingBoxBandwidth(s *proxyFields, proxy map[string]any) {
+	for _, dir := range []string{"up", "down"} {
+		if s.has(dir) {
+			proxy[dir] = s.str(dir)
+		} else if s.has(dir + "_mbps") {
+			proxy[dir] = strconv.Itoa(s.integer(dir+"_mbps")) + " Mbps"
+		}
+	}
+}
+
+// importSingBoxServerPorts maps server_ports ("1000:2000") and
+// hop_interval used by the hysteria protocols for port hopping
+func importSingBoxServerPorts(s *proxyFields, proxy map[string]any) error {
+	if s.has("server_ports") {
+		ports := s.strs("server_ports")
+		for i, port := range ports {
+			ports[i] = strings.ReplaceAll(port, ":", "-")
+		}
+		proxy["ports"] = strings.Join(ports, ",")
+	}
+	if s.has("hop_interval") {
+		interval, err := importSingBoxDuration(s, "hop_interval", time.Second)
+		if err != nil {
+			return err
+		}
+		proxy["hop-interval"] = interval
+	}
+	return nil
+}
+
+func importSingBoxHysteria(s *proxyFields, proxy map[string]any) error {
+	proxy["type"] = "hysteria"
+	importSingBoxBandwidth(s, proxy)
+	if s.has("obfs") {
+		proxy["obfs"] = s.str("obfs")
+	}
+	if s.has("auth") {
+		proxy["auth"] = s.str("auth")
+	}
+	if s.has("auth_str") {
+		proxy["auth-str"] = s.str("auth_str")
+	}
+	if s.has("recv_window_conn") {
+		proxy["recv-window-conn"] = s.integer("recv_window_conn")
+	}
+	if s.has("recv_window") {
+		proxy["recv-window"] = s.integer("recv_window")
+	}
+	if s.boolean("disable_mtu_discovery") {
+		proxy["disable-mtu-discovery"] = true
+	}
+	s.use("network")
+	importSingBoxTLS(s, proxy, singBoxTLSTarget{sni: "sni", alpn: true, ech: true})
+	return importSingBoxServerPorts(s, proxy)
+}
+
+func importSingBoxHysteria2(s *proxyFields, proxy map[string]any) error {
+	proxy["type"] = "hysteria2"
+	proxy["password"] = s.str("password")
+	importSingBoxBandwidth(s, proxy)
+	if s.has("obfs.type") {
+		proxy["obfs"] = s.str("obfs.type")
+		proxy["obfs-password"] = s.str("obfs.password")
+	}
+	s.use("network")
+	importSingBoxTLS(s, proxy, singBoxTLSTarget{sni: "sni", alpn: true, ech: true})
+	return importSingBoxServerPorts(s, proxy)
+}
+
+func importSingBoxTUIC(s *proxyFields, proxy map[string]any) error {
+	proxy["type"] = "tuic"
+	proxy["uuid"] = s.str("uuid")
+	proxy["password"] = s.str("password")
+	if s.has("congestion_control") {
+		proxy["congestion-controller"] = s.str("congestion_control")
+	}
+	if s.has("udp_relay_mode") {
+		proxy["udp-relay-mode"] = s.str("udp_relay_mode")
+	}
+	if s.boolean("udp_over_stream") {
+		proxy["udp-over-stream"] = true
+	}
+	if s.boolean("zero_rtt_handshake") {
+		proxy["reduce-rtt"] = true
+	}
+	if s.has("heartbeat") {
+		heartbeat, err := importSingBoxDuration(s, "heartbeat", time.Millisecond)
+		if err != nil {
+			return err
+		}
+		proxy["heartbeat-interval"] = heartbeat
+	}
+	s.use("network")
+	importSingBoxTLS(s, proxy, singBoxTLSTarget{sni: "sni", alpn: true, ech: true})
+	if s.boolean("tls.disable_sni") {
+		proxy["disable-sni"] = true
+	}
+	return nil
+}
+
+func importSingBoxAnyTLS(s *proxyFields, proxy map[string]any) error {
+	proxy["type"] = "anytls"
+	proxy["password"] = s.str("password")
+	proxy["udp"] = true
+	for key, target := range map[string]string{
+		"idle_session_check_interval": "idle-session-check-interval",
+		"idle_session_timeout":        "idle-session-timeout",
+	} {
+		if s.has(key) {
+			seconds, err := importSingBoxDuration(s, key, time.Second)
+			if err != nil {
+				return err
+			}
+			proxy[target] = seconds
+		}
+	}
+	if s.has("min_idle_session") {
+		proxy["min-idle-session"] = s.integer("min_idle_session")
+	}
+	importSingBoxTLS(s, proxy, singBoxTLSTarget{sni: "sni", alpn: true, utls: true, ech: true})
+	return nil
+}
+
+func importSingBoxSocks(s *proxyFields, proxy map[string]any) error {
+	if version := s.str("version"); version != "" && version != "5" {
+		return fmt.Errorf("socks version %s is not supported by mihomo", version)
+	}
+	proxy["type"] = "socks5"
+	if s.has("username") {
+		proxy["username"] = s.str("username")
+		proxy["password"] = s.str("password")
+	}
+	importSingBoxUDP(s, proxy)
+	return nil
+}
+
+func importSingBoxHTTP(s *proxyFields, proxy map[string]any) error {
+	proxy["type"] = "http"
+	if s.has("username") {
+		proxy["username"] = s.str("username")
+		proxy["password"] = s.str("password")
+	}
+	if headers := importSingBoxHeaders(s, "headers"); len(headers) > 0 {
+		proxy["headers"] = headers
+	}
+	importSingBoxTLS(s, proxy, singBoxTLSTarget{flag: true, sni: "sni"})
+	return nil
+}
+
+// importSingBoxWireGuard maps the legacy wireguard outbound, with either
+// a single implicit peer or a peers list
+func importSingBoxWireGuard(s *proxyFields, proxy map[string]any) error {
+	proxy["type"] = "wireguard"
+	proxy["private-key"] = s.str("private_key")
+	proxy["udp"] = true
+	for _, address := range s.strs("local_address") {
+		ip, _, _ := strings.Cut(address, "/")
+		if strings.Contains(ip, ":") {
+			proxy["ipv6"] = ip
+		} else {
+			proxy["ip"] = ip
+		}
+	}
+	if s.has("mtu") {
+		proxy["mtu"] = s.integer("mtu")
+	}
+	if s.has("workers") {
+		proxy["workers"] = s.integer("workers")
+	}
+
+	if !s.has("peers") {
+		proxy["public-key"] = s.str("peer_public_key")
+		if s.has("pre_shared_key") {
+			proxy["pre-shared-key"] = s.str("pre_shared_key")
+		}
+		if v, ok := s.get("reserved"); ok {
+			proxy["reserved"] = v
+		}
+		return nil
+	}
+
+	v, _ := s.get("peers")
+	items, _ := v.([]any)
+	peers := make([]any, 0, len(items))
+	for _, item := range items {
+		m, ok := item.(map[string]any)
+		if !ok {
+			return errors.New("wireguard peer is not an object")
+		}
+		peer := map[string]any{
+			"server":     anyToString(m["server"]),
+			"port":       anyToInt(m["server_port"]),
+			"public-key": anyToString(m["public_key"]),
+		}
+		if psk := anyToString(m["pre_shared_key"]); psk != "" {
+			peer["pre-shared-key"] = psk
+		}
+		if ips := anyToStrings(m["allowed_ips"]); len(ips) > 0 {
+			peer["allowed-ips"] = ips
+		}
+		if reserved, ok := m["reserved"]; ok {
+			peer["reserved"] = reserved
+		}
+		peers = append(peers, peer)
+	}
+	proxy["peers"] = peers
+	if proxy["server"] == "" && len(peers) > 0 {
+		first := peers[0].(map[string]any)
+		proxy["server"] = first["server"]
+		proxy["port"] = first["port"]
+	}
+	return nil
+}
+
+func importSingBoxSSH(s *proxyFields, proxy map[string]any) error {
+	proxy["type"] = "ssh"
+	proxy["username"] = s.str("user")
+	if s.has("password") {
+		proxy["password"] = s.str("password")
+	}
+	if s.has("private_key") {
+		proxy["private-key"] = strings.Join(s.strs("private_key"), "\n")
+	}
+	if s.has("private_key_passphrase") {
+		proxy["private-key-passphrase"] = s.str("private_key_passphrase")
+	}
+	if s.has("host_key") {
+		proxy["host-key"] = s.strs("host_key")
+	}
+	if s.has("host_key_algorithms") {
+		proxy["host-key-algorithms"] = s.strs("host_key_algorithms")
+	}
+	return nil
+}
+
+// ImportSingBox converts sing-box outbounds (a full config, an outbounds
+// array or one outbound object) to mihomo proxies and reports per
+// outbound which fields could not be carried over
+//
+//export ImportSingBox
+func ImportSingBox(data *C.char) *C.char {
+	return respond("ImportSingBox", func() (any, []string, error) {
+		if data == nil {
+			return nil, nil, errNullInput
+		}
+
+		outbounds, err := decodeSingBoxOutbounds([]byte(C.GoString(data)))
+		if err != nil {
+			return nil, nil, err
+		}
+		imported := importSingBox(outbounds)
+		return imported, imported.warnings(), nil
+	})
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"reflect"
+	"slices"
+	"testing"
+)
+
+// singBoxConfig is a client config as exported by sing-box GUI clients,
+// with groups, a shadowtls detour and outbounds mihomo cannot use
+const singBoxConfig = `{
+  "log": {"level": "info"},
+  "outbounds": [
+    {"type": "selector", "tag": "proxy", "outbounds": ["reality", "ws", "hy2"]},
+    {
+      "type": "vless", "tag": "reality", "server": "a.com", "server_port": 443,
+      "uuid": "b831381d-6324-4d53-ad4f-8cda48b30811", "flow": "xtls-rprx-vision",
+      "packet_encoding": "xudp",
+      "tls": {
+        "enabled": true, "server_name": "www.apple.com",
+        "utls": {"enabled": true, "fingerprint": "safari"},
+        "reality": {"enabled": true, "public_key": "jNXHt1yRo0vDuchQlIP6Z0ZvjT3KtzVI-T4E7RoLJS0", "short_id": "0123"}
+      }
+    },
+    {
+      "type": "vmess", "tag": "ws", "server": "b.com", "server_port": 443,
+      "uuid": "b831381d-6324-4d53-ad4f-8cda48b30811", "security": "auto", "network": "tcp",
+      "tls": {"enabled": true, "server_name": "b.com", "insecure": true, "alpn": ["http/1.1"]},
+      "transport": {"type": "ws", "path": "/ray", "headers": {"Host": "cdn.b.com"}, "max_early_data": 2048},
+      "connect_timeout": "5s"
+    },
+    {
+      "type": "hysteria2", "server": "c.com", "server_port": 8443, "password": "pw",
+      "server_ports": ["20000:30000"], "hop_interval": "30s", "up_mbps": 50, "down_mbps": 200,
+      "obfs": {"type": "salamander", "password": "obfs"},
+      "tls": {"enabled": true, "server_name": "c.com"}
+    },
+    {
+      "type": "shadowsocks", "tag": "ss-stls", "method": "2022-blake3-aes-128-gcm",
+      "password": "c2VjcmV0c2VjcmV0c2VjcmV0", "detour": "stls"
+    },
+    {
+      "type": "shadowtls", "tag": "stls", "server": "d.com", "server_port": 443, "version": 3,
+      "password": "stls-pw", "tls": {"enabled": true, "server_name": "www.bing.com"}
+    },
+    {"type": "trojan", "tag": "quic", "server": "e.com", "server_port": 443, "password": "pw", "transport": {"type": "quic"}},
+    {"type": "tor", "tag": "tor"},
+    {"type": "socks", "tag": "socks4", "server": "f.com", "server_port": 1080, "version": "4"},
+    {"type": "direct", "tag": "direct"}
+  ]
+}`
+
+func TestImportSingBox(t *testing.T) {
+	outbounds, err := decodeSingBoxOutbounds([]byte(singBoxConfig))
+	if err != nil {
+		t.Fatalf("decodeSingBoxOutbounds: %v", err)
+	}
+	imported := importSingBox(outbounds)
+
+	want := []map[string]any{
+		{
+			"name": "reality", "type": "vless", "server": "a.com", "port": 443,
+			"uuid": "b831381d-6324-4d53-ad4f-8cda48b30811", "flow": "xtls-rprx-vision",
+			"udp": true, "xudp": true, "tls": true, "servername": "www.apple.com",
+			"client-fingerprint": "safari",
+			"reality-opts":       map[string]any{"public-key": "jNXHt1yRo0vDuchQlIP6Z0ZvjT3KtzVI-T4E7RoLJS0", "short-id": "0123"},
+		},
+		{
+			"name": "ws", "type": "vmess", "server": "b.com", "port": 443,
+			"uuid": "b831381d-6324-4d53-ad4f-8cda48b30811", "alterId": 0, "cipher": "auto",
+			"tls": true, "servername": "b.com", "skip-cert-verify": true, "alpn": []string{"http/1.1"},
+			"network": "ws",
+			"ws-opts": map[string]any{"path": "/ray", "headers": map[string]any{"Host": "cdn.b.com"}, "max-early-data": 2048},
+		},
+		{
+			"name": "c.com:8443", "type": "hysteria2", "server": "c.com", "port": 8443, "password": "pw",
+			"ports": "20000-30000", "hop-interval": 30, "up": "50 Mbps", "down": "200 Mbps",
+			"obfs": "salamander", "obfs-password": "obfs", "sni": "c.com",
+		},
+		{
+			"name": "ss-stls", "type": "ss", "server": "d.com", "port": 443,
+			"cipher": "2022-blake3-aes-128-gcm", "password": "c2VjcmV0c2VjcmV0c2VjcmV0", "udp": true,
+			"plugin":      "shadow-tls",
+			"plugin-opts": map[string]any{"host": "www.bing.com", "password": "stls-pw", "version": 3},
+		},
+	}
+	if len(imported.Proxies) != len(want) {
+		t.Fatalf("imported %q, want %d proxies", proxyNames(imported.Proxies), len(want))
+	}
+	for i := range want {
+		if !reflect.DeepEqual(imported.Proxies[i], want[i]) {
+			t.Errorf("proxy %d = %v, want %v", i, imported.Proxies[i], want[i])
+		}
+	}
+
+	statuses := []string{
+		outboundSkipped, outboundConverted, outboundConverted, outboundConverted, outboundConverted,
+		outboundSkipped, outboundFailed, outboundFailed, outboundFailed, outboundSkipped,
+	}
+	var got []string
+	for _, outbound := range imported.Outbounds {
+		got = append(got, outbound.Status)
+	}
+	if !slices.Equal(got, statuses) {
+		t.Errorf("statuses = %q, want %q", got, statuses)
+	}
+
+	warnings := []string{
+		"sing-box outbound 'ws' has fields mihomo cannot use: connect_timeout",
+		`sing-box outbound 'quic' (trojan) was rejected: transport "quic" is not supported by mihomo trojan`,
+		`sing-box outbound 'tor' (tor) was rejected: outbound type "tor" is not supported by mihomo`,
+		"sing-box outbound 'socks4' (socks) was rejected: socks version 4 is not supported by mihomo",
+	}
+	if got := imported.warnings(); !slices.Equal(got, warnings) {
+		t.Errorf("warnings = %q, want %q", got, warnings)
+	}
+}
+
+func TestDecodeSingBoxOutbounds(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		count int
+		err   string
+	}{
+		{name: "outbounds array", input: `[{"type": "direct"}, {"type": "block"}]`, count: 2},
+		{name: "single outbound", input: `{"type": "trojan", "server": "a.com"}`, count: 1},
+		{name: "empty", input: " \n", err: "empty input"},
+		{name: "no outbounds", input: `{"inbounds": []}`, err: "no outbounds found in input"},
+		{name: "outbound is not an object", input: `{"outbounds": [{"type": "direct"}, "b"]}`, err: "outbound #2 is not an object"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			outbounds, err := decodeSingBoxOutbounds([]byte(tt.input))
+			if tt.err != "" {
+				if err == nil || err.Error() != tt.err {
+					t.Errorf("decodeSingBoxOutbounds = %v, want %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil || len(outbounds) != tt.count {
+				t.Errorf("decodeSingBoxOutbounds = %d outbounds, %v, want %d", len(outbounds), err, tt.count)
+			}
+		})
+	}
+}
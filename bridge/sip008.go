@@ -0,0 +1,139 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// formatSIP008 marks diagnostics of servers from a SIP008 document
+const formatSIP008 = "sip008"
+
+// sip008Server is one entry of a SIP008 online configuration. Some
+// providers write server_port as a string.
+type sip008Server struct {
+	ID         string `json:"id"`
+	Remarks    string `json:"remarks"`
+	Server     string `json:"server"`
+	ServerPort any    `json:"server_port"`
+	Password   string `json:"password"`
+	Method     string `json:"method"`
+	Plugin     string `json:"plugin"`
+	PluginOpts string `json:"plugin_opts"`
+}
+
+type sip008Document struct {
+	Version int            `json:"version"`
+	Servers []sip008Server `json:"servers"`
+}
+
+// decodeSIP008 parses data as a SIP008 document. ok is false for anything
+// else, including JSON without a top-level servers list.
+func decodeSIP008(data string) (doc sip008Document, ok bool) {
+	data = strings.TrimSpace(data)
+	if !strings.HasPrefix(data, "{") {
+		return doc, false
+	}
+	var probe map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(data), &probe); err != nil {
+		return doc, false
+	}
+	if _, found := probe["servers"]; !found {
+		return doc, false
+	}
+	if err := json.Unmarshal([]byte(data), &doc); err != nil {
+		return doc, false
+	}
+	return doc, true
+}
+
+// isSIP008 reports whether data is a SIP008 document
+func isSIP008(data string) bool {
+	_, ok := decodeSIP008(data)
+	return ok
+}
+
+// sip008Proxy converts one SIP008 server to a mihomo ss proxy
+func sip008Proxy(server sip008Server) (map[string]any, error) {
+	if server.Server == "" {
+		return nil, newLinkError("missing_server", "server has no address")
+	}
+	port := anyToInt(server.ServerPort)
+	if port < 1 || port > 65535 {
+		return nil, newLinkError("invalid_port", "bad server_port %v", server.ServerPort)
+	}
+	if server.Method == "" || server.Password == "" {
+		return nil, newLinkError("missing_credentials", "server needs a method and a password")
+	}
+
+	name := server.Remarks
+	if name == "" {
+		name = fmt.Sprintf("%s:%d", server.Server, port)
+	}
+	proxy := map[string]any{
+		"name":     name,
+		"type":     "ss",
+		"server":   server.Server,
+		"port":     port,
+		"cipher":   server.Method,
+		"password": server.Password,
+		"udp":      true,
+	}
+	if server.Plugin != "" {
+		if err := applySIP003Plugin(proxy, server.Plugin, server.PluginOpts); err != nil {
+			return nil, newLinkError("unsupported_plugin", "%s", err.Error())
+		}
+	}
+	return proxy, nil
+}
+
+// convertSIP008 converts every usable server of a SIP008 document,
+// de-duplicating names like ConvertsV2Ray, and warns about the rest. Once
+// ctx is done it stops and returns the number of servers left out.
+func convertSIP008(ctx context.Context, doc sip008Document) (proxies []map[string]any, warnings []string, left int, err error) {
+	proxies = make([]map[string]any, 0, len(doc.Servers))
+	names := make(map[string]int)
+	for i, server := range doc.Servers {
+		if ctx.Err() != nil {
+			return proxies, warnings, len(doc.Servers) - i, nil
+		}
+		proxy, err := sip008Proxy(server)
+		if err != nil {
+			warnings = append(warnings, fmt.Sprintf("SIP008 server %d (%s) was rejected: %v",
+				i+1, cmp.Or(server.Remarks, server.Server), err))
+			continue
+		}
+		proxy["name"] = uniqueProxyName(names, anyToString(proxy["name"]))
+		proxies = append(proxies, proxy)
+	}
+	if len(proxies) == 0 {
+		return nil, warnings, 0, errors.New("SIP008 document has no usable servers")
+	}
+	return proxies, warnings, 0, nil
+}
+
+// diagnoseSIP008 reports every server of a SIP008 document until ctx is
+// done, and how many servers were left out. Line numbers are the 1-based
+// positions in the servers list.
+func diagnoseSIP008(ctx context.Context, doc sip008Document) ([]diagnosedLine, int) {
+	lines := make([]diagnosedLine, 0, len(doc.Servers))
+	for i, server := range doc.Servers {
+		if ctx.Err() != nil {
+			return lines, len(doc.Servers) - i
+		}
+		diag := lineDiagnostic{Line: i + 1, Scheme: "ss", Status: lineParsed, Format: formatSIP008}
+		proxy, err := sip008Proxy(server)
+		var linkErr *linkError
+		if errors.As(err, &linkErr) {
+			diag.Status = lineFailed
+			diag.Code, diag.Message = linkErr.Code, linkErr.Message
+		} else {
+			nameDiagnostic(&diag, proxy)
+		}
+		lines = append(lines, diagnosedLine{diag: diag, proxy: proxy})
+	}
+	return lines, 0
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"slices"
+	"testing"
+)
+
+// sip008Config is an online configuration as served by Outline and
+// shadowsocks-manager, with servers mihomo cannot use
+const sip008Config = `{
+  "version": 1,
+  "servers": [
+    {"id": "1", "remarks": "HK", "server": "a.com", "server_port": 8388, "password": "pw", "method": "aes-128-gcm"},
+    {"id": "2", "remarks": "HK", "server": "b.com", "server_port": "8389", "password": "pw", "method": "chacha20-ietf-poly1305",
+     "plugin": "obfs-local", "plugin_opts": "obfs=http;obfs-host=www.bing.com"},
+    {"id": "3", "server": "c.com", "server_port": 443, "password": "pw", "method": "aes-256-gcm",
+     "plugin": "v2ray-plugin", "plugin_opts": "tls;host=c.com;path=/ws"},
+    {"id": "4", "remarks": "no mode", "server": "d.com", "server_port": 8388, "password": "pw", "method": "aes-128-gcm",
+     "plugin": "obfs-local", "plugin_opts": "obfs-host=www.bing.com"},
+    {"id": "5", "remarks": "kcptun", "server": "e.com", "server_port": 8388, "password": "pw", "method": "aes-128-gcm", "plugin": "kcptun"},
+    {"id": "6", "server": "f.com", "server_port": 0, "password": "pw", "method": "aes-128-gcm"},
+    {"id": "7", "remarks": "no password", "server": "g.com", "server_port": 8388, "method": "aes-128-gcm"}
+  ],
+  "bytes_used": 274877906944
+}`
+
+func TestConvertSIP008(t *testing.T) {
+	doc, ok := decodeSIP008(sip008Config)
+	if !ok {
+		t.Fatal("decodeSIP008 did not recognize the document")
+	}
+	proxies, warnings, left, err := convertSIP008(context.Background(), doc)
+	if err != nil || left != 0 {
+		t.Fatalf("convertSIP008 = %d left, %v", left, err)
+	}
+
+	want := []map[string]any{
+		{"name": "HK", "type": "ss", "server": "a.com", "port": 8388, "cipher": "aes-128-gcm", "password": "pw", "udp": true},
+		{
+			"name": "HK-01", "type": "ss", "server": "b.com", "port": 8389, "cipher": "chacha20-ietf-poly1305", "password": "pw", "udp": true,
+			"plugin": "obfs", "plugin-opts": map[string]any{"mode": "http", "host": "www.bing.com"},
+		},
+		{
+			"name": "c.com:443", "type": "ss", "server": "c.com", "port": 443, "cipher": "aes-256-gcm", "password": "pw", "udp": true,
+			"plugin": "v2ray-plugin", "plugin-opts": map[string]any{"mode": "websocket", "host": "c.com", "path": "/ws", "tls": true},
+		},
+	}
+	if !reflect.DeepEqual(proxies, want) {
+		t.Errorf("proxies = %v, want %v", proxies, want)
+	}
+
+	wantWarnings := []string{
+		`SIP008 server 4 (no mode) was rejected: obfs plugin needs obfs=http or obfs=tls, got ""`,
+		`SIP008 server 5 (kcptun) was rejected: shadowsocks plugin "kcptun" is not supported by mihomo`,
+		"SIP008 server 6 (f.com) was rejected: bad server_port 0",
+		"SIP008 server 7 (no password) was rejected: server needs a method and a password",
+	}
+	if !slices.Equal(warnings, wantWarnings) {
+		t.Errorf("warnings = %q, want %q", warnings, wantWarnings)
+	}
+}
+
+func TestDiagnoseSIP008(t *testing.T) {
+	doc, _ := decodeSIP008(sip008Config)
+	lines, left := diagnoseSIP008(context.Background(), doc)
+
+	var codes []string
+	for i, line := range lines {
+		if line.diag.Line != i+1 || line.diag.Format != formatSIP008 {
+			t.Errorf("server %d reported as line %d, format %q", i+1, line.diag.Line, line.diag.Format)
+		}
+		codes = append(codes, line.diag.Code)
+	}
+	want := []string{"", "", "", "unsupported_plugin", "unsupported_plugin", "invalid_port", "missing_credentials"}
+	if !slices.Equal(codes, want) || left != 0 {
+		t.Errorf("codes = %q, %d left, want %q, 0 left", codes, left, want)
+	}
+}
+
+func TestDecodeSIP008(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		ok   bool
+	}{
+		{name: "document", data: sip008Config, ok: true},
+		{name: "no servers", data: `{"version": 1, "outbounds": []}`},
+		{name: "servers is not a list", data: `{"servers": "a.com"}`},
+		{name: "links", data: "ss://YWVzLTEyOC1nY206cHc@a.com:8388#A"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, ok := decodeSIP008(tt.data); ok != tt.ok {
+				t.Errorf("decodeSIP008 = %v, want %v", ok, tt.ok)
+			}
+		})
+	}
+
+	// A document without any usable server fails as a whole
+	doc, _ := decodeSIP008(`{"servers": [{"server": "a.com", "server_port": 8388}]}`)
+	if _, _, _, err := convertSIP008(context.Background(), doc); err == nil {
+		t.Error("convertSIP008 without usable servers succeeded")
+	}
+}
@@ -0,0 +1,339 @@
+package main
+
+import "C"
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/metacubex/mihomo/common/yaml"
+)
+
+// Content formats recognized by ParseAny
+const (
+	formatBase64     = "base64"
+	formatLinks      = "links"
+	formatProxyLines = "proxy-lines" // Surge, Loon or Quantumult X
+	formatClash      = "clash"
+	formatSingBox    = "singbox"
+	formatXray       = "xray"
+	formatWireGuard  = "wireguard"
+)
+
+// sniffResult is the detected format of some content. Confidence is 1
+// for unambiguous markers and the share of recognized lines for text.
+type sniffResult struct {
+	Format     string  `json:"format"`
+	Confidence float64 `json:"confidence"`
+}
+
+// anyParseResult is the result of ParseAny. Text formats carry the line
+// diagnostics of DiagnoseSubscription; the importers report their
+// problems as response warnings.
+type anyParseResult struct {
+	sniffResult
+	Proxies   []map[string]any      `json:"proxies"`
+	Lines     []lineDiagnostic      `json:"lines,omitempty"`
+	Summary   *diagnosticSummary    `json:"summary,omitempty"`
+	Envelope  *subscriptionEnvelope `json:"envelope,omitempty"`
+	Truncated bool                  `json:"truncated,omitempty"` // max_nodes was reached or the conversion stopped
+}
+
+// sniffContent detects the format of content after it has been
+// decompressed and transcoded. ok is false if nothing matched or ctx is
+// done.
+func sniffContent(ctx context.Context, content string) (sniffResult, bool) {
+	trimmed := strings.TrimSpace(content)
+	if trimmed == "" {
+		return sniffResult{}, false
+	}
+
+	if trimmed[0] == '{' || trimmed[0] == '[' {
+		if result, ok := sniffJSON(trimmed); ok {
+			return result, true
+		}
+	}
+	if isWireGuardConf(trimmed) {
+		return sniffResult{Format: formatWireGuard, Confidence: 1}, true
+	}
+
+	schema := &struct {
+		Proxies []any `yaml:"proxies"`
+	}{}
+	if err := yaml.Unmarshal([]byte(trimmed), schema); err == nil && schema.Proxies != nil {
+		return sniffResult{Format: formatClash, Confidence: 1}, true
+	}
+
+	return sniffText(ctx, content)
+}
+
+// sniffJSON tells sing-box, Xray, SIP008 and Clash JSON apart by their
+// keys: sing-box outbounds have "type", Xray outbounds "protocol"
+func sniffJSON(data string) (sniffResult, bool) {
+	var doc any
+	if err := json.Unmarshal([]byte(data), &doc); err != nil {
+		return sniffResult{}, false
+	}
+
+	single := false
+	if m, ok := doc.(map[string]any); ok {
+		switch {
+		case m["outbounds"] != nil:
+			doc = m["outbounds"]
+		case m["proxies"] != nil:
+			return sniffResult{Format: formatClash, Confidence: 1}, true
+		case m["servers"] != nil:
+			confidence := 0.8
+			if _, ok := m["version"]; ok {
+				confidence = 1
+			}
+			return sniffResult{Format: formatSIP008, Confidence: confidence}, true
+		default:
+			doc, single = []any{m}, true
+		}
+	}
+
+	items, _ := doc.([]any)
+	var singBox, xray int
+	for _, item := range items {
+		m, _ := item.(map[string]any)
+		if _, ok := m["protocol"]; ok {
+			xray++
+		} else if _, ok := m["type"]; ok {
+			singBox++
+		}
+	}
+	if singBox == 0 && xray == 0 {
+		return sniffResult{}, false
+	}
+
+	result := sniffResult{Format: formatSingBox, Confidence: float64(singBox) / float64(len(items))}
+	if xray > singBox {
+		result = sniffResult{Format: formatXray, Confidence: float64(xray) / float64(len(items))}
+	}
+	if single {
+		result.Confidence *= 0.9
+	}
+	return result, true
+}
+
+// proxySections are the sections of Surge, Loon and Quantumult X configs
+// that hold proxy lines
+var proxySections = map[string]bool{"proxy": true, "server_local": true}
+
+// builtinPolicies are Surge and Loon policies written like proxy lines
+var builtinPolicies = map[string]bool{
+	"direct": true, "reject": true, "reject-tinygif": true, "reject-drop": true, "reject-no-drop": true,
+}
+
+// sniffText classifies share links and proxy lines, after removing any
+// base64 envelope. In a sectioned config only the proxy sections are
+// counted; comments and blank lines never are.
+func sniffText(ctx context.Context, content string) (sniffResult, bool) {
+	data, envelope, err := decodeEnvelope(ctx, content)
+	if err != nil {
+		return sniffResult{}, false
+	}
+
+	var total, links, proxyLines int
+	inProxies := true
+	for _, line := range strings.Split(data, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || line[0] == '#' || line[0] == ';' || strings.HasPrefix(line, "//") {
+			continue
+		}
+		if line[0] == '[' && line[len(line)-1] == ']' {
+			inProxies = proxySections[strings.ToLower(line[1:len(line)-1])]
+			continue
+		}
+		if !inProxies {
+			continue
+		}
+		if _, policy, found := strings.Cut(line, "="); found && builtinPolicies[strings.ToLower(strings.TrimSpace(policy))] {
+			continue
+		}
+		total++
+		if isProxyLine(line) {
+			proxyLines++
+		} else if scheme, _, found := strings.Cut(line, "://"); found {
+			scheme = strings.ToLower(scheme)
+			if convertSchemes[scheme] || bridgeLinkParsers[scheme] != nil {
+				links++
+			}
+		}
+	}
+	if links == 0 && proxyLines == 0 {
+		return sniffResult{}, false
+	}
+
+	if proxyLines > links {
+		return sniffResult{Format: formatProxyLines, Confidence: float64(proxyLines) / float64(total)}, true
+	}
+	result := sniffResult{Format: formatLinks, Confidence: float64(links) / float64(total)}
+	for _, step := range envelope.Path {
+		if strings.HasPrefix(step, "base64") {
+			result.Format = formatBase64
+		}
+	}
+	return result, true
+}
+
+// parseAny detects the format of content and parses it with the matching
+// importer, which may report warnings. Line formats are converted under
+// opts; the proxies of the other importers are filtered by them. Once ctx
+// is done the result is cut short and marked truncated.
+func parseAny(ctx context.Context, content string, opts convertOptions) (anyParseResult, []string, error) {
+	decoded, _, err := decodeLayer(ctx, content)
+	if isStopped(ctx, err) {
+		return stoppedParse(ctx, content)
+	}
+	if err != nil {
+		return anyParseResult{}, nil, err
+	}
+	sniffed, ok := sniffContent(ctx, decoded)
+	if !ok {
+		// Binary payloads arrive base64 encoded from the C++ side, which
+		// may hide compressed JSON or YAML
+		if inner, found := decodeBase64Layer(ctx, decoded); found {
+			if sniffed, ok = sniffContent(ctx, inner); ok && !isLineFormat(sniffed.Format) {
+				decoded = inner
+			}
+		}
+	}
+	if ctx.Err() != nil {
+		return stoppedParse(ctx, content)
+	}
+	if !ok {
+		return anyParseResult{}, nil, newBridgeError(codeUnrecognized, "content format not recognized")
+	}
+
+	result := anyParseResult{sniffResult: sniffed}
+	var warnings []string
+	switch sniffed.Format {
+	case formatSingBox:
+		outbounds, err := decodeSingBoxOutbounds([]byte(decoded))
+		if err != nil {
+			return result, nil, err
+		}
+		imported := importSingBox(outbounds)
+		result.Proxies, warnings = imported.Proxies, imported.warnings()
+	case formatXray:
+		config, err := decodeXrayOutbounds([]byte(decoded))
+		if err != nil {
+			return result, nil, err
+		}
+		imported := importXray(config)
+		result.Proxies, warnings = imported.Proxies, imported.warnings()
+	case formatWireGuard:
+		imported, err := importWireGuardConf(decoded)
+		if err != nil {
+			return result, nil, err
+		}
+		result.Proxies, warnings = imported.Proxies, imported.warnings()
+	case formatClash:
+		provider, err := parseProvider([]byte(decoded), &providerOptions{})
+		if err != nil {
+			return result, nil, err
+		}
+		result.Proxies = provider.Proxies
+	default:
+		// Share links and proxy lines go through the subscription
+		// diagnostics, which remove the envelope themselves
+		if !isLineFormat(sniffed.Format) {
+			content = decoded
+		}
+		converted, warnings, err := convertSubscriptionEx(ctx, content, opts)
+		if err != nil {
+			return result, warnings, err
+		}
+		result.Proxies = converted.Proxies
+		result.Lines = converted.Lines
+		result.Summary = &converted.Summary
+		result.Envelope = &converted.Envelope
+		result.Truncated = converted.Truncated
+		return result, warnings, nil
+	}
+
+	var filtered []string
+	result.Proxies, result.Truncated, filtered = opts.applyToProxies(ctx, result.Proxies)
+	return result, append(warnings, filtered...), nil
+}
+
+// stoppedParse is the empty, truncated result of a parse whose ctx was
+// done before its format was known
+func stoppedParse(ctx context.Context, content string) (anyParseResult, []string, error) {
+	result := anyParseResult{Proxies: make([]map[string]any, 0), Truncated: true}
+	return result, []string{stoppedWarning(ctx, countLines(content), "lines")}, nil
+}
+
+// isLineFormat reports whether a format is read line by line, after the
+// subscription diagnostics have removed its envelope
+func isLineFormat(format string) bool {
+	switch format {
+	case formatBase64, formatLinks, formatProxyLines:
+		return true
+	}
+	return false
+}
+
+// decodeBase64Layer decodes content as one layer of base64 and then
+// decompresses and transcodes the result
+func decodeBase64Layer(ctx context.Context, content string) (string, bool) {
+	compact := strings.Join(strings.Fields(content), "")
+	encoding, _, ok := detectBase64(compact)
+	if !ok {
+		return "", false
+	}
+	buf, err := encoding.DecodeString(strings.TrimRight(compact, "="))
+	if err != nil {
+		return "", false
+	}
+	decoded, _, err := decodeLayer(ctx, string(buf))
+	if err != nil || !isPlainText(decoded) {
+		return "", false
+	}
+	return decoded, true
+}
+
+// outboundWarnings describes a failed outbound, or the fields a converted
+// outbound had to leave behind
+func outboundWarnings(source, tag, kind, status, message string, unsupported []string) []string {
+	switch {
+	case status == outboundFailed:
+		return []string{fmt.Sprintf("%s outbound '%s' (%s) was rejected: %s", source, tag, kind, message)}
+	case status == outboundConverted && len(unsupported) > 0:
+		return []string{fmt.Sprintf("%s outbound '%s' has fields mihomo cannot use: %s",
+			source, tag, strings.Join(unsupported, ", "))}
+	}
+	return nil
+}
+
+// ParseAny detects whether data is a base64 or plain link subscription,
+// Clash YAML, sing-box or Xray JSON, SIP008, Surge/Loon/Quantumult X proxy
+// lines or a WireGuard configuration, and parses it accordingly. options
+// are the JSON conversion options of ConvertSubscriptionEx.
+//
+//export ParseAny
+func ParseAny(data *C.char, options *C.char) *C.char {
+	return respond("ParseAny", func() (any, []string, error) {
+		if data == nil {
+			return nil, nil, errNullInput
+		}
+
+		var optionsJSON string
+		if options != nil {
+			optionsJSON = C.GoString(options)
+		}
+		opts, err := decodeConvertOptions(optionsJSON)
+		if err != nil {
+			return nil, nil, err
+		}
+		ctx, cancel, err := opts.context()
+		if err != nil {
+			return nil, nil, err
+		}
+		defer cancel()
+		return parseAny(ctx, C.GoString(data), opts)
+	})
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestParseAny(t *testing.T) {
+	links := trojanLinks(2)
+
+	tests := []struct {
+		name    string
+		content string
+		format  string
+		names   []string
+		lines   bool   // Routed through the subscription diagnostics
+		failed  int    // Lines the diagnostics report as failed
+		warning string // Prefix of the first warning, naming the importer
+	}{
+		{
+			name:    "links",
+			content: links,
+			format:  formatLinks,
+			names:   []string{"1", "2"},
+			lines:   true,
+		},
+		{
+			name:    "base64",
+			content: base64.StdEncoding.EncodeToString([]byte(links)),
+			format:  formatBase64,
+			names:   []string{"1", "2"},
+			lines:   true,
+		},
+		{
+			name:    "proxy lines",
+			content: "[Proxy]\nDIRECT = direct\nHK = trojan, a.com, 443, password=pw\nJP = ss, b.com, 8388, encrypt-method=aes-128-gcm, password=pw\n",
+			format:  formatProxyLines,
+			names:   []string{"HK", "JP"},
+			lines:   true,
+		},
+		{
+			name:    "SIP008",
+			content: sip008Config,
+			format:  formatSIP008,
+			names:   []string{"HK", "HK-01", "c.com:443"},
+			lines:   true,
+			failed:  4,
+		},
+		{
+			name:    "Clash YAML",
+			content: "proxies:\n  - {name: HK, type: trojan, server: a.com, port: 443, password: pw}\n",
+			format:  formatClash,
+			names:   []string{"HK"},
+		},
+		{
+			name:    "Clash JSON",
+			content: `{"proxies": [{"name": "HK", "type": "trojan", "server": "a.com", "port": 443, "password": "pw"}]}`,
+			format:  formatClash,
+			names:   []string{"HK"},
+		},
+		{
+			name:    "sing-box",
+			content: singBoxConfig,
+			format:  formatSingBox,
+			names:   []string{"reality", "ws", "c.com:8443", "ss-stls"},
+			warning: "sing-box outbound",
+		},
+		{
+			name:    "compressed sing-box",
+			content: base64.StdEncoding.EncodeToString([]byte(compressString(t, "gzip", singBoxConfig))),
+			format:  formatSingBox,
+			names:   []string{"reality", "ws", "c.com:8443", "ss-stls"},
+			warning: "sing-box outbound",
+		},
+		{
+			name:    "Xray",
+			content: xrayClientConfig,
+			format:  formatXray,
+			names:   []string{"HK 01", "ws", "HK 01-01"},
+			warning: "Xray outbound",
+		},
+		{
+			name:    "WireGuard",
+			content: wireGuardConf,
+			format:  formatWireGuard,
+			names:   []string{"Tokyo", "[2001:db8::1]:51820"},
+			warning: "WireGuard peer",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, warnings, err := parseAny(context.Background(), tt.content, convertOptions{})
+			if err != nil {
+				t.Fatalf("parseAny: %v", err)
+			}
+			if result.Format != tt.format {
+				t.Errorf("format = %q, want %q", result.Format, tt.format)
+			}
+			if got := proxyNames(result.Proxies); !slices.Equal(got, tt.names) {
+				t.Errorf("names = %q, want %q", got, tt.names)
+			}
+			if (result.Lines != nil) != tt.lines || (result.Summary != nil) != tt.lines {
+				t.Errorf("%d lines, summary %v, want line diagnostics %v", len(result.Lines), result.Summary, tt.lines)
+			} else if tt.lines && result.Summary.Failed != tt.failed {
+				t.Errorf("%d lines failed, want %d", result.Summary.Failed, tt.failed)
+			}
+			if tt.warning != "" && (len(warnings) == 0 || !strings.HasPrefix(warnings[0], tt.warning)) {
+				t.Errorf("warnings = %q, want the first to start with %q", warnings, tt.warning)
+			}
+		})
+	}
+}
+
+func TestParseAnyOptions(t *testing.T) {
+	// Importer results are filtered by the options too
+	opts := convertOptions{AllowedSchemes: []string{"vless"}}
+	result, warnings, err := parseAny(context.Background(), singBoxConfig, opts)
+	if err != nil {
+		t.Fatalf("parseAny: %v", err)
+	}
+	want := "proxy 'ws' left out: vmess proxies are not allowed"
+	if got := proxyNames(result.Proxies); !slices.Equal(got, []string{"reality"}) || !slices.Contains(warnings, want) {
+		t.Errorf("parseAny = %q, warnings %q, want [reality] and %q", got, warnings, want)
+	}
+}
+
+func TestParseAnyUnrecognized(t *testing.T) {
+	for _, content := range []string{"", "hello world", `{"log": {"level": "info"}}`} {
+		_, _, err := parseAny(context.Background(), content, convertOptions{})
+		var bridgeErr *bridgeError
+		if !errors.As(err, &bridgeErr) || bridgeErr.Code != codeUnrecognized {
+			t.Errorf("parseAny(%q) = %v, want %s", content, err, codeUnrecognized)
+		}
+	}
+}
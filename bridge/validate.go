@@ -0,0 +1,70 @@
+package main
+
+import "C"
+import (
+	"fmt"
+
+	"github.com/metacubex/mihomo/adapter"
+)
+
+// proxyValidation is the verdict of mihomo's outbound constructor for one proxy
+type proxyValidation struct {
+	Index    int    `json:"index"`
+	Name     string `json:"name"`
+	Type     string `json:"type"`
+	Accepted bool   `json:"accepted"`
+	Error    string `json:"error,omitempty"`
+}
+
+// validateProxy builds the proxy with adapter.ParseProxy, exactly as the
+// core does when loading a config. Constructors only decode options and
+// prepare clients; nothing is dialed or resolved here.
+func validateProxy(proxy map[string]any) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("constructor panic: %v", r)
+		}
+	}()
+
+	instance, err := adapter.ParseProxy(proxy)
+	if err != nil {
+		return err
+	}
+	return instance.Close()
+}
+
+func validateProxies(proxies []map[string]any) []proxyValidation {
+	results := make([]proxyValidation, 0, len(proxies))
+	for i, proxy := range proxies {
+		result := proxyValidation{
+			Index: i,
+			Name:  anyToString(proxy["name"]),
+			Type:  anyToString(proxy["type"]),
+		}
+		if err := validateProxy(proxy); err != nil {
+			result.Error = err.Error()
+		} else {
+			result.Accepted = true
+		}
+		results = append(results, result)
+	}
+	return results
+}
+
+// ValidateProxies runs each mihomo proxy (JSON array, or JSON/YAML with a
+// "proxies" list) through mihomo's outbound constructors offline
+//
+//export ValidateProxies
+func ValidateProxies(data *C.char) *C.char {
+	return respond("ValidateProxies", func() (any, []string, error) {
+		if data == nil {
+			return nil, nil, errNullInput
+		}
+
+		proxies, err := decodeProxyList([]byte(C.GoString(data)))
+		if err != nil {
+			return nil, nil, err
+		}
+		return validateProxies(proxies), nil, nil
+	})
+}
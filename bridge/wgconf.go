@@ -0,0 +1,319 @@
+package main
+
+import "C"
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"net"
+	"net/netip"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// wireGuardIgnoredKeys are wg-quick settings that only matter to the local
+// interface and have no mihomo equivalent
+var wireGuardIgnoredKeys = map[string]bool{
+	"listenport": true, "fwmark": true, "table": true, "saveconfig": true,
+	"preup": true, "postup": true, "predown": true, "postdown": true,
+}
+
+// wireGuardSection is one [Interface] or [Peer] section of a wg-quick file.
+// Keys are lowercase; repeated keys keep all their values.
+type wireGuardSection struct {
+	Line   int
+	Name   string // from a "# Name = ..." comment, as written by wg-easy
+	Values map[string][]string
+}
+
+func (s *wireGuardSection) get(key string) string {
+	if values := s.Values[key]; len(values) > 0 {
+		return strings.TrimSpace(values[len(values)-1])
+	}
+	return ""
+}
+
+// list returns the comma separated values of all occurrences of key
+func (s *wireGuardSection) list(key string) []string {
+	var list []string
+	for _, value := range s.Values[key] {
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				list = append(list, item)
+			}
+		}
+	}
+	return list
+}
+
+// wireGuardPeer describes what happened to one [Peer] section
+type wireGuardPeer struct {
+	Index  int    `json:"index"` // Position among the [Peer] sections
+	Line   int    `json:"line"`
+	Status string `json:"status"` // "converted" or "failed"
+	Name   string `json:"name,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+type wireGuardImport struct {
+	Proxies []map[string]any `json:"proxies"`
+	Peers   []wireGuardPeer  `json:"peers"`
+	Ignored []string         `json:"ignored,omitempty"` // wg-quick keys and addresses left out
+}
+
+// warnings describes the rejected peers and the ignored interface keys
+func (r wireGuardImport) warnings() []string {
+	var warnings []string
+	for _, peer := range r.Peers {
+		if peer.Status == outboundFailed {
+			warnings = append(warnings, fmt.Sprintf("WireGuard peer at line %d was rejected: %s", peer.Line, peer.Error))
+		}
+	}
+	if len(r.Ignored) > 0 {
+		warnings = append(warnings, "WireGuard interface settings not used by mihomo: "+strings.Join(r.Ignored, ", "))
+	}
+	return warnings
+}
+
+// isWireGuardConf reports whether content is a wg-quick configuration
+func isWireGuardConf(content string) bool {
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(strings.TrimPrefix(line, "\ufeff"))
+		if line == "" || line[0] == '#' || line[0] == ';' {
+			continue
+		}
+		return strings.EqualFold(line, "[Interface]")
+	}
+	return false
+}
+
+// parseWireGuardSections splits a wg-quick file into its sections
+func parseWireGuardSections(content string) (*wireGuardSection, []*wireGuardSection, error) {
+	var iface *wireGuardSection
+	var peers []*wireGuardSection
+	var current *wireGuardSection
+
+	for i, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(strings.TrimPrefix(line, "\ufeff"))
+		if line == "" {
+			continue
+		}
+		if line[0] == '#' || line[0] == ';' {
+			comment := strings.TrimSpace(line[1:])
+			if key, value, ok := strings.Cut(comment, "="); ok && current != nil &&
+				strings.EqualFold(strings.TrimSpace(key), "name") {
+				current.Name = strings.TrimSpace(value)
+			}
+			continue
+		}
+
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			current = &wireGuardSection{Line: i + 1, Values: make(map[string][]string)}
+			switch name := strings.ToLower(strings.TrimSpace(line[1 : len(line)-1])); name {
+			case "interface":
+				if iface != nil {
+					return nil, nil, fmt.Errorf("line %d: duplicate [Interface] section", i+1)
+				}
+				iface = current
+			case "peer":
+				peers = append(peers, current)
+			default:
+				return nil, nil, fmt.Errorf("line %d: unknown section [%s]", i+1, line[1:len(line)-1])
+			}
+			continue
+		}
+
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			return nil, nil, fmt.Errorf("line %d: expected key = value", i+1)
+		}
+		if current == nil {
+			return nil, nil, fmt.Errorf("line %d: setting outside of a section", i+1)
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		current.Values[key] = append(current.Values[key], strings.TrimSpace(value))
+	}
+
+	if iface == nil {
+		return nil, nil, errors.New("no [Interface] section")
+	}
+	if len(peers) == 0 {
+		return nil, nil, errors.New("no [Peer] section")
+	}
+	return iface, peers, nil
+}
+
+// importWireGuardConf converts a wg-quick configuration to mihomo
+// wireguard proxies, one per peer, each carrying the interface settings.
+// AmneziaWG junk parameters become amnezia-wg-option.
+func importWireGuardConf(content string) (wireGuardImport, error) {
+	iface, peers, err := parseWireGuardSections(content)
+	if err != nil {
+		return wireGuardImport{}, err
+	}
+
+	base, ignoredAddresses, err := wireGuardInterface(iface)
+	if err != nil {
+		return wireGuardImport{}, fmt.Errorf("[Interface] at line %d: %w", iface.Line, err)
+	}
+
+	result := wireGuardImport{
+		Proxies: make([]map[string]any, 0, len(peers)),
+		Peers:   make([]wireGuardPeer, 0, len(peers)),
+	}
+	for key := range iface.Values {
+		if wireGuardIgnoredKeys[key] {
+			result.Ignored = append(result.Ignored, key)
+		}
+	}
+	slices.Sort(result.Ignored)
+	for _, address := range ignoredAddresses {
+		result.Ignored = append(result.Ignored, "address "+address)
+	}
+
+	names := make(map[string]int)
+	for i, section := range peers {
+		entry := wireGuardPeer{Index: i, Line: section.Line}
+		proxy, err := wireGuardPeerProxy(base, section)
+		if err != nil {
+			entry.Status = outboundFailed
+			entry.Error = err.Error()
+		} else {
+			entry.Status = outboundConverted
+			entry.Name = uniqueProxyName(names, anyToString(proxy["name"]))
+			proxy["name"] = entry.Name
+			result.Proxies = append(result.Proxies, proxy)
+		}
+		result.Peers = append(result.Peers, entry)
+	}
+
+	return result, nil
+}
+
+// wireGuardInterface reads the [Interface] settings shared by all peers.
+// It also returns the addresses left out for lack of room in the proxy.
+func wireGuardInterface(iface *wireGuardSection) (map[string]any, []string, error) {
+	privateKey := iface.get("privatekey")
+	if err := checkWireGuardKey("private key", privateKey); err != nil {
+		return nil, nil, err
+	}
+	proxy := map[string]any{
+		"type":        "wireguard",
+		"private-key": privateKey,
+		"udp":         true,
+	}
+	ignored, err := setWireGuardAddresses(proxy, iface.list("address"))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Entries that are not IPs are search domains
+	var dns []string
+	for _, server := range iface.list("dns") {
+		if _, err := netip.ParseAddr(server); err == nil {
+			dns = append(dns, server)
+		}
+	}
+	if len(dns) > 0 {
+		proxy["dns"] = dns
+		proxy["remote-dns-resolve"] = true
+	}
+	if value := iface.get("mtu"); value != "" {
+		mtu, err := strconv.Atoi(value)
+		if err != nil || mtu <= 0 {
+			return nil, nil, fmt.Errorf("bad MTU %q", value)
+		}
+		proxy["mtu"] = mtu
+	}
+
+	amneziaQuery := make(url.Values)
+	for key := range amneziaKeys {
+		if value := iface.get(key); value != "" {
+			amneziaQuery.Set(key, value)
+		}
+	}
+	amnezia, err := amneziaOptions(amneziaQuery)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(amnezia) > 0 {
+		proxy["amnezia-wg-option"] = amnezia
+	}
+	return proxy, ignored, nil
+}
+
+// wireGuardPeerProxy builds the proxy for one [Peer] section
+func wireGuardPeerProxy(base map[string]any, peer *wireGuardSection) (map[string]any, error) {
+	endpoint := peer.get("endpoint")
+	if endpoint == "" {
+		return nil, errors.New("peer has no Endpoint")
+	}
+	server, portStr, err := net.SplitHostPort(endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("bad Endpoint %q: %w", endpoint, err)
+	}
+	port, err := strconv.Atoi(portStr)
+	if err != nil || port < 1 || port > 65535 {
+		return nil, fmt.Errorf("bad Endpoint port %q", portStr)
+	}
+	publicKey := peer.get("publickey")
+	if err := checkWireGuardKey("public key", publicKey); err != nil {
+		return nil, err
+	}
+
+	proxy := maps.Clone(base)
+	proxy["name"] = peer.Name
+	if peer.Name == "" {
+		proxy["name"] = endpoint
+	}
+	proxy["server"] = server
+	proxy["port"] = port
+	proxy["public-key"] = publicKey
+
+	if psk := peer.get("presharedkey"); psk != "" {
+		if err := checkWireGuardKey("pre-shared key", psk); err != nil {
+			return nil, err
+		}
+		proxy["pre-shared-key"] = psk
+	}
+	if allowed := peer.list("allowedips"); len(allowed) > 0 {
+		proxy["allowed-ips"] = allowed
+	}
+	if value := peer.get("persistentkeepalive"); value != "" && !strings.EqualFold(value, "off") {
+		keepalive, err := strconv.Atoi(value)
+		if err != nil || keepalive < 0 {
+			return nil, fmt.Errorf("bad PersistentKeepalive %q", value)
+		}
+		proxy["persistent-keepalive"] = keepalive
+	}
+	// Not part of wg-quick, but Cloudflare WARP configs carry it
+	if value := peer.get("reserved"); value != "" {
+		reserved, err := parseReserved(value)
+		if err != nil {
+			return nil, err
+		}
+		proxy["reserved"] = reserved
+	}
+	return proxy, nil
+}
+
+// ImportWireGuardConf converts a wg-quick configuration file to mihomo
+// wireguard proxies, one per [Peer], and reports per peer whether it
+// could be converted
+//
+//export ImportWireGuardConf
+func ImportWireGuardConf(data *C.char) *C.char {
+	return respond("ImportWireGuardConf", func() (any, []string, error) {
+		if data == nil {
+			return nil, nil, errNullInput
+		}
+
+		imported, err := importWireGuardConf(C.GoString(data))
+		if err != nil {
+			return nil, nil, err
+		}
+		return imported, imported.warnings(), nil
+	})
+}
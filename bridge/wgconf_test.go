@@ -0,0 +1,157 @@
+package main
+
+import (
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+)
+
+const (
+	wireGuardPrivateKey = "yAnz5TF+lXXJte14tji3zlMNq+hd2rYUIgJBgB3fBmk="
+	wireGuardPublicKey  = "xTIBA5rboUvnH4htodjb6e697QjLERt1NAB4mZqp8Dg="
+	wireGuardPSK        = "TrMvSoP4jYQlY6RIzBgbssQqY3vxI2Pi+y71lOWWXX0="
+)
+
+// wireGuardConf is a wg-quick file as written by wg-easy, with a second
+// IPv4 address, a rejected peer and local-only settings
+var wireGuardConf = strings.NewReplacer(
+	"PRIVATE", wireGuardPrivateKey,
+	"PUBLIC", wireGuardPublicKey,
+	"PSK", wireGuardPSK,
+).Replace(`[Interface]
+PrivateKey = PRIVATE
+Address = 10.8.0.2/24, fd42:42:42::2/64
+Address = 10.9.0.2
+DNS = 1.1.1.1, 2606:4700:4700::1111, lan
+MTU = 1420
+ListenPort = 51820
+PostUp = iptables -A FORWARD -i wg0 -j ACCEPT
+
+[Peer]
+# Name = Tokyo
+PublicKey = PUBLIC
+PresharedKey = PSK
+AllowedIPs = 0.0.0.0/0, ::/0
+Endpoint = jp.example.com:51820
+PersistentKeepalive = 25
+
+[Peer]
+PublicKey = not a key
+Endpoint = sg.example.com:51820
+
+[Peer]
+PublicKey = PUBLIC
+AllowedIPs = 10.0.0.0/8
+AllowedIPs = 192.168.0.0/16
+Endpoint = [2001:db8::1]:51820
+PersistentKeepalive = off
+`)
+
+func TestImportWireGuardConf(t *testing.T) {
+	imported, err := importWireGuardConf(wireGuardConf)
+	if err != nil {
+		t.Fatalf("importWireGuardConf: %v", err)
+	}
+
+	base := map[string]any{
+		"type":               "wireguard",
+		"private-key":        wireGuardPrivateKey,
+		"udp":                true,
+		"ip":                 "10.8.0.2/24",
+		"ipv6":               "fd42:42:42::2/64",
+		"dns":                []string{"1.1.1.1", "2606:4700:4700::1111"},
+		"remote-dns-resolve": true,
+		"mtu":                1420,
+		"public-key":         wireGuardPublicKey,
+	}
+	tokyo := map[string]any{
+		"name":                 "Tokyo",
+		"server":               "jp.example.com",
+		"port":                 51820,
+		"pre-shared-key":       wireGuardPSK,
+		"allowed-ips":          []string{"0.0.0.0/0", "::/0"},
+		"persistent-keepalive": 25,
+	}
+	ipv6 := map[string]any{
+		"name":        "[2001:db8::1]:51820",
+		"server":      "2001:db8::1",
+		"port":        51820,
+		"allowed-ips": []string{"10.0.0.0/8", "192.168.0.0/16"},
+	}
+	for _, peer := range []map[string]any{tokyo, ipv6} {
+		for key, value := range base {
+			peer[key] = value
+		}
+	}
+
+	if len(imported.Proxies) != 2 {
+		t.Fatalf("imported %d proxies, want 2", len(imported.Proxies))
+	}
+	for i, want := range []map[string]any{tokyo, ipv6} {
+		if got := imported.Proxies[i]; !reflect.DeepEqual(got, want) {
+			t.Errorf("proxy %d = %v, want %v", i, got, want)
+		}
+	}
+
+	peers := []wireGuardPeer{
+		{Index: 0, Line: 10, Status: outboundConverted, Name: "Tokyo"},
+		{Index: 1, Line: 18, Status: outboundFailed, Error: "wireguard public key is not a base64 encoded 32 byte key"},
+		{Index: 2, Line: 22, Status: outboundConverted, Name: "[2001:db8::1]:51820"},
+	}
+	if !slices.Equal(imported.Peers, peers) {
+		t.Errorf("peers = %+v, want %+v", imported.Peers, peers)
+	}
+
+	warnings := []string{
+		"WireGuard peer at line 18 was rejected: wireguard public key is not a base64 encoded 32 byte key",
+		"WireGuard interface settings not used by mihomo: listenport, postup, address 10.9.0.2",
+	}
+	if got := imported.warnings(); !slices.Equal(got, warnings) {
+		t.Errorf("warnings = %q, want %q", got, warnings)
+	}
+}
+
+func TestImportWireGuardConfErrors(t *testing.T) {
+	peer := "\n[Peer]\nPublicKey = " + wireGuardPublicKey + "\nEndpoint = a.com:51820\n"
+
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{
+			name:    "no peer",
+			content: "[Interface]\nPrivateKey = " + wireGuardPrivateKey + "\nAddress = 10.8.0.2/24\n",
+			want:    "no [Peer] section",
+		},
+		{
+			name:    "no address",
+			content: "[Interface]\nPrivateKey = " + wireGuardPrivateKey + "\n" + peer,
+			want:    "[Interface] at line 1: wireguard interface has no address",
+		},
+		{
+			name:    "bad address",
+			content: "[Interface]\nPrivateKey = " + wireGuardPrivateKey + "\nAddress = 10.8.0.300/24\n" + peer,
+			want:    `[Interface] at line 1: bad interface address "10.8.0.300/24"`,
+		},
+		{
+			name:    "unknown section",
+			content: "[Interface]\nPrivateKey = " + wireGuardPrivateKey + "\n[Relay]\n",
+			want:    "line 3: unknown section [Relay]",
+		},
+		{
+			name:    "setting outside of a section",
+			content: "PrivateKey = " + wireGuardPrivateKey + "\n[Interface]\n",
+			want:    "line 1: setting outside of a section",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := importWireGuardConf(tt.content); err == nil || err.Error() != tt.want {
+				t.Errorf("importWireGuardConf = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
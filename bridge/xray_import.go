@@ -0,0 +1,589 @@
+package main
+
+import "C"
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// xrayNonProxyProtocols are outbound protocols that route traffic locally,
+// so they have no mihomo proxy counterpart
+var xrayNonProxyProtocols = map[string]bool{
+	"freedom": true, "blackhole": true, "dns": true, "loopback": true,
+}
+
+// xrayIPVersions maps Xray sockopt.domainStrategy to mihomo ip-version
+var xrayIPVersions = map[string]string{
+	"useipv4":   "ipv4",
+	"useipv6":   "ipv6",
+	"useipv4v6": "ipv4-prefer",
+	"useipv6v4": "ipv6-prefer",
+	"forceipv4": "ipv4",
+	"forceipv6": "ipv6",
+}
+
+// xrayOutbound describes what happened to one Xray outbound
+type xrayOutbound struct {
+	Index       int      `json:"index"`
+	Tag         string   `json:"tag"`
+	Protocol    string   `json:"protocol"`
+	Status      string   `json:"status"`
+	Name        string   `json:"name,omitempty"`
+	Unsupported []string `json:"unsupported,omitempty"`
+	Error       string   `json:"error,omitempty"`
+}
+
+type xrayImport struct {
+	Proxies   []map[string]any `json:"proxies"`
+	Outbounds []xrayOutbound   `json:"outbounds"`
+}
+
+// warnings describes the outbounds that were rejected or lost fields
+func (r xrayImport) warnings() []string {
+	var warnings []string
+	for _, outbound := range r.Outbounds {
+		warnings = append(warnings, outboundWarnings("Xray", outbound.Tag, outbound.Protocol,
+			outbound.Status, outbound.Error, outbound.Unsupported)...)
+	}
+	return warnings
+}
+
+// xrayConfig is the decoded input. Remarks is the config name v2rayN
+// writes at the top level.
+type xrayConfig struct {
+	Outbounds []map[string]any
+	Remarks   string
+}
+
+// decodeXrayOutbounds accepts a full Xray/v2ray config, a bare array of
+// outbounds or a single outbound object
+func decodeXrayOutbounds(data []byte) (xrayConfig, error) {
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 {
+		return xrayConfig{}, errors.New("empty input")
+	}
+
+	var doc any
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return xrayConfig{}, fmt.Errorf("input is not valid JSON: %w", err)
+	}
+	var config xrayConfig
+	if m, ok := doc.(map[string]any); ok {
+		config.Remarks = anyToString(m["remarks"])
+		if outbounds, ok := m["outbounds"]; ok {
+			doc = outbounds
+		} else if _, ok := m["protocol"]; ok {
+			doc = []any{m}
+		} else {
+			return xrayConfig{}, errors.New("no outbounds found in input")
+		}
+	}
+
+	items, ok := doc.([]any)
+	if !ok {
+		return xrayConfig{}, errors.New("outbounds is not a list")
+	}
+	config.Outbounds = make([]map[string]any, 0, len(items))
+	for i, item := range items {
+		m, ok := item.(map[string]any)
+		if !ok {
+			return xrayConfig{}, fmt.Errorf("outbound #%d is not an object", i+1)
+		}
+		config.Outbounds = append(config.Outbounds, m)
+	}
+	return config, nil
+}
+
+// importXray converts Xray outbounds to mihomo proxy maps. Outbounds
+// without a tag, or with v2rayN's generic "proxy" tag, are named after the
+// config remarks or their server.
+func importXray(config xrayConfig) xrayImport {
+	result := xrayImport{
+		Proxies:   make([]map[string]any, 0),
+		Outbounds: make([]xrayOutbound, 0, len(config.Outbounds)),
+	}
+
+	names := make(map[string]int)
+	for i, outbound := range config.Outbounds {
+		entry := xrayOutbound{
+			Index:    i,
+			Tag:      anyToString(outbound["tag"]),
+			Protocol: anyToString(outbound["protocol"]),
+		}
+
+		if xrayNonProxyProtocols[entry.Protocol] {
+			entry.Status = outboundSkipped
+			entry.Error = fmt.Sprintf("%s outbound is not a proxy", entry.Protocol)
+			result.Outbounds = append(result.Outbounds, entry)
+			continue
+		}
+
+		proxy, unsupported, err := importXrayOutbound(outbound)
+		entry.Unsupported = unsupported
+		if err != nil {
+			entry.Status = outboundFailed
+			entry.Error = err.Error()
+		} else {
+			name := entry.Tag
+			if name == "" || name == "proxy" {
+				name = config.Remarks
+			}
+			if name == "" {
+				name = fmt.Sprintf("%s:%d", proxy["server"], proxy["port"])
+			}
+			entry.Status = outboundConverted
+			entry.Name = uniqueProxyName(names, name)
+			proxy["name"] = entry.Name
+			result.Proxies = append(result.Proxies, proxy)
+		}
+		result.Outbounds = append(result.Outbounds, entry)
+	}
+
+	return result
+}
+
+// xrayEntry is one element of a settings list (vnext, servers, users)
+// together with the path its unused fields are reported under
+type xrayEntry struct {
+	*proxyFields
+	path string
+}
+
+// xrayFirst returns the first object of the list at path. Further
+// entries are reported as unsupported, since a mihomo proxy has one
+// server and one user.
+func xrayFirst(f *proxyFields, path string, unsupported *[]string) (*xrayEntry, bool) {
+	v, ok := f.get(path)
+	if !ok {
+		return nil, false
+	}
+	items, _ := v.([]any)
+	if len(items) == 0 {
+		return nil, false
+	}
+	m, ok := items[0].(map[string]any)
+	if !ok {
+		return nil, false
+	}
+	for i := 1; i < len(items); i++ {
+		*unsupported = append(*unsupported, fmt.Sprintf("%s[%d]", path, i))
+	}
+	entry := &xrayEntry{proxyFields: newProxyFields(m), path: path + "[0]"}
+	// Xray bookkeeping with no meaning for the client side
+	entry.use("email", "level")
+	return entry, true
+}
+
+// xrayServer reads the server entry of vnext (vless, vmess) or servers
+// (the other protocols). Newer Xray versions also accept the fields
+// directly in settings.
+func xrayServer(s *proxyFields, list string, unsupported *[]string) *xrayEntry {
+	if entry, ok := xrayFirst(s, "settings."+list, unsupported); ok {
+		return entry
+	}
+	v, _ := s.get("settings")
+	m, _ := v.(map[string]any)
+	if m == nil {
+		m = make(map[string]any)
+	}
+	entry := &xrayEntry{proxyFields: newProxyFields(m), path: "settings"}
+	entry.use("email", "level")
+	return entry
+}
+
+// xrayUser reads the first user of a vnext or servers entry, falling back
+// to the entry itself in the flat form
+func xrayUser(server *xrayEntry, unsupported *[]string) *xrayEntry {
+	var extra []string
+	if user, ok := xrayFirst(server.proxyFields, "users", &extra); ok {
+		for _, path := range extra {
+			*unsupported = append(*unsupported, server.path+"."+path)
+		}
+		user.path = server.path + "." + user.path
+		return user
+	}
+	return server
+}
+
+// unusedPaths prefixes the unused fields of an entry with its path
+func (e *xrayEntry) unusedPaths() []string {
+	var paths []string
+	for _, path := range e.unused() {
+		paths = append(paths, e.path+"."+path)
+	}
+	return paths
+}
+
+// importXrayOutbound maps one proxy outbound and returns the Xray fields
+// that have no mihomo equivalent
+func importXrayOutbound(outbound map[string]any) (map[string]any, []string, error) {
+	s := newProxyFields(outbound)
+	s.use("protocol", "tag")
+
+	var unsupported []string
+	var entries []*xrayEntry
+	proxy := make(map[string]any)
+
+	var err error
+	switch p := anyToString(outbound["protocol"]); p {
+	case "vless", "vmess":
+		server := xrayServer(s, "vnext", &unsupported)
+		user := xrayUser(server, &unsupported)
+		entries = append(entries, server)
+		if user != server {
+			entries = append(entries, user)
+		}
+		proxy["server"], proxy["port"] = server.str("address"), server.integer("port")
+		if p == "vless" {
+			err = importXrayVLESS(user, proxy)
+		} else {
+			err = importXrayVMess(user, proxy)
+		}
+	case "trojan", "shadowsocks":
+		server := xrayServer(s, "servers", &unsupported)
+		entries = append(entries, server)
+		proxy["server"], proxy["port"] = server.str("address"), server.integer("port")
+		if p == "trojan" {
+			proxy["type"] = "trojan"
+			proxy["password"] = server.str("password")
+			if proxy["password"] == "" {
+				err = errors.New("trojan outbound has no password")
+			}
+			proxy["udp"] = true
+		} else {
+			err = importXrayShadowsocks(server, proxy)
+		}
+	case "socks", "http":
+		server := xrayServer(s, "servers", &unsupported)
+		user := xrayUser(server, &unsupported)
+		entries = append(entries, server)
+		if user != server {
+			entries = append(entries, user)
+		}
+		proxy["server"], proxy["port"] = server.str("address"), server.integer("port")
+		proxy["type"] = map[string]string{"socks": "socks5", "http": "http"}[p]
+		if user.has("user") {
+			proxy["username"] = user.str("user")
+			proxy["password"] = user.str("pass")
+		}
+		if p == "socks" {
+			proxy["udp"] = true
+		}
+	case "":
+		err = errors.New("outbound has no protocol")
+	default:
+		err = fmt.Errorf("outbound protocol %q is not supported by mihomo", p)
+	}
+	if err == nil {
+		err = importXrayStream(s, proxy)
+	}
+	// mihomo's trojan always runs over TLS and its shadowsocks never does
+	if err == nil && proxy["type"] == "trojan" {
+		if proxy["tls"] != true {
+			err = errors.New("trojan without TLS is not supported by mihomo")
+		}
+		delete(proxy, "tls")
+	}
+	if err == nil && proxy["type"] == "ss" && proxy["tls"] == true {
+		err = errors.New("shadowsocks over TLS is not supported by mihomo")
+	}
+
+	importXrayMux(s)
+	if s.has("proxySettings.tag") {
+		proxy["dialer-proxy"] = s.str("proxySettings.tag")
+	}
+
+	unsupported = append(unsupported, s.unused()...)
+	for _, entry := range entries {
+		unsupported = append(unsupported, entry.unusedPaths()...)
+	}
+	if err != nil {
+		return nil, unsupported, err
+	}
+	if proxy["server"] == "" {
+		return nil, unsupported, errors.New("outbound has no server address")
+	}
+	return proxy, unsupported, nil
+}
+
+func importXrayVLESS(user *xrayEntry, proxy map[string]any) error {
+	proxy["type"] = "vless"
+	proxy["uuid"] = user.str("id")
+	if proxy["uuid"] == "" {
+		return errors.New("vless outbound has no user id")
+	}
+	if user.has("flow") {
+		proxy["flow"] = user.str("flow")
+	}
+	if encryption := user.str("encryption"); encryption != "" && encryption != "none" {
+		proxy["encryption"] = encryption
+	}
+	proxy["udp"] = true
+	return nil
+}
+
+func importXrayVMess(user *xrayEntry, proxy map[string]any) error {
+	proxy["type"] = "vmess"
+	proxy["uuid"] = user.str("id")
+	if proxy["uuid"] == "" {
+		return errors.New("vmess outbound has no user id")
+	}
+	proxy["alterId"] = user.integer("alterId")
+	cipher := user.str("security")
+	if cipher == "" {
+		cipher = "auto"
+	}
+	proxy["cipher"] = cipher
+	user.use("experiments")
+	proxy["udp"] = true
+	return nil
+}
+
+func importXrayShadowsocks(server *xrayEntry, proxy map[string]any) error {
+	proxy["type"] = "ss"
+	proxy["cipher"] = server.str("method")
+	proxy["password"] = server.str("password")
+	if proxy["cipher"] == "" || proxy["password"] == "" {
+		return errors.New("shadowsocks outbound needs a method and a password")
+	}
+	proxy["udp"] = true
+	if server.boolean("uot") {
+		proxy["udp-over-tcp"] = true
+		if server.has("UoTVersion") {
+			proxy["udp-over-tcp-version"] = server.integer("UoTVersion")
+		}
+	}
+	return nil
+}
+
+// importXrayMux marks a disabled mux block as read. Xray's mux protocol
+// is not sing-mux, so an enabled one is left to be reported.
+func importXrayMux(s *proxyFields) {
+	mux, _ := s.m["mux"].(map[string]any)
+	if !anyToBool(mux["enabled"]) {
+		s.use("mux")
+	}
+}
+
+// importXrayStream maps streamSettings: the transport, TLS or REALITY and
+// the socket options
+func importXrayStream(s *proxyFields, proxy map[string]any) error {
+	if !s.has("streamSettings") {
+		return nil
+	}
+
+	switch security := strings.ToLower(s.str("streamSettings.security")); security {
+	case "", "none":
+	case "tls":
+		proxy["tls"] = true
+		importXrayTLS(s, proxy, "streamSettings.tlsSettings")
+	case "reality":
+		proxy["tls"] = true
+		importXrayTLS(s, proxy, "streamSettings.realitySettings")
+		publicKey := s.str("streamSettings.realitySettings.publicKey")
+		if publicKey == "" {
+			publicKey = s.str("streamSettings.realitySettings.password")
+		}
+		proxy["reality-opts"] = map[string]any{
+			"public-key": publicKey,
+			"short-id":   s.str("streamSettings.realitySettings.shortId"),
+		}
+	default:
+		return fmt.Errorf("security %q is not supported by mihomo", security)
+	}
+
+	if err := importXrayTransport(s, proxy); err != nil {
+		return err
+	}
+	importXraySockopt(s, proxy)
+	return nil
+}
+
+// importXrayTLS maps the tlsSettings or realitySettings at path
+func importXrayTLS(s *proxyFields, proxy map[string]any, path string) {
+	sniKey := "sni"
+	switch proxy["type"] {
+	case "vmess", "vless":
+		sniKey = "servername"
+	case "socks5":
+		sniKey = ""
+	}
+	if sniKey != "" && s.has(path+".serverName") {
+		proxy[sniKey] = s.str(path + ".serverName")
+	}
+	if s.boolean(path + ".allowInsecure") {
+		proxy["skip-cert-verify"] = true
+	}
+	if s.has(path + ".alpn") {
+		proxy["alpn"] = s.strs(path + ".alpn")
+	}
+	if s.has(path + ".fingerprint") {
+		proxy["client-fingerprint"] = s.str(path + ".fingerprint")
+	}
+	if s.has(path + ".echConfigList") {
+		proxy["ech-opts"] = map[string]any{"enable": true, "config": s.str(path + ".echConfigList")}
+	}
+}
+
+// importXrayTransport maps the network of streamSettings. Transports
+// mihomo lacks (xhttp, kcp, quic) fail the outbound.
+func importXrayTransport(s *proxyFields, proxy map[string]any) error {
+	network := strings.ToLower(s.str("streamSettings.network"))
+	switch proxy["type"] {
+	case "ss", "socks5", "http":
+		if network != "" && network != "tcp" && network != "raw" {
+			return fmt.Errorf("transport %q is not supported by mihomo %s", network, proxy["type"])
+		}
+	}
+
+	switch network {
+	case "", "tcp", "raw":
+		settings := "streamSettings.tcpSettings"
+		if s.has("streamSettings.rawSettings") {
+			settings = "streamSettings.rawSettings"
+		}
+		switch header := s.str(settings + ".header.type"); header {
+		case "", "none":
+		case "http":
+			if proxy["type"] != "vmess" && proxy["type"] != "vless" {
+				return fmt.Errorf("HTTP header obfuscation is not supported by mihomo %s", proxy["type"])
+			}
+			proxy["network"] = "http"
+			opts := map[string]any{}
+			request := settings + ".header.request"
+			if s.has(request + ".method") {
+				opts["method"] = s.str(request + ".method")
+			}
+			if s.has(request + ".path") {
+				opts["path"] = s.strs(request + ".path")
+			}
+			headers := map[string]any{}
+			v, _ := s.get(request + ".headers")
+			m, _ := v.(map[string]any)
+			for key, value := range m {
+				headers[key] = anyToStrings(value)
+			}
+			if len(headers) > 0 {
+				opts["headers"] = headers
+			}
+			s.use(request + ".version")
+			proxy["http-opts"] = opts
+		default:
+			return fmt.Errorf("tcp header %q is not supported by mihomo", header)
+		}
+	case "ws", "httpupgrade":
+		settings := "streamSettings.wsSettings"
+		if network == "httpupgrade" {
+			settings = "streamSettings.httpupgradeSettings"
+		}
+		proxy["network"] = "ws"
+		opts := map[string]any{}
+		headers := map[string]any{}
+		v, _ := s.get(settings + ".headers")
+		m, _ := v.(map[string]any)
+		for key, value := range m {
+			headers[key] = anyToString(value)
+		}
+		if s.has(settings + ".host") {
+			headers["Host"] = s.str(settings + ".host")
+		}
+		if len(headers) > 0 {
+			opts["headers"] = headers
+		}
+		// Early data rides in the path as "?ed=2048"
+		path := s.str(settings + ".path")
+		if base, query, found := strings.Cut(path, "?"); found {
+			values, _ := url.ParseQuery(query)
+			if ed, err := strconv.Atoi(values.Get("ed")); err == nil && ed > 0 {
+				path = base
+				opts["max-early-data"] = ed
+				opts["early-data-header-name"] = "Sec-WebSocket-Protocol"
+			}
+		}
+		if path != "" {
+			opts["path"] = path
+		}
+		if network == "httpupgrade" {
+			opts["v2ray-http-upgrade"] = true
+		}
+		proxy["ws-opts"] = opts
+	case "grpc", "gun":
+		proxy["network"] = "grpc"
+		opts := map[string]any{
+			"grpc-service-name": s.str("streamSettings.grpcSettings.serviceName"),
+		}
+		if s.has("streamSettings.grpcSettings.user_agent") {
+			opts["grpc-user-agent"] = s.str("streamSettings.grpcSettings.user_agent")
+		}
+		proxy["grpc-opts"] = opts
+	case "h2", "http":
+		if proxy["type"] != "vmess" && proxy["type"] != "vless" {
+			return fmt.Errorf("transport %q is not supported by mihomo %s", network, proxy["type"])
+		}
+		proxy["network"] = "h2"
+		opts := map[string]any{}
+		if s.has("streamSettings.httpSettings.host") {
+			opts["host"] = s.strs("streamSettings.httpSettings.host")
+		}
+		if s.has("streamSettings.httpSettings.path") {
+			opts["path"] = s.str("streamSettings.httpSettings.path")
+		}
+		proxy["h2-opts"] = opts
+	default:
+		return fmt.Errorf("transport %q is not supported by mihomo", network)
+	}
+	return nil
+}
+
+// importXraySockopt maps the socket options shared by all outbounds
+func importXraySockopt(s *proxyFields, proxy map[string]any) {
+	const sockopt = "streamSettings.sockopt"
+	if s.has(sockopt + ".dialerProxy") {
+		proxy["dialer-proxy"] = s.str(sockopt + ".dialerProxy")
+	}
+	if s.boolean(sockopt + ".tcpFastOpen") {
+		proxy["tfo"] = true
+	}
+	if s.boolean(sockopt + ".tcpMptcp") {
+		proxy["mptcp"] = true
+	}
+	if s.has(sockopt + ".interface") {
+		proxy["interface-name"] = s.str(sockopt + ".interface")
+	}
+	if s.has(sockopt + ".mark") {
+		proxy["routing-mark"] = s.integer(sockopt + ".mark")
+	}
+	if strategy, ok := s.lookup(sockopt + ".domainStrategy"); ok {
+		if v, ok := xrayIPVersions[strings.ToLower(anyToString(strategy))]; ok {
+			s.use(sockopt + ".domainStrategy")
+			proxy["ip-version"] = v
+		} else if strings.EqualFold(anyToString(strategy), "AsIs") {
+			s.use(sockopt + ".domainStrategy")
+		}
+	}
+}
+
+// ImportXray converts Xray/v2ray outbounds (a full client config, an
+// outbounds array or one outbound object) to mihomo proxies and reports
+// per outbound which fields could not be carried over
+//
+//export ImportXray
+func ImportXray(data *C.char) *C.char {
+	return respond("ImportXray", func() (any, []string, error) {
+		if data == nil {
+			return nil, nil, errNullInput
+		}
+
+		config, err := decodeXrayOutbounds([]byte(C.GoString(data)))
+		if err != nil {
+			return nil, nil, err
+		}
+		imported := importXray(config)
+		return imported, imported.warnings(), nil
+	})
+}
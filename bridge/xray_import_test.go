@@ -0,0 +1,144 @@
+package main
+
+import (
+	"reflect"
+	"slices"
+	"testing"
+)
+
+// xrayClientConfig is a client config as exported by v2rayN, with routing
+// outbounds and outbounds mihomo cannot use
+const xrayClientConfig = `{
+  "remarks": "HK 01",
+  "log": {"loglevel": "warning"},
+  "outbounds": [
+    {
+      "tag": "proxy", "protocol": "vless",
+      "settings": {"vnext": [{"address": "a.com", "port": 443, "users": [
+        {"id": "b831381d-6324-4d53-ad4f-8cda48b30811", "encryption": "none", "flow": "xtls-rprx-vision", "level": 8},
+        {"id": "c831381d-6324-4d53-ad4f-8cda48b30811"}
+      ]}]},
+      "streamSettings": {
+        "network": "tcp", "security": "reality",
+        "realitySettings": {"serverName": "www.apple.com", "fingerprint": "chrome", "publicKey": "jNXHt1yRo0vDuchQlIP6Z0ZvjT3KtzVI-T4E7RoLJS0", "shortId": "0123", "spiderX": "/"}
+      },
+      "mux": {"enabled": false, "concurrency": -1}
+    },
+    {
+      "tag": "ws", "protocol": "vmess",
+      "settings": {"vnext": [{"address": "b.com", "port": 443, "users": [{"id": "b831381d-6324-4d53-ad4f-8cda48b30811", "alterId": 0, "security": "auto"}]}]},
+      "streamSettings": {
+        "network": "ws", "security": "tls",
+        "tlsSettings": {"serverName": "b.com", "allowInsecure": true, "alpn": ["http/1.1"]},
+        "wsSettings": {"path": "/ray?ed=2048", "headers": {"Host": "cdn.b.com"}},
+        "sockopt": {"domainStrategy": "UseIPv4", "tcpFastOpen": true}
+      }
+    },
+    {
+      "protocol": "trojan",
+      "settings": {"servers": [{"address": "c.com", "port": 8443, "password": "pw", "flow": "xtls-rprx-direct"}]},
+      "streamSettings": {"network": "grpc", "security": "tls", "tlsSettings": {"serverName": "c.com"}, "grpcSettings": {"serviceName": "gun"}},
+      "mux": {"enabled": true, "concurrency": 8}
+    },
+    {
+      "tag": "plain-trojan", "protocol": "trojan",
+      "settings": {"servers": [{"address": "d.com", "port": 443, "password": "pw"}]}
+    },
+    {
+      "tag": "xhttp", "protocol": "vless",
+      "settings": {"vnext": [{"address": "e.com", "port": 443, "users": [{"id": "b831381d-6324-4d53-ad4f-8cda48b30811"}]}]},
+      "streamSettings": {"network": "xhttp", "security": "tls"}
+    },
+    {"tag": "wg", "protocol": "wireguard", "settings": {}},
+    {"tag": "direct", "protocol": "freedom", "settings": {}},
+    {"tag": "block", "protocol": "blackhole", "settings": {"response": {"type": "http"}}}
+  ]
+}`
+
+func TestImportXray(t *testing.T) {
+	config, err := decodeXrayOutbounds([]byte(xrayClientConfig))
+	if err != nil {
+		t.Fatalf("decodeXrayOutbounds: %v", err)
+	}
+	imported := importXray(config)
+
+	want := []map[string]any{
+		{
+			"name": "HK 01", "type": "vless", "server": "a.com", "port": 443,
+			"uuid": "b831381d-6324-4d53-ad4f-8cda48b30811", "flow": "xtls-rprx-vision", "udp": true,
+			"tls": true, "servername": "www.apple.com", "client-fingerprint": "chrome",
+			"reality-opts": map[string]any{"public-key": "jNXHt1yRo0vDuchQlIP6Z0ZvjT3KtzVI-T4E7RoLJS0", "short-id": "0123"},
+		},
+		{
+			"name": "ws", "type": "vmess", "server": "b.com", "port": 443,
+			"uuid": "b831381d-6324-4d53-ad4f-8cda48b30811", "alterId": 0, "cipher": "auto", "udp": true,
+			"tls": true, "servername": "b.com", "skip-cert-verify": true, "alpn": []string{"http/1.1"},
+			"network": "ws",
+			"ws-opts": map[string]any{
+				"path": "/ray", "headers": map[string]any{"Host": "cdn.b.com"},
+				"max-early-data": 2048, "early-data-header-name": "Sec-WebSocket-Protocol",
+			},
+			"ip-version": "ipv4", "tfo": true,
+		},
+		{
+			"name": "HK 01-01", "type": "trojan", "server": "c.com", "port": 8443, "password": "pw", "udp": true,
+			"sni": "c.com", "network": "grpc", "grpc-opts": map[string]any{"grpc-service-name": "gun"},
+		},
+	}
+	if len(imported.Proxies) != len(want) {
+		t.Fatalf("imported %q, want %d proxies", proxyNames(imported.Proxies), len(want))
+	}
+	for i := range want {
+		if !reflect.DeepEqual(imported.Proxies[i], want[i]) {
+			t.Errorf("proxy %d = %v, want %v", i, imported.Proxies[i], want[i])
+		}
+	}
+
+	statuses := []string{
+		outboundConverted, outboundConverted, outboundConverted, outboundFailed,
+		outboundFailed, outboundFailed, outboundSkipped, outboundSkipped,
+	}
+	var got []string
+	for _, outbound := range imported.Outbounds {
+		got = append(got, outbound.Status)
+	}
+	if !slices.Equal(got, statuses) {
+		t.Errorf("statuses = %q, want %q", got, statuses)
+	}
+
+	warnings := []string{
+		"Xray outbound 'proxy' has fields mihomo cannot use: settings.vnext[0].users[1], streamSettings.realitySettings.spiderX",
+		"Xray outbound '' has fields mihomo cannot use: mux.concurrency, mux.enabled, settings.servers[0].flow",
+		"Xray outbound 'plain-trojan' (trojan) was rejected: trojan without TLS is not supported by mihomo",
+		`Xray outbound 'xhttp' (vless) was rejected: transport "xhttp" is not supported by mihomo`,
+		`Xray outbound 'wg' (wireguard) was rejected: outbound protocol "wireguard" is not supported by mihomo`,
+	}
+	if got := imported.warnings(); !slices.Equal(got, warnings) {
+		t.Errorf("warnings = %q, want %q", got, warnings)
+	}
+}
+
+func TestImportXrayNames(t *testing.T) {
+	const outbound = `{"protocol": "socks", "settings": {"servers": [{"address": "a.com", "port": 1080}]}}`
+
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{name: "bare outbound", input: outbound, want: "a.com:1080"},
+		{name: "remarks", input: `{"remarks": "JP", "outbounds": [` + outbound + `]}`, want: "JP"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config, err := decodeXrayOutbounds([]byte(tt.input))
+			if err != nil {
+				t.Fatalf("decodeXrayOutbounds: %v", err)
+			}
+			if got := proxyNames(importXray(config).Proxies); !slices.Equal(got, []string{tt.want}) {
+				t.Errorf("names = %q, want [%q]", got, tt.want)
+			}
+		})
+	}
+}
@@ -429,6 +429,8 @@ func parseProtocolParamsAuto(protocol string, fileMap map[string]*ProtocolFileIn
 			"ss":      "shadowsocks",
 			"ssr":     "shadowsocksr",
 			"socks5h": "socks5",
+			"wg":      "wireguard",
+			"mierus":  "mieru",
 		}
 		if target, ok := manualMap[protocol]; ok {
 			info = fileMap[target]
@@ -533,8 +535,15 @@ func parseProtocolParamsAuto(protocol string, fileMap map[string]*ProtocolFileIn
 		params[k] = &paramCopy
 	}
 
-	for _, field := range optionStruct.Fields.List {
+	fields := optionStruct.Fields.List
+	for i := 0; i < len(fields); i++ {
+		field := fields[i]
 		if len(field.Names) == 0 {
+			// Embedded option structs (e.g. WireGuardPeerOption) add their
+			// fields; BasicOption was added above
+			if embedded := findEmbeddedStruct(node, field); embedded != nil {
+				fields = append(fields, embedded.Fields.List...)
+			}
 			continue
 		}
 
@@ -551,6 +560,25 @@ func parseProtocolParamsAuto(protocol string, fileMap map[string]*ProtocolFileIn
 	}
 }
 
+// findEmbeddedStruct finds the struct type of an embedded field declared
+// in the same file, skipping BasicOption and tagged (named) embeddings
+func findEmbeddedStruct(node *ast.File, field *ast.Field) *ast.StructType {
+	ident, ok := field.Type.(*ast.Ident)
+	if !ok || ident.Name == "BasicOption" || field.Tag != nil {
+		return nil
+	}
+
+	var result *ast.StructType
+	ast.Inspect(node, func(n ast.Node) bool {
+		if typeSpec, ok := n.(*ast.TypeSpec); ok && typeSpec.Name.Name == ident.Name {
+			result, _ = typeSpec.Type.(*ast.StructType)
+			return false
+		}
+		return result == nil
+	})
+	return result
+}
+
 // parseProtocolParams parses parameters for a specific protocol
 func parseProtocolParams(protocol string) *ProtocolCompat {
 	filename := findProtocolFile(protocol)
@@ -787,11 +815,23 @@ func generateCppHeader(compatMap map[string]*ProtocolCompat, outputPath string)
 
 	sb.WriteString("};\n\n")
 
-	// Helper function
+	// Runtime table and helper functions
+	sb.WriteString("// Parameters of the linked mihomo, reflected from its option structs by the\n")
+	sb.WriteString("// bridge and merged with the hardcoded flags above (see mihomo_bridge.cpp).\n")
+	sb.WriteString("// Empty if the bridge cannot be queried.\n")
+	sb.WriteString("const std::map<std::string, std::map<std::string, ParamCompatInfo>>& runtimeParamCompat();\n\n")
+
+	sb.WriteString("// The runtime table, or PARAM_COMPAT without one\n")
+	sb.WriteString("inline const std::map<std::string, std::map<std::string, ParamCompatInfo>>& paramCompatTable() {\n")
+	sb.WriteString("    const auto& runtime = runtimeParamCompat();\n")
+	sb.WriteString("    return runtime.empty() ? PARAM_COMPAT : runtime;\n")
+	sb.WriteString("}\n\n")
+
 	sb.WriteString("// Check if a protocol supports a specific parameter\n")
 	sb.WriteString("inline bool isParamSupported(const std::string& protocol, const std::string& param) {\n")
-	sb.WriteString("    auto proto_it = PARAM_COMPAT.find(protocol);\n")
-	sb.WriteString("    if (proto_it == PARAM_COMPAT.end()) return false;\n")
+	sb.WriteString("    const auto& table = paramCompatTable();\n")
+	sb.WriteString("    auto proto_it = table.find(protocol);\n")
+	sb.WriteString("    if (proto_it == table.end()) return false;\n")
 	sb.WriteString("    auto param_it = proto_it->second.find(param);\n")
 	sb.WriteString("    return param_it != proto_it->second.end() && param_it->second.supported;\n")
 	sb.WriteString("}\n\n")
@@ -799,8 +839,9 @@ func generateCppHeader(compatMap map[string]*ProtocolCompat, outputPath string)
 	sb.WriteString("// Check if a parameter is hardcoded by Mihomo for this protocol\n")
 	sb.WriteString("// Hardcoded parameters should NOT be overridden by global settings\n")
 	sb.WriteString("inline bool isParamHardcoded(const std::string& protocol, const std::string& param) {\n")
-	sb.WriteString("    auto proto_it = PARAM_COMPAT.find(protocol);\n")
-	sb.WriteString("    if (proto_it == PARAM_COMPAT.end()) return false;\n")
+	sb.WriteString("    const auto& table = paramCompatTable();\n")
+	sb.WriteString("    auto proto_it = table.find(protocol);\n")
+	sb.WriteString("    if (proto_it == table.end()) return false;\n")
 	sb.WriteString("    auto param_it = proto_it->second.find(param);\n")
 	sb.WriteString("    return param_it != proto_it->second.end() && param_it->second.hardcoded;\n")
 	sb.WriteString("}\n\n")
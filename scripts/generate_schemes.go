@@ -3,6 +3,7 @@ package main
 import (
 	"fmt"
 	"go/ast"
+	"go/format"
 	"go/parser"
 	"go/token"
 	"os"
@@ -160,18 +161,29 @@ func main() {
 		return false
 	})
 
+	// 4. Write the same list for the bridge, which routes links by scheme
+	writeConvertSchemes(moduleRoot, schemes)
+
+	// 5. Add the schemes the bridge parses itself
+	for _, scheme := range bridgeSchemes(moduleRoot) {
+		if !seen[scheme] {
+			schemes = append(schemes, scheme)
+			seen[scheme] = true
+		}
+	}
+
 	if len(schemes) == 0 {
 		fmt.Println("Warning: No schemes found!")
 	} else {
 		fmt.Printf("Total schemes found: %d\n", len(schemes))
 	}
 
-	// 4. Generate Output
+	// 6. Generate Output
 	data := struct {
 		Version string
 		Schemes []string
 	}{
-		Version: "latest",
+		Version: mihomoVersion(moduleRoot),
 		Schemes: schemes,
 	}
 
@@ -186,3 +198,98 @@ func main() {
 	tmpl.Execute(f, data)
 	fmt.Printf("Successfully generated %s\n", outputPath)
 }
+
+// convertSchemesTemplate is the bridge's copy of the schemes handled by
+// convert.ConvertsV2Ray
+const convertSchemesTemplate = `// Code generated by scripts/generate_schemes.go; DO NOT EDIT.
+// Based on mihomo version: {{.Version}}
+
+package main
+
+// convertSchemes lists the schemes handled by convert.ConvertsV2Ray
+var convertSchemes = map[string]bool{
+{{- range .Schemes}}
+	"{{.}}": true,
+{{- end}}
+}
+`
+
+// writeConvertSchemes writes convertschemes.go into the bridge module
+func writeConvertSchemes(moduleRoot string, schemes []string) {
+	outputPath := filepath.Join(moduleRoot, "convertschemes.go")
+	data := struct {
+		Version string
+		Schemes []string
+	}{
+		Version: mihomoVersion(moduleRoot),
+		Schemes: schemes,
+	}
+
+	var sb strings.Builder
+	tmpl := template.Must(template.New("schemes").Parse(convertSchemesTemplate))
+	if err := tmpl.Execute(&sb, data); err != nil {
+		fmt.Printf("Error generating %s: %v\n", outputPath, err)
+		os.Exit(1)
+	}
+	source, err := format.Source([]byte(sb.String()))
+	if err != nil {
+		fmt.Printf("Error formatting %s: %v\n", outputPath, err)
+		os.Exit(1)
+	}
+	if err := os.WriteFile(outputPath, source, 0o644); err != nil {
+		fmt.Printf("Error writing %s: %v\n", outputPath, err)
+		os.Exit(1)
+	}
+	fmt.Printf("Successfully generated %s\n", outputPath)
+}
+
+// mihomoVersion asks go list for the mihomo version the bridge requires,
+// the same version the BridgeInfo export reports at runtime
+func mihomoVersion(moduleRoot string) string {
+	cmd := exec.Command("go", "list", "-m", "-f", "{{.Version}}", "github.com/metacubex/mihomo")
+	cmd.Dir = moduleRoot
+	output, err := cmd.Output()
+	if version := strings.TrimSpace(string(output)); err == nil && version != "" {
+		return version
+	}
+	return "unknown"
+}
+
+// bridgeSchemes reads the keys of bridgeLinkParsers, the schemes the
+// bridge parses without ConvertsV2Ray
+func bridgeSchemes(moduleRoot string) []string {
+	targetFile := filepath.Join(moduleRoot, "bridgelinks.go")
+	fmt.Printf("Analyzing %s...\n", targetFile)
+
+	fset := token.NewFileSet()
+	node, err := parser.ParseFile(fset, targetFile, nil, 0)
+	if err != nil {
+		fmt.Printf("Error parsing file: %v\n", err)
+		os.Exit(1)
+	}
+
+	schemes := make([]string, 0)
+	ast.Inspect(node, func(n ast.Node) bool {
+		spec, ok := n.(*ast.ValueSpec)
+		if !ok || len(spec.Names) != 1 || spec.Names[0].Name != "bridgeLinkParsers" || len(spec.Values) != 1 {
+			return true
+		}
+		lit, ok := spec.Values[0].(*ast.CompositeLit)
+		if !ok {
+			return false
+		}
+		for _, elt := range lit.Elts {
+			kv, ok := elt.(*ast.KeyValueExpr)
+			if !ok {
+				continue
+			}
+			if key, ok := kv.Key.(*ast.BasicLit); ok && key.Kind == token.STRING {
+				schemes = append(schemes, strings.Trim(key.Value, "\""))
+			}
+		}
+		return false
+	})
+
+	fmt.Printf("Found %d bridge schemes\n", len(schemes))
+	return schemes
+}
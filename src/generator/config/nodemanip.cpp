@@ -54,6 +54,206 @@ static bool isBrowserUA(const std::string &ua) {
   return false;
 }
 
+#ifdef USE_MIHOMO_PARSER
+// Convert mihomo::ProxyNode to subconverter's Proxy structure
+static Proxy mihomoNodeToProxy(const mihomo::ProxyNode &mnode) {
+  Proxy node;
+  node.Remark = mnode.name;
+  node.Type = ProxyType::Unknown; // Will be set based on type string
+
+  // Map mihomo proxy type to subconverter ProxyType
+  node.Type = getProxyTypeFromString(mnode.type);
+
+  // Copy all raw params for generic pass-through
+  for (const auto &[key, value] : mnode.params) {
+    node.RawParams[key] = value;
+  }
+
+  // CRITICAL: Preserve original type string from mihomo
+  // This ensures unknown protocols (e.g., linksb) output correctly as
+  // "type: linksb" instead of "type: Unknown" which would break Clash
+  node.RawParams["type"] = mnode.type;
+  node.RawJSON = mnode.raw;
+
+  // Add more types as needed
+
+  node.Hostname = mnode.server;
+  node.Port = mnode.port;
+
+  // Store all additional params for later serialization
+  // (mihomo guarantees these are correct for the protocol)
+  for (const auto &[key, value] : mnode.params) {
+    // These will be used when generating the final config
+    if (key == "password")
+      node.Password = value;
+    else if (key == "cipher" || key == "method")
+      node.EncryptMethod = value;
+    else if (key == "uuid")
+      node.UserId = value;
+    else if (key == "alterId")
+      node.AlterId = std::stoi(value);
+    else if (key == "udp")
+      node.UDP = (value == "true");
+    else if (key == "tls")
+      node.TLSStr = value;
+    else if (key == "sni" || key == "servername")
+      node.ServerName = value;
+    else if (key == "network")
+      node.TransferProtocol = value;
+    // Store everything else in a raw format for mihomo-compatible
+    // output
+  }
+
+  return node;
+}
+
+// Detect the format of downloaded or local content, parse it under the
+// request's conversion options and log what had to be left out
+static mihomo::AnyParseResult
+parseAnyContent(const std::string &content,
+                const mihomo::ConvertOptions *options) {
+  auto parsed = options ? mihomo::parseAny(content, *options)
+                        : mihomo::parseAny(content);
+  writeLog(LOG_TYPE_INFO,
+           "Detected content format: " + parsed.format + " (confidence " +
+               std::to_string(static_cast<int>(parsed.confidence * 100 + 0.5)) +
+               "%).");
+  for (const auto &warning : parsed.warnings)
+    writeLog(LOG_TYPE_WARN, warning);
+  return parsed;
+}
+
+// Node share links (not http(s) subscription URLs) the bridge can parse
+static bool isMihomoNodeLink(const std::string &link) {
+  if (isLink(link))
+    return false;
+  for (const auto &scheme : mihomo::SUPPORTED_SCHEMES) {
+    if (startsWith(link, scheme + "://"))
+      return true;
+  }
+  return false;
+}
+
+// Parse pipe separated node links in one bridge session under the
+// request's conversion options, so names are unique and max_nodes counts
+// across all of them. The links are fed as one newline separated input,
+// so the bridge is called once to parse them all. Returns false if any
+// part is not a node link or the session fails, leaving the links to the
+// per-link path.
+static bool addNodeLinks(const string_array &links,
+                         std::vector<Proxy> &allNodes, int groupID,
+                         parse_settings &parse_set,
+                         const std::string &custom_group) {
+  std::string input;
+  std::vector<size_t> positions; // 1-based position of each fed line
+  for (size_t i = 0; i < links.size(); i++) {
+    if (links[i].empty())
+      continue;
+    if (!isMihomoNodeLink(links[i]))
+      return false;
+    if (!input.empty())
+      input += '\n';
+    input += links[i];
+    positions.push_back(i + 1);
+  }
+
+  std::vector<Proxy> nodes;
+  try {
+    mihomo::ConversionSession session(parse_set.convert_options
+                                          ? *parse_set.convert_options
+                                          : mihomo::ConvertOptions{});
+    if (!input.empty())
+      session.feed("links", groupID, input);
+    auto result = session.finalize();
+    for (const auto &source : result.sources) {
+      for (const auto &error : source.errors) {
+        std::string link = std::to_string(error.line);
+        if (error.line > 0 &&
+            static_cast<size_t>(error.line) <= positions.size())
+          link = std::to_string(positions[error.line - 1]);
+        writeLog(LOG_TYPE_WARN, "Mihomo parser rejected link " + link + " (" +
+                                    error.scheme + "): " + error.code +
+                                    (error.message.empty()
+                                         ? ""
+                                         : ", " + error.message));
+      }
+    }
+    for (const auto &warning : result.warnings)
+      writeLog(LOG_TYPE_WARN, warning);
+    for (const auto &proxy : result.proxies) {
+      nodes.push_back(mihomoNodeToProxy(proxy.node));
+    }
+  } catch (const std::exception &e) {
+    writeLog(LOG_TYPE_WARN, "Mihomo session error: " + std::string(e.what()) +
+                                ", parsing links one by one.");
+    return false;
+  }
+
+  writeLog(LOG_TYPE_INFO, "Mihomo parser parsed " +
+                              std::to_string(nodes.size()) + " nodes from " +
+                              std::to_string(links.size()) + " links.");
+  getSubInfoFromNodes(nodes, *parse_set.stream_rules, *parse_set.time_rules,
+                      *parse_set.sub_info);
+  filterNodes(nodes, *parse_set.exclude_remarks, *parse_set.include_remarks,
+              groupID);
+  for (Proxy &x : nodes) {
+    x.GroupId = groupID;
+    if (!custom_group.empty())
+      x.Group = custom_group;
+  }
+  copyNodes(nodes, allNodes);
+  return true;
+}
+
+int addProxyLines(const std::string &content, std::vector<Proxy> &allNodes,
+                  int groupID, parse_settings &parse_set) {
+  mihomo::ConvertOptions options = parse_set.convert_options
+                                       ? *parse_set.convert_options
+                                       : mihomo::ConvertOptions{};
+  options.preprocess = "none"; // Proxy lines are plain text
+
+  std::vector<Proxy> nodes;
+  try {
+    auto converted = mihomo::convertSubscription(content, options);
+    for (const auto &warning : converted.warnings)
+      writeLog(LOG_TYPE_WARN, warning);
+    for (const auto &line : converted.diagnostics.lines) {
+      if (!line.unsupported.empty())
+        writeLog(LOG_TYPE_INFO, "Ignored " + line.format +
+                                    " options of line " +
+                                    std::to_string(line.line) + ": " +
+                                    join(line.unsupported, ", "));
+      if (!line.scheme.empty() && line.status != "parsed")
+        writeLog(LOG_TYPE_WARN, "Mihomo parser rejected line " +
+                                    std::to_string(line.line) + " (" +
+                                    line.scheme + "): " + line.code +
+                                    (line.message.empty()
+                                         ? ""
+                                         : ", " + line.message));
+    }
+    for (const auto &mnode : converted.diagnostics.nodes)
+      nodes.push_back(mihomoNodeToProxy(mnode));
+  } catch (const std::exception &e) {
+    writeLog(LOG_TYPE_ERROR, "Mihomo parser error: " + std::string(e.what()));
+    return -1;
+  }
+  if (nodes.empty()) {
+    writeLog(LOG_TYPE_ERROR, "No valid proxy lines were found!");
+    return -1;
+  }
+
+  writeLog(LOG_TYPE_INFO, "Mihomo parser parsed " +
+                              std::to_string(nodes.size()) +
+                              " nodes from proxy lines.");
+  filterNodes(nodes, *parse_set.exclude_remarks, *parse_set.include_remarks,
+              groupID);
+  for (Proxy &x : nodes)
+    x.GroupId = groupID;
+  copyNodes(nodes, allNodes);
+  return 0;
+}
+#endif
+
 int addNodes(std::string link, std::vector<Proxy> &allNodes, int groupID,
              parse_settings &parse_set) {
   std::string &proxy = *parse_set.proxy, &subInfo = *parse_set.sub_info;
@@ -155,6 +355,10 @@ int addNodes(std::string link, std::vector<Proxy> &allNodes, int groupID,
   // Handle pipe separated links recursively
   if (link.find('|') != std::string::npos && (isLink(link) || isMihomoScheme)) {
     std::vector<std::string> links = split(link, "|");
+#ifdef USE_MIHOMO_PARSER
+    if (addNodeLinks(links, allNodes, groupID, parse_set, custom_group))
+      return 0;
+#endif
     for (const auto &l : links) {
       if (l.empty())
         continue;
@@ -199,8 +403,13 @@ int addNodes(std::string link, std::vector<Proxy> &allNodes, int groupID,
     bool isSubscription = false; // 订阅链接标志
     bool isNodeLink = false;     // 节点链接标志
 
+    // 规则 0: surge:///install-config 包装的是 Surge 配置的地址，
+    // 下载并解包后由 mihomo 解析其中的代理行
+    if (startsWith(link, "surge:///install-config")) {
+      writeLog(LOG_TYPE_INFO, "Surge config link detected: " + link);
+    }
     // 规则 1: HTTP(S) 开头的链接
-    if (startsWith(link, "http://") || startsWith(link, "https://")) {
+    else if (startsWith(link, "http://") || startsWith(link, "https://")) {
       size_t protocolEnd = link.find("://") + 3;
       size_t pathStart = link.find("/", protocolEnd);
       size_t queryStart = link.find("?", protocolEnd);
@@ -310,57 +519,56 @@ int addNodes(std::string link, std::vector<Proxy> &allNodes, int groupID,
 #ifdef USE_MIHOMO_PARSER
       // Use mihomo parser (100% compatible with mihomo)
       try {
-        auto mihomo_nodes = mihomo::parseSubscription(strSub);
-
-        // Convert mihomo::ProxyNode to subconverter's Proxy structure
-        for (const auto &mnode : mihomo_nodes) {
-          Proxy node;
-          node.Remark = mnode.name;
-          node.Type = ProxyType::Unknown; // Will be set based on type string
-
-          // Map mihomo proxy type to subconverter ProxyType
-          node.Type = getProxyTypeFromString(mnode.type);
-
-          // Copy all raw params for generic pass-through
-          for (const auto &[key, value] : mnode.params) {
-            node.RawParams[key] = value;
-          }
-
-          // CRITICAL: Preserve original type string from mihomo
-          // This ensures unknown protocols (e.g., linksb) output correctly as
-          // "type: linksb" instead of "type: Unknown" which would break Clash
-          node.RawParams["type"] = mnode.type;
-
-          // Add more types as needed
-
-          node.Hostname = mnode.server;
-          node.Port = mnode.port;
-
-          // Store all additional params for later serialization
-          // (mihomo guarantees these are correct for the protocol)
-          for (const auto &[key, value] : mnode.params) {
-            // These will be used when generating the final config
-            if (key == "password")
-              node.Password = value;
-            else if (key == "cipher" || key == "method")
-              node.EncryptMethod = value;
-            else if (key == "uuid")
-              node.UserId = value;
-            else if (key == "alterId")
-              node.AlterId = std::stoi(value);
-            else if (key == "udp")
-              node.UDP = (value == "true");
-            else if (key == "tls")
-              node.TLSStr = value;
-            else if (key == "sni" || key == "servername")
-              node.ServerName = value;
-            else if (key == "network")
-              node.TransferProtocol = value;
-            // Store everything else in a raw format for mihomo-compatible
-            // output
+        auto parsed = parseAnyContent(strSub, parse_set.convert_options);
+        auto &diagnostics = parsed.diagnostics;
+        auto &mihomo_nodes = diagnostics.nodes;
+        if (!diagnostics.envelope.empty())
+          writeLog(LOG_TYPE_INFO, "Subscription envelope decoded as: " +
+                                      join(diagnostics.envelope, " > "));
+
+        // Report links that mihomo could not use instead of dropping them
+        // silently; plain text lines without a scheme are not counted
+        int link_count = 0, rejected_count = 0;
+        for (const auto &line : diagnostics.lines) {
+          for (const auto &rewrite : line.rewrites)
+            writeLog(LOG_TYPE_INFO, "Normalized " + rewrite.component +
+                                        " of line " +
+                                        std::to_string(line.line) + ": '" +
+                                        rewrite.before + "' -> '" +
+                                        rewrite.after + "'");
+          if (!line.name_fixes.empty())
+            writeLog(LOG_TYPE_INFO, "Cleaned name of line " +
+                                        std::to_string(line.line) + " (" +
+                                        join(line.name_fixes, ", ") +
+                                        "): '" + line.name + "'");
+          if (!line.unsupported.empty())
+            writeLog(LOG_TYPE_INFO, "Ignored " + line.format +
+                                        " options of line " +
+                                        std::to_string(line.line) + ": " +
+                                        join(line.unsupported, ", "));
+          if (line.scheme.empty())
+            continue;
+          link_count++;
+          if (line.status != "parsed") {
+            rejected_count++;
+            writeLog(LOG_TYPE_WARN, "Mihomo parser rejected line " +
+                                        std::to_string(line.line) + " (" +
+                                        line.scheme + "): " + line.code +
+                                        (line.message.empty()
+                                             ? ""
+                                             : ", " + line.message));
           }
+        }
+        if (rejected_count > 0)
+          writeLog(LOG_TYPE_WARN, std::to_string(rejected_count) + " of " +
+                                      std::to_string(link_count) +
+                                      " links were rejected by mihomo parser.");
+        if (mihomo_nodes.empty())
+          throw std::runtime_error(
+              "convert v2ray subscribe error: format invalid");
 
-          nodes.push_back(node);
+        for (const auto &mnode : mihomo_nodes) {
+          nodes.push_back(mihomoNodeToProxy(mnode));
         }
 
         if (nodes.empty()) {
@@ -426,7 +634,25 @@ int addNodes(std::string link, std::vector<Proxy> &allNodes, int groupID,
     if (!authorized)
       return -1;
     writeLog(LOG_TYPE_INFO, "Parsing configuration file data...");
-    if (explodeConf(link, nodes) == 0) {
+#ifdef USE_MIHOMO_PARSER
+    // Clash/mihomo provider files load exactly as the core would load
+    // them; other formats go through the matching importer
+    try {
+      auto parsed = parseAnyContent(fileGet(link), parse_set.convert_options);
+      for (const auto &mnode : parsed.diagnostics.nodes) {
+        nodes.push_back(mihomoNodeToProxy(mnode));
+      }
+      writeLog(LOG_TYPE_INFO, "Mihomo parser loaded " +
+                                  std::to_string(nodes.size()) + " nodes (" +
+                                  parsed.format + ").");
+    } catch (const std::exception &e) {
+      writeLog(LOG_TYPE_WARN, "Mihomo parser error: " +
+                                  std::string(e.what()) +
+                                  ", falling back to legacy parser.");
+      nodes.clear();
+    }
+#endif
+    if (nodes.empty() && explodeConf(link, nodes) == 0) {
       writeLog(LOG_TYPE_ERROR, "Invalid configuration file!");
       return -1;
     }
@@ -10,6 +10,9 @@
 #endif // NO_JS_RUNTIME
 
 #include "config/regmatch.h"
+#ifdef USE_MIHOMO_PARSER
+#include "parser/mihomo_bridge.h"
+#endif // USE_MIHOMO_PARSER
 #include "parser/config/proxy.h"
 #include "utils/map_extra.h"
 #include "utils/string.h"
@@ -28,9 +31,17 @@ struct parse_settings
     qjs::Runtime *js_runtime = nullptr;
     qjs::Context *js_context = nullptr;
 #endif // NO_JS_RUNTIME
+#ifdef USE_MIHOMO_PARSER
+    mihomo::ConvertOptions *convert_options = nullptr;
+#endif // USE_MIHOMO_PARSER
 };
 
 int addNodes(std::string link, std::vector<Proxy> &allNodes, int groupID, parse_settings &parse_set);
+#ifdef USE_MIHOMO_PARSER
+// Parse Surge, Loon or Quantumult X proxy lines, e.g. the [Proxy] section
+// of a Surge config, through the bridge
+int addProxyLines(const std::string &content, std::vector<Proxy> &allNodes, int groupID, parse_settings &parse_set);
+#endif // USE_MIHOMO_PARSER
 void filterNodes(std::vector<Proxy> &nodes, string_array &exclude_remarks, string_array &include_remarks, int groupID);
 bool applyMatcher(const std::string &rule, std::string &real_rule, const Proxy &node);
 void preprocessNodes(std::vector<Proxy> &nodes, extra_settings &ext);
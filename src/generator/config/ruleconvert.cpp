@@ -1,6 +1,7 @@
 #include <string>
 
 #include "handler/settings.h"
+#include "parser/mihomo_bridge.h"
 #include "utils/logger.h"
 #include "utils/network.h"
 #include "utils/regexp.h"
@@ -124,6 +125,45 @@ static std::string transformRuleToCommon(string_view_array &temp, const std::str
     return strLine;
 }
 
+#ifdef USE_MIHOMO_PARSER
+/// drop generated rules that mihomo's rule parser rejects, so one bad line does not break the whole config
+static void dropRejectedRules(string_array &rules)
+{
+    if(rules.empty())
+        return;
+    mihomo::RuleValidation validation;
+    try
+    {
+        validation = mihomo::validateRuleLines(rules);
+    }
+    catch(std::exception &e)
+    {
+        writeLog(0, std::string("Failed to validate rules with mihomo parser: ") + e.what(), LOG_LEVEL_WARNING);
+        return;
+    }
+    if(!validation.invalid)
+        return;
+
+    std::vector<bool> rejected(rules.size(), false);
+    for(const mihomo::RuleCheck &check : validation.rules)
+    {
+        if(check.error.empty() || check.index < 0 || static_cast<size_t>(check.index) >= rules.size())
+            continue;
+        rejected[check.index] = true;
+        writeLog(0, "Dropped rule '" + check.rule + "' rejected by mihomo: " + check.error, LOG_LEVEL_WARNING);
+    }
+
+    string_array kept;
+    kept.reserve(rules.size());
+    for(size_t i = 0; i < rules.size(); i++)
+    {
+        if(!rejected[i])
+            kept.emplace_back(std::move(rules[i]));
+    }
+    rules = std::move(kept);
+}
+#endif
+
 void rulesetToClash(YAML::Node &base_rule, std::vector<RulesetContent> &ruleset_content_array, bool overwrite_original_rules, bool new_field_name)
 {
     string_array allRules;
@@ -184,6 +224,9 @@ void rulesetToClash(YAML::Node &base_rule, std::vector<RulesetContent> &ruleset_
         }
     }
 
+#ifdef USE_MIHOMO_PARSER
+    dropRejectedRules(allRules);
+#endif
     for(std::string &x : allRules)
     {
         rules.push_back(x);
@@ -207,6 +250,7 @@ std::string rulesetToClashStr(YAML::Node &base_rule, std::vector<RulesetContent>
     }
     base_rule.remove(field_name);
 
+    string_array generated_rules;
     string_view_array temp(4);
     for(RulesetContent &x : ruleset_content_array)
     {
@@ -225,7 +269,7 @@ std::string rulesetToClashStr(YAML::Node &base_rule, std::vector<RulesetContent>
             if(startsWith(strLine, "FINAL"))
                 strLine.replace(0, 5, "MATCH");
             strLine = transformRuleToCommon(temp, strLine, rule_group);
-            output_content += "  - " + strLine + "\n";
+            generated_rules.emplace_back(strLine);
             total_rules++;
             continue;
         }
@@ -254,18 +298,18 @@ std::string rulesetToClashStr(YAML::Node &base_rule, std::vector<RulesetContent>
             //AND & OR & NOT
             if(startsWith(strLine, "AND") || startsWith(strLine, "OR") || startsWith(strLine, "NOT"))
             {
-                output_content += "  - " + strLine + "," + rule_group + "\n";
+                generated_rules.emplace_back(strLine + "," + rule_group);
             }
             //SUB-RULE & RULE-SET
             else if (startsWith(strLine, "SUB-RULE") || startsWith(strLine, "RULE-SET"))
             {
-                output_content += "  - " + strLine + "\n";
+                generated_rules.emplace_back(strLine);
             }
             else
             //OTHER
             {
                 strLine = transformRuleToCommon(temp, strLine, rule_group);
-                output_content += "  - " + strLine + "\n";
+                generated_rules.emplace_back(strLine);
             }
 
             //strLine = transformRuleToCommon(temp, strLine, rule_group);
@@ -273,6 +317,12 @@ std::string rulesetToClashStr(YAML::Node &base_rule, std::vector<RulesetContent>
             total_rules++;
         }
     }
+
+#ifdef USE_MIHOMO_PARSER
+    dropRejectedRules(generated_rules);
+#endif
+    for(std::string &x : generated_rules)
+        output_content += "  - " + x + "\n";
     return output_content;
 }
 
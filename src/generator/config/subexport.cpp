@@ -10,6 +10,7 @@
 #include "handler/settings.h"
 #include "nodemanip.h"
 #include "parser/config/proxy.h"
+#include "parser/mihomo_bridge.h"
 #include "parser/param_compat.h"
 #include "ruleconvert.h"
 #include "script/script_quickjs.h"
@@ -3160,6 +3161,11 @@ void proxyToSingBox(std::vector<Proxy> &nodes, rapidjson::Document &json,
   std::vector<Proxy> nodelist;
   string_array remarks_list;
   std::string search = " Mbps";
+#ifdef USE_MIHOMO_PARSER
+  // Nodes exported through the bridge and the outbounds they go to
+  std::vector<mihomo::ProxyNode> bridge_nodes;
+  std::vector<rapidjson::SizeType> bridge_slots;
+#endif
 
   if (!ext.nodelist) {
     auto direct = buildObject(allocator, "type", "direct", "tag", "DIRECT");
@@ -3185,6 +3191,67 @@ void proxyToSingBox(std::vector<Proxy> &nodes, rapidjson::Document &json,
     tfo.define(x.TCPFastOpen);
     scv.define(x.AllowInsecure);
 
+#ifdef USE_MIHOMO_PARSER
+    // Nodes from the mihomo parser keep their full proxy map in RawJSON.
+    // Export them from that map so fields the Proxy struct cannot hold
+    // (anytls, hysteria2 obfs, reality, smux...) survive. They are
+    // exported in one bridge call after this loop; until then a null
+    // outbound holds their place.
+    if (!x.RawParams.empty()) {
+      mihomo::ProxyNode mnode;
+      mnode.name = x.Remark;
+      mnode.server = x.Hostname;
+      mnode.port = x.Port;
+      mnode.type = x.RawParams.count("type") ? x.RawParams["type"] : "";
+
+      rapidjson::Document raw;
+      if (!x.RawJSON.empty() && !raw.Parse(x.RawJSON.c_str()).HasParseError() &&
+          raw.IsObject()) {
+        // Keep the typed values, a password "true" must stay a string
+        auto &raw_allocator = raw.GetAllocator();
+        raw | AddMemberOrReplace(
+                  "name", rapidjson::Value(x.Remark.c_str(), raw_allocator),
+                  raw_allocator);
+        auto applyGlobal = [&](const char *key, const tribool &value) {
+          if (!value.is_undef() && mihomo::isParamSupported(mnode.type, key) &&
+              !mihomo::isParamHardcoded(mnode.type, key))
+            raw | AddMemberOrReplace(key, buildBooleanValue(value),
+                                     raw_allocator);
+        };
+        applyGlobal("udp", udp);
+        applyGlobal("skip-cert-verify", scv);
+        applyGlobal("tfo", tfo);
+        applyGlobal("xudp", xudp);
+        mnode.raw = raw | SerializeObject();
+      } else {
+        for (const auto &[key, value] : x.RawParams) {
+          if (key == "name" || key == "type" || key == "server" ||
+              key == "port" || startsWith(key, "_"))
+            continue;
+          mnode.params[key] = value;
+        }
+
+        // Same global parameter rules as the Clash pass-through
+        auto applyGlobal = [&](const std::string &key, const tribool &value) {
+          if (!value.is_undef() && mihomo::isParamSupported(mnode.type, key) &&
+              !mihomo::isParamHardcoded(mnode.type, key))
+            mnode.params[key] = value.get() ? "true" : "false";
+        };
+        applyGlobal("udp", udp);
+        applyGlobal("skip-cert-verify", scv);
+        applyGlobal("tfo", tfo);
+        applyGlobal("xudp", xudp);
+      }
+
+      bridge_nodes.push_back(std::move(mnode));
+      bridge_slots.push_back(outbounds.Size());
+      outbounds.PushBack(rapidjson::Value(rapidjson::kNullType), allocator);
+      nodelist.push_back(x);
+      remarks_list.emplace_back(x.Remark);
+      continue;
+    }
+#endif
+
     rapidjson::Value proxy(rapidjson::kObjectType);
     switch (x.Type) {
     case ProxyType::Shadowsocks: {
@@ -3599,6 +3666,59 @@ void proxyToSingBox(std::vector<Proxy> &nodes, rapidjson::Document &json,
     outbounds.PushBack(proxy, allocator);
   }
 
+#ifdef USE_MIHOMO_PARSER
+  if (!bridge_nodes.empty()) {
+    std::vector<mihomo::SingBoxExport> exported;
+    try {
+      exported = mihomo::exportSingBoxNodes(bridge_nodes);
+    } catch (const std::exception &e) {
+      writeLog(LOG_TYPE_WARN,
+               "Cannot export nodes to sing-box: " + std::string(e.what()));
+    }
+
+    // Put the exported outbounds in place of their nulls and drop the
+    // nodes that could not be exported
+    rapidjson::Value merged(rapidjson::kArrayType);
+    size_t next = 0;
+    for (rapidjson::SizeType i = 0; i < outbounds.Size(); i++) {
+      if (next == bridge_slots.size() || bridge_slots[next] != i) {
+        merged.PushBack(outbounds[i], allocator);
+        continue;
+      }
+      const std::string &remark = bridge_nodes[next].name;
+      const mihomo::SingBoxExport *result =
+          next < exported.size() ? &exported[next] : nullptr;
+      next++;
+      if (!result || !result->error.empty()) {
+        writeLog(LOG_TYPE_WARN, "Cannot export node '" + remark +
+                                    "' to sing-box: " +
+                                    (result ? result->error : "no result"));
+        nodelist.erase(std::remove_if(nodelist.begin(), nodelist.end(),
+                                      [&](const Proxy &node) {
+                                        return node.Remark == remark;
+                                      }),
+                       nodelist.end());
+        remarks_list.erase(std::remove(remarks_list.begin(),
+                                       remarks_list.end(), remark),
+                           remarks_list.end());
+        continue;
+      }
+      if (!result->unsupported.empty())
+        writeLog(LOG_TYPE_INFO, "Node '" + remark +
+                                    "' has fields sing-box cannot use: " +
+                                    join(result->unsupported, ", "));
+
+      for (const auto &outbound : result->outbounds) {
+        rapidjson::Document parsed;
+        parsed.Parse(outbound.c_str());
+        rapidjson::Value value(parsed, allocator);
+        merged.PushBack(value, allocator);
+      }
+    }
+    outbounds.Swap(merged);
+  }
+#endif
+
   if (ext.nodelist) {
     json | AddMemberOrReplace("outbounds", outbounds, allocator);
     return;
@@ -16,6 +16,7 @@
 #include "generator/template/templates.h"
 #include "interfaces.h"
 #include "multithread.h"
+#include "parser/mihomo_bridge.h"
 #include "script/cron.h"
 #include "script/script_quickjs.h"
 #include "server/webserver.h"
@@ -160,17 +161,31 @@ std::string getRuleset(RESPONSE_CALLBACK_ARGS) {
   /// type: 1 for Surge, 2 for Quantumult X, 3 for Clash domain rule-provider, 4
   /// for Clash ipcidr rule-provider, 5 for Surge DOMAIN-SET, 6 for Clash
   /// classical ruleset
+  /// format: "mrs" to serve type 3/4 as a binary MRS rule-set
   std::string url = urlSafeBase64Decode(getUrlArg(argument, "url")),
               type = getUrlArg(argument, "type"),
-              group = urlSafeBase64Decode(getUrlArg(argument, "group"));
+              group = urlSafeBase64Decode(getUrlArg(argument, "group")),
+              format = getUrlArg(argument, "format");
   std::string output_content, dummy;
   int type_int = to_int(type, 0);
+  bool mrs = format == "mrs";
 
   if (url.empty() || type.empty() || (type_int == 2 && group.empty()) ||
-      (type_int < 1 || type_int > 6)) {
+      (type_int < 1 || type_int > 6) || (!format.empty() && !mrs)) {
     *status_code = 400;
     return "Invalid request!";
   }
+  if (mrs && type_int != 3 && type_int != 4) {
+    *status_code = 400;
+    return "MRS is only available for domain (type=3) and ipcidr (type=4) "
+           "rule-sets!";
+  }
+#ifndef USE_MIHOMO_PARSER
+  if (mrs) {
+    *status_code = 400;
+    return "MRS output requires the mihomo parser!";
+  }
+#endif
 
   std::string proxy = parseProxy(global.proxyRuleset);
   string_array vArray = split(url, "|");
@@ -317,6 +332,27 @@ std::string getRuleset(RESPONSE_CALLBACK_ARGS) {
       break;
     }
   }
+
+#ifdef USE_MIHOMO_PARSER
+  if (mrs) {
+    try {
+      auto compiled = mihomo::compileRuleSetMrs(
+          output_content, type_int == 3 ? "domain" : "ipcidr");
+      if (!compiled.invalid.empty())
+        writeLog(0,
+                 "Dropped " + std::to_string(compiled.invalid.size()) +
+                     " invalid rule(s) while compiling MRS rule-set.",
+                 LOG_LEVEL_WARNING);
+      response.content_type = "application/octet-stream";
+      return compiled.data;
+    } catch (const std::exception &e) {
+      writeLog(0, std::string("Failed to compile MRS rule-set: ") + e.what(),
+               LOG_LEVEL_ERROR);
+      *status_code = 400;
+      return "Invalid request!";
+    }
+  }
+#endif
   return output_content;
 }
 
@@ -665,7 +701,8 @@ std::string subconverter(RESPONSE_CALLBACK_ARGS) {
           argAppendUserinfo = getUrlArg(argument, "append_info");
   tribool argPrependInsert = getUrlArg(argument, "prepend"),
           argGenClassicalRuleProvider = getUrlArg(argument, "classic"),
-          argTLS13 = getUrlArg(argument, "tls13");
+          argTLS13 = getUrlArg(argument, "tls13"),
+          argValidateConfig = getUrlArg(argument, "validate");
 
   std::string base_content, output_content;
   ProxyGroupConfigs lCustomProxyGroups = global.customProxyGroups;
@@ -996,6 +1033,19 @@ std::string subconverter(RESPONSE_CALLBACK_ARGS) {
   parse_set.request_header = &request.headers;
   parse_set.js_runtime = ext.js_runtime;
   parse_set.js_context = ext.js_context;
+#ifdef USE_MIHOMO_PARSER
+  // Fill in the node flags at parse time, wherever the proxy type takes
+  // them and the subscription left them out
+  mihomo::ConvertOptions convert_options;
+  if (!ext.udp.is_undef())
+    convert_options.default_params["udp"] = ext.udp.get() ? "true" : "false";
+  if (!ext.tfo.is_undef())
+    convert_options.default_params["tfo"] = ext.tfo.get() ? "true" : "false";
+  if (!ext.skip_cert_verify.is_undef())
+    convert_options.default_params["skip-cert-verify"] =
+        ext.skip_cert_verify.get() ? "true" : "false";
+  parse_set.convert_options = &convert_options;
+#endif // USE_MIHOMO_PARSER
 
   if (!global.insertUrls.empty() && argEnableInsert) {
     groupID = -1;
@@ -1309,6 +1359,31 @@ std::string subconverter(RESPONSE_CALLBACK_ARGS) {
                        argTarget == "clashr", ext);
     }
 
+    if (argValidateConfig) {
+#ifdef USE_MIHOMO_PARSER
+      auto validation = mihomo::validateConfig(output_content);
+      if (!validation.valid) {
+        std::string errors;
+        for (const auto &error : validation.errors) {
+          writeLog(0,
+                   "Generated config rejected at '" + error.path +
+                       "': " + error.message,
+                   LOG_LEVEL_ERROR);
+          errors += "\n  ";
+          if (!error.path.empty())
+            errors += error.path + " (line " + std::to_string(error.line) +
+                      "): ";
+          errors += error.message;
+        }
+        *status_code = 400;
+        return "Generated config would be rejected by mihomo:" + errors;
+      }
+#else
+      *status_code = 400;
+      return "Config validation requires the mihomo parser!";
+#endif
+    }
+
     if (argUpload)
       uploadGist(argTarget, argUploadPath, output_content, false);
     break;
@@ -1604,7 +1679,9 @@ std::string surgeConfToClash(RESPONSE_CALLBACK_ARGS) {
   ini.get_items("Proxy Group", section);
   std::string name, type, content;
   string_array links;
+#ifndef USE_MIHOMO_PARSER
   links.emplace_back(url);
+#endif
   YAML::Node singlegroup;
   for (auto &x : section) {
     singlegroup.reset();
@@ -1648,6 +1725,38 @@ std::string surgeConfToClash(RESPONSE_CALLBACK_ARGS) {
   parse_set.request_header = &request.headers;
   parse_set.sub_info = &subInfo;
   parse_set.authorized = !global.APIMode;
+#ifdef USE_MIHOMO_PARSER
+  // The [Proxy] section goes through the bridge's proxy line parser in file
+  // order; built-in policies become groups below
+  std::string proxy_lines;
+  bool in_proxy_section = false;
+  for (const std::string &x : split(base_content, "\n")) {
+    std::string line = trimWhitespace(x, true, true);
+    if (line.empty() || line[0] == '#' || line[0] == ';')
+      continue;
+    if (line.front() == '[' && line.back() == ']') {
+      in_proxy_section = line == "[Proxy]";
+      continue;
+    }
+    if (!in_proxy_section || line.find('=') == std::string::npos)
+      continue;
+    type = toLower(trim(line.substr(line.find('=') + 1)));
+    type = trim(type.substr(0, type.find(',')));
+    if (type == "direct" || startsWith(type, "reject"))
+      continue;
+    proxy_lines += line + "\n";
+  }
+  if (!proxy_lines.empty() &&
+      addProxyLines(proxy_lines, nodes, 0, parse_set) == -1) {
+    if (global.skipFailedLinks)
+      writeLog(0, "The [Proxy] section doesn't contain any valid node info.",
+               LOG_LEVEL_WARNING);
+    else {
+      *status_code = 400;
+      return "The [Proxy] section doesn't contain any valid node info.";
+    }
+  }
+#endif
   for (std::string &x : links) {
     // std::cerr<<"Fetching node data from url '"<<x<<"'."<<std::endl;
     writeLog(0, "Fetching node data from url '" + x + "'.", LOG_LEVEL_INFO);
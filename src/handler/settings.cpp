@@ -5,9 +5,11 @@
 #include "handler/webget.h"
 #include "interfaces.h"
 #include "multithread.h"
+#include "parser/mihomo_bridge.h"
 #include "script/cron.h"
 #include "server/webserver.h"
 #include "settings.h"
+#include "utils/defer.h"
 #include "utils/logger.h"
 #include "utils/network.h"
 
@@ -531,6 +533,8 @@ void readYAMLConf(YAML::Node &node) {
     node["advanced"]["max_allowed_rules"] >> global.maxAllowedRules;
     node["advanced"]["max_allowed_download_size"] >>
         global.maxAllowedDownloadSize;
+    node["advanced"]["max_decompressed_size"] >> global.maxDecompressedSize;
+    node["advanced"]["conversion_timeout"] >> global.conversionTimeout;
     if (node["advanced"]["enable_cache"].IsDefined()) {
       if (safe_as<bool>(node["advanced"]["enable_cache"])) {
         node["advanced"]["cache_subscription"] >> global.cacheSubscription;
@@ -710,6 +714,8 @@ void readTOMLConf(toml::value &root) {
       "max_concurrent_threads", global.maxConcurThreads, "max_allowed_rulesets",
       global.maxAllowedRulesets, "max_allowed_rules", global.maxAllowedRules,
       "max_allowed_download_size", global.maxAllowedDownloadSize,
+      "max_decompressed_size", global.maxDecompressedSize,
+      "conversion_timeout", global.conversionTimeout,
       "enable_cache", enable_cache, "cache_subscription", cache_subscription,
       "cache_config", cache_config, "cache_ruleset", cache_ruleset,
       "script_clean_context", global.scriptCleanContext, "async_fetch_ruleset",
@@ -754,6 +760,11 @@ void readTOMLConf(toml::value &root) {
 void readConf() {
   guarded_mutex guard(gMutexConfigure);
   writeLog(0, "Loading preference settings...", LOG_LEVEL_INFO);
+#ifdef USE_MIHOMO_PARSER
+  // Applied on every return path, whichever format the settings are in
+  defer(mihomo::setMaxDecompressedSize(global.maxDecompressedSize);)
+  defer(mihomo::setConversionTimeout(global.conversionTimeout);)
+#endif
 
   eraseElements(global.excludeRemarks);
   eraseElements(global.includeRemarks);
@@ -1009,6 +1020,8 @@ void readConf() {
   ini.get_number_if_exist("max_allowed_rules", global.maxAllowedRules);
   ini.get_number_if_exist("max_allowed_download_size",
                           global.maxAllowedDownloadSize);
+  ini.get_number_if_exist("max_decompressed_size", global.maxDecompressedSize);
+  ini.get_number_if_exist("conversion_timeout", global.conversionTimeout);
   if (ini.item_exist("enable_cache")) {
     if (ini.get_bool("enable_cache")) {
       ini.get_int_if_exist("cache_subscription", global.cacheSubscription);
@@ -37,6 +37,8 @@ struct Settings {
   std::string custom_group;
   int logLevel = LOG_LEVEL_VERBOSE;
   long maxAllowedDownloadSize = 1048576L;
+  long maxDecompressedSize = 16777216L;
+  long conversionTimeout = 30000L;
   string_map aliases;
 
   // global variables for template
@@ -10,6 +10,9 @@
 #include "handler/interfaces.h"
 #include "handler/settings.h"
 #include "handler/webget.h"
+#ifdef USE_MIHOMO_PARSER
+#include "parser/mihomo_bridge.h"
+#endif
 #include "script/cron.h"
 #include "server/socket.h"
 #include "server/webserver.h"
@@ -192,6 +195,49 @@ int main(int argc, char *argv[]) {
           return candidate;
         };
         std::string build_date_display = format_build_date(build_date);
+        std::string parser_section;
+#ifdef USE_MIHOMO_PARSER
+        // Name the exact parser in bug reports: the linked mihomo module
+        // and what the bridge built from it supports
+        try {
+          auto info = mihomo::bridgeInfo();
+          auto join = [](const std::vector<std::string> &items) {
+            std::string joined;
+            for (const auto &item : items)
+              joined += (joined.empty() ? "" : ", ") + item;
+            return joined;
+          };
+          std::string aliases, transports;
+          for (const auto &[alias, scheme] : info.aliases)
+            aliases += (aliases.empty() ? "" : ", ") + alias + " → " + scheme;
+          for (const auto &[type, networks] : info.transports)
+            transports += R"(<p class="description">)" + type + " transports: " +
+                          join(networks) + "</p>";
+          parser_section =
+              R"(<div class="section">
+            <div class="section-title">🧩 Parser</div>
+            <p class="description">Mihomo )" +
+              info.mihomo_version + ", bridge API v" +
+              std::to_string(info.api_version) + ", response v" +
+              std::to_string(info.response_version) + ", " + info.go_version +
+              R"(</p>
+            <p class="description">Schemes: )" +
+              join(info.schemes) + R"(</p>
+            <p class="description">Aliases: )" +
+              aliases + R"(</p>
+            <p class="description">Formats: )" +
+              join(info.formats) + "</p>\n            " + transports +
+              R"(
+        </div>)";
+        } catch (const std::exception &e) {
+          parser_section =
+              R"(<div class="section">
+            <div class="section-title">🧩 Parser</div>
+            <p class="description">Mihomo bridge unavailable: )" +
+              std::string(e.what()) + R"(</p>
+        </div>)";
+        }
+#endif
         std::string commit_link =
             build_id.empty()
                 ? ""
@@ -479,6 +525,9 @@ int main(int argc, char *argv[]) {
             <p class="description">Dedicated companion backend for the <a href="https://github.com/Aethersailor/Custom_OpenClash_Rules" target="_blank">Custom_OpenClash_Rules</a> project.</p>
         </div>
 
+        )" + parser_section +
+               R"(
+
         <div class="section">
             <div class="section-title">🚀 Lineage</div>
             <p class="description">Originated and enhanced from: <a href="https://github.com/asdlokj1qpi233/subconverter" target="_blank">subconverter</a></p>
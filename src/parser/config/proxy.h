@@ -149,6 +149,9 @@ struct Proxy {
 
   // Store raw params from mihomo parser for generic pass-through
   std::map<String, String> RawParams;
+  // The typed proxy object from mihomo parser as JSON, since RawParams
+  // holds every value as a string
+  String RawJSON;
 };
 
 #define SS_DEFAULT_GROUP "SSProvider"
@@ -1,4 +1,6 @@
 #include "mihomo_bridge.h"
+#include "param_compat.h"
+#include "utils/base64/base64.h"
 #include <nlohmann/json.hpp>
 #include <sstream>
 #include <stdexcept>
@@ -6,11 +8,286 @@
 // Go library functions (generated from libconvert.h)
 extern "C" {
 char *ConvertSubscription(char *data);
+char *DiagnoseSubscription(char *data);
+char *ConvertSubscriptionEx(char *data, char *options);
+char *ParseAny(char *data, char *options);
+char *ExportShareLinks(char *data);
+char *ValidateProxies(char *data);
+char *ParseProvider(char *data, char *options);
+char *ImportSingBox(char *data);
+char *ExportSingBox(char *data);
+char *ImportWireGuardConf(char *data);
+char *ImportXray(char *data);
+char *ConvertRuleSetToMrs(char *data, char *options);
+char *ConvertMrsToRuleSet(char *data);
+char *ValidateConfig(char *data);
+char *ValidateRules(char *data, char *options);
+long long CreateSession();
+char *CreateSessionEx(char *options);
+char *FeedSession(long long handle, char *source, int group, char *data);
+char *FinalizeSession(long long handle);
+void CloseSession(long long handle);
+void SetMaxDecompressedSize(long long size);
+long long CreateCancelHandle();
+void CancelConversion(long long handle);
+void ReleaseCancelHandle(long long handle);
+void SetConversionTimeout(long long ms);
+char *BridgeInfo();
+char *ParamCompat();
 void FreeString(char *s);
 }
 
 namespace mihomo {
 
+namespace {
+
+// Version of the response envelope this wrapper understands
+constexpr int kResponseVersion = 1;
+
+// Parse the response envelope of a Go export, free it and return its
+// data. Warnings are appended to warnings if given.
+nlohmann::json parseBridgeResult(char *result, const char *name,
+                                 std::vector<std::string> *warnings = nullptr) {
+  if (!result) {
+    throw std::runtime_error(std::string("Failed to call Go ") + name +
+                             " function");
+  }
+
+  nlohmann::json json_result;
+  try {
+    json_result = nlohmann::json::parse(result);
+  } catch (const nlohmann::json::exception &e) {
+    FreeString(result);
+    throw std::runtime_error(std::string("JSON parse error: ") + e.what());
+  }
+  FreeString(result);
+
+  if (!json_result.is_object() ||
+      json_result.value("version", 0) != kResponseVersion) {
+    throw std::runtime_error(std::string("Unsupported response from Go ") +
+                             name + " function");
+  }
+  if (warnings && json_result.contains("warnings")) {
+    for (const auto &warning : json_result["warnings"])
+      warnings->push_back(warning.get<std::string>());
+  }
+  if (!json_result.value("ok", false)) {
+    throw BridgeError(json_result.value("code", ""),
+                      json_result.value("message", ""),
+                      json_result.contains("details")
+                          ? json_result["details"].dump()
+                          : "");
+  }
+  return json_result.contains("data") ? json_result["data"]
+                                      : nlohmann::json();
+}
+
+// Call a Go export taking one string argument and parse its JSON result
+nlohmann::json callBridge(char *(*fn)(char *), const std::string &input,
+                          const char *name,
+                          std::vector<std::string> *warnings = nullptr) {
+  return parseBridgeResult(fn(const_cast<char *>(input.c_str())), name,
+                           warnings);
+}
+
+// Call a Go export taking two string arguments and parse its JSON result
+nlohmann::json callBridge(char *(*fn)(char *, char *), const std::string &first,
+                          const std::string &second, const char *name,
+                          std::vector<std::string> *warnings = nullptr) {
+  return parseBridgeResult(fn(const_cast<char *>(first.c_str()),
+                              const_cast<char *>(second.c_str())),
+                           name, warnings);
+}
+
+// C strings end at the first NUL, so content that contains NULs (UTF-16)
+// is sent base64-encoded; the bridge transcodes it after decoding
+std::string subscriptionArg(const std::string &content) {
+  if (content.find('\0') == std::string::npos)
+    return content;
+  return base64Encode(content);
+}
+
+// subscriptionArg for exports that take conversion options. Preprocess
+// "none" would convert the base64 wrapper instead of the content, so
+// content with NULs is rejected under it.
+std::string subscriptionArg(const std::string &content,
+                            const std::string &preprocess) {
+  if (preprocess == "none" && content.find('\0') != std::string::npos) {
+    throw BridgeError("invalid_input",
+                      "preprocess \"none\" cannot convert content with NUL "
+                      "bytes, use \"envelope\" or \"full\"");
+  }
+  return subscriptionArg(content);
+}
+
+// Convert one proxy object returned by the bridge into a ProxyNode
+ProxyNode parseProxyNode(const nlohmann::json &item) {
+  ProxyNode node;
+  node.raw = item.dump();
+  node.name = item.value("name", "");
+  node.type = item.value("type", "");
+  node.server = item.value("server", "");
+
+  // Port: handle both number and string
+  if (item.contains("port")) {
+    if (item["port"].is_number()) {
+      node.port = item["port"].get<int>();
+    } else if (item["port"].is_string()) {
+      try {
+        node.port = std::stoi(item["port"].get<std::string>());
+      } catch (...) {
+        node.port = 0;
+      }
+    } else {
+      node.port = 0;
+    }
+  } else {
+    node.port = 0;
+  }
+
+  // Store all other fields in params
+  for (auto it = item.begin(); it != item.end(); ++it) {
+    const std::string &key = it.key();
+    if (key != "name" && key != "type" && key != "server" && key != "port") {
+      std::string value;
+      if (it->is_string()) {
+        value = it->get<std::string>();
+      } else if (it->is_number_integer()) {
+        value = std::to_string(it->get<int>());
+      } else if (it->is_number_float()) {
+        value = std::to_string(it->get<double>());
+      } else if (it->is_boolean()) {
+        value = it->get<bool>() ? "true" : "false";
+      } else {
+        value = it->dump(); // For complex types, serialize to JSON
+      }
+      node.params[key] = value;
+    }
+  }
+
+  return node;
+}
+
+// Convert one line diagnostic returned by the bridge
+LineDiagnostic parseLineDiagnostic(const nlohmann::json &item) {
+  LineDiagnostic line;
+  line.line = item.value("line", 0);
+  line.scheme = item.value("scheme", "");
+  line.status = item.value("status", "");
+  line.code = item.value("code", "");
+  line.message = item.value("message", "");
+  line.name = item.value("name", "");
+  line.format = item.value("format", "");
+  line.unsupported = item.value("unsupported", std::vector<std::string>{});
+  line.original_name = item.value("original_name", "");
+  line.name_fixes = item.value("name_fixes", std::vector<std::string>{});
+  if (item.contains("rewrites")) {
+    for (const auto &entry : item["rewrites"]) {
+      line.rewrites.push_back({entry.value("component", ""),
+                               entry.value("before", ""),
+                               entry.value("after", "")});
+    }
+  }
+  return line;
+}
+
+// Convert one per-source report returned by the session exports
+SessionSource parseSessionSource(const nlohmann::json &item) {
+  SessionSource source;
+  source.id = item.value("id", "");
+  source.group = item.value("group", 0);
+  source.total = item.value("total", 0);
+  source.parsed = item.value("parsed", 0);
+  source.failed = item.value("failed", 0);
+  source.truncated = item.value("truncated", false);
+  for (const auto &error : item["errors"]) {
+    source.errors.push_back(parseLineDiagnostic(error));
+  }
+  return source;
+}
+
+// Fill diagnostics from the proxies, lines, summary and envelope returned
+// by the subscription exports
+void parseDiagnostics(const nlohmann::json &json_result,
+                      SubscriptionDiagnostics &diagnostics) {
+  for (const auto &item : json_result["proxies"]) {
+    diagnostics.nodes.push_back(parseProxyNode(item));
+  }
+  if (json_result.contains("lines")) {
+    for (const auto &item : json_result["lines"]) {
+      diagnostics.lines.push_back(parseLineDiagnostic(item));
+    }
+  }
+  if (json_result.contains("summary")) {
+    const auto &summary = json_result["summary"];
+    diagnostics.total = summary.value("total", 0);
+    diagnostics.parsed = summary.value("parsed", 0);
+    diagnostics.skipped = summary.value("skipped", 0);
+    diagnostics.failed = summary.value("failed", 0);
+  }
+  if (json_result.contains("envelope")) {
+    const auto &envelope = json_result["envelope"];
+    diagnostics.envelope =
+        envelope.value("path", std::vector<std::string>{});
+    diagnostics.envelope_lines = envelope.value("lines", std::vector<int>{});
+  }
+}
+
+// Serialize conversion options to the JSON the bridge exports accept
+std::string convertOptionsToJSON(const ConvertOptions &options) {
+  nlohmann::json item = {{"preprocess", options.preprocess},
+                         {"strict", options.strict},
+                         {"allowed_schemes", options.allowed_schemes},
+                         {"max_nodes", options.max_nodes},
+                         {"dedup", options.dedup},
+                         {"timeout_ms", options.timeout_ms},
+                         {"cancel_handle", options.cancel_handle}};
+  auto params = nlohmann::json::object();
+  for (const auto &[key, value] : options.default_params) {
+    auto parsed = nlohmann::json::parse(value, nullptr, false);
+    params[key] = parsed.is_discarded() ? nlohmann::json(value) : parsed;
+  }
+  item["default_params"] = std::move(params);
+  return item.dump();
+}
+
+// Serialize nodes to the JSON proxy list the bridge exports accept
+std::string nodesToJSON(const std::vector<ProxyNode> &nodes) {
+  auto proxies = nlohmann::json::array();
+  for (const auto &node : nodes) {
+    if (!node.raw.empty()) {
+      proxies.push_back(nlohmann::json::parse(node.raw));
+      continue;
+    }
+    // Nodes built by hand carry no raw JSON; rebuild it from the fields.
+    // Scalars of keys mihomo takes as strings stay strings, so a password
+    // like "true" or "1e5" is not re-typed. Objects and arrays are parsed
+    // either way, as the fallback table lists some of them as strings.
+    nlohmann::json item = {{"name", node.name},
+                           {"type", node.type},
+                           {"server", node.server},
+                           {"port", node.port}};
+    const auto &table = paramCompatTable();
+    auto params = table.find(node.type);
+    for (const auto &[key, value] : node.params) {
+      if (params != table.end()) {
+        auto param = params->second.find(key);
+        if (param != params->second.end() && param->second.type == "string" &&
+            (value.empty() || (value[0] != '{' && value[0] != '['))) {
+          item[key] = value;
+          continue;
+        }
+      }
+      auto parsed = nlohmann::json::parse(value, nullptr, false);
+      item[key] = parsed.is_discarded() ? nlohmann::json(value) : parsed;
+    }
+    proxies.push_back(std::move(item));
+  }
+  return proxies.dump();
+}
+
+} // namespace
+
 std::string ProxyNode::toYAML() const {
   std::stringstream ss;
   ss << "  - name: \"" << name << "\"\n";
@@ -28,96 +305,467 @@ std::string ProxyNode::toYAML() const {
 
 std::vector<ProxyNode> parseSubscription(const std::string &subscription) {
   std::vector<ProxyNode> nodes;
+  auto json_result =
+      callBridge(ConvertSubscription, subscriptionArg(subscription),
+                 "ConvertSubscription");
 
-  // Call Go function
-  char *result = ConvertSubscription(const_cast<char *>(subscription.c_str()));
-  if (!result) {
-    throw std::runtime_error("Failed to call Go ConvertSubscription function");
+  // Parse proxy array
+  for (const auto &item : json_result) {
+    nodes.push_back(parseProxyNode(item));
   }
 
-  // Parse JSON result
-  try {
-    auto json_result = nlohmann::json::parse(result);
+  return nodes;
+}
+
+SubscriptionDiagnostics diagnoseSubscription(const std::string &subscription) {
+  SubscriptionDiagnostics diagnostics;
+  auto json_result =
+      callBridge(DiagnoseSubscription, subscriptionArg(subscription),
+                 "DiagnoseSubscription");
+  parseDiagnostics(json_result, diagnostics);
+  return diagnostics;
+}
+
+ConvertResult convertSubscription(const std::string &subscription,
+                                  const ConvertOptions &options) {
+  ConvertResult converted;
+  auto json_result = callBridge(
+      ConvertSubscriptionEx, subscriptionArg(subscription, options.preprocess),
+      convertOptionsToJSON(options), "ConvertSubscriptionEx",
+      &converted.warnings);
+  parseDiagnostics(json_result, converted.diagnostics);
+  converted.truncated = json_result.value("truncated", false);
+  return converted;
+}
+
+AnyParseResult parseAny(const std::string &content,
+                        const ConvertOptions &options) {
+  AnyParseResult parsed;
+  auto json_result =
+      callBridge(ParseAny, subscriptionArg(content, options.preprocess),
+                 convertOptionsToJSON(options), "ParseAny", &parsed.warnings);
+
+  parsed.format = json_result.value("format", "");
+  parsed.confidence = json_result.value("confidence", 0.0);
+  parsed.truncated = json_result.value("truncated", false);
+  parseDiagnostics(json_result, parsed.diagnostics);
+  return parsed;
+}
+
+CancelHandle::CancelHandle() : handle_(CreateCancelHandle()) {}
+
+CancelHandle::~CancelHandle() {
+  if (handle_ != 0) {
+    ReleaseCancelHandle(handle_);
+  }
+}
+
+void CancelHandle::cancel() { CancelConversion(handle_); }
+
+ConversionSession::ConversionSession(const ConvertOptions &options)
+    : preprocess_(options.preprocess) {
+  std::string json = convertOptionsToJSON(options);
+  auto json_result = parseBridgeResult(
+      CreateSessionEx(const_cast<char *>(json.c_str())), "CreateSessionEx");
+  handle_ = json_result.value("handle", 0LL);
+}
+
+ConversionSession::~ConversionSession() {
+  if (handle_ != 0) {
+    CloseSession(handle_);
+  }
+}
+
+SessionSource ConversionSession::feed(const std::string &source, int group,
+                                      const std::string &content) {
+  if (handle_ == 0) {
+    throw std::runtime_error("Conversion session is already finalized");
+  }
+  std::string data = subscriptionArg(content, preprocess_);
+  auto json_result = parseBridgeResult(
+      FeedSession(handle_, const_cast<char *>(source.c_str()), group,
+                  const_cast<char *>(data.c_str())),
+      "FeedSession");
+
+  return parseSessionSource(json_result);
+}
+
+SessionResult ConversionSession::finalize() {
+  if (handle_ == 0) {
+    throw std::runtime_error("Conversion session is already finalized");
+  }
+  long long handle = handle_;
+  handle_ = 0;
+  SessionResult result;
+  auto json_result = parseBridgeResult(FinalizeSession(handle),
+                                       "FinalizeSession", &result.warnings);
+
+  result.truncated = json_result.value("truncated", false);
+  for (const auto &item : json_result["proxies"]) {
+    SessionProxy proxy;
+    proxy.source = item.value("source", "");
+    proxy.group = item.value("group", 0);
+    proxy.renamed_from = item.value("original_name", "");
+    proxy.node = parseProxyNode(item["proxy"]);
+    result.proxies.push_back(std::move(proxy));
+  }
+  for (const auto &item : json_result["sources"]) {
+    result.sources.push_back(parseSessionSource(item));
+  }
+
+  return result;
+}
+
+ProviderParseResult parseProvider(const std::string &content,
+                                  const std::string &options) {
+  ProviderParseResult parsed;
+  auto json_result =
+      callBridge(ParseProvider, content, options, "ParseProvider");
+
+  parsed.format = json_result.value("format", "");
+  for (const auto &item : json_result["proxies"]) {
+    parsed.nodes.push_back(parseProxyNode(item));
+  }
+
+  return parsed;
+}
 
-    // Check for error
-    if (json_result.contains("error")) {
-      std::string error = json_result["error"];
-      FreeString(result);
-      throw std::runtime_error("Mihomo parser error: " + error);
+SingBoxImport importSingBox(const std::string &config) {
+  SingBoxImport imported;
+  auto json_result = callBridge(ImportSingBox, config, "ImportSingBox");
+
+  for (const auto &item : json_result["proxies"]) {
+    imported.nodes.push_back(parseProxyNode(item));
+  }
+
+  for (const auto &item : json_result["outbounds"]) {
+    SingBoxOutbound outbound;
+    outbound.index = item.value("index", 0);
+    outbound.tag = item.value("tag", "");
+    outbound.type = item.value("type", "");
+    outbound.status = item.value("status", "");
+    outbound.name = item.value("name", "");
+    outbound.error = item.value("error", "");
+    if (item.contains("unsupported")) {
+      outbound.unsupported =
+          item["unsupported"].get<std::vector<std::string>>();
     }
+    imported.outbounds.push_back(std::move(outbound));
+  }
 
-    // Parse proxy array
-    for (const auto &item : json_result) {
-      ProxyNode node;
-      node.name = item.value("name", "");
-      node.type = item.value("type", "");
-      node.server = item.value("server", "");
-
-      // Port: handle both number and string
-      if (item.contains("port")) {
-        if (item["port"].is_number()) {
-          node.port = item["port"].get<int>();
-        } else if (item["port"].is_string()) {
-          try {
-            node.port = std::stoi(item["port"].get<std::string>());
-          } catch (...) {
-            node.port = 0;
-          }
-        } else {
-          node.port = 0;
-        }
-      } else {
-        node.port = 0;
-      }
+  return imported;
+}
 
-      // Store all other fields in params
-      for (auto it = item.begin(); it != item.end(); ++it) {
-        const std::string &key = it.key();
-        if (key != "name" && key != "type" && key != "server" &&
-            key != "port") {
-          std::string value;
-          if (it->is_string()) {
-            value = it->get<std::string>();
-          } else if (it->is_number_integer()) {
-            value = std::to_string(it->get<int>());
-          } else if (it->is_number_float()) {
-            value = std::to_string(it->get<double>());
-          } else if (it->is_boolean()) {
-            value = it->get<bool>() ? "true" : "false";
-          } else {
-            value = it->dump(); // For complex types, serialize to JSON
-          }
-          node.params[key] = value;
-        }
+XrayImport importXray(const std::string &config) {
+  XrayImport imported;
+  auto json_result = callBridge(ImportXray, config, "ImportXray");
+
+  for (const auto &item : json_result["proxies"]) {
+    imported.nodes.push_back(parseProxyNode(item));
+  }
+
+  for (const auto &item : json_result["outbounds"]) {
+    XrayOutbound outbound;
+    outbound.index = item.value("index", 0);
+    outbound.tag = item.value("tag", "");
+    outbound.protocol = item.value("protocol", "");
+    outbound.status = item.value("status", "");
+    outbound.name = item.value("name", "");
+    outbound.error = item.value("error", "");
+    if (item.contains("unsupported")) {
+      outbound.unsupported =
+          item["unsupported"].get<std::vector<std::string>>();
+    }
+    imported.outbounds.push_back(std::move(outbound));
+  }
+
+  return imported;
+}
+
+WireGuardImport importWireGuardConf(const std::string &config) {
+  WireGuardImport imported;
+  auto json_result =
+      callBridge(ImportWireGuardConf, config, "ImportWireGuardConf");
+
+  for (const auto &item : json_result["proxies"]) {
+    imported.nodes.push_back(parseProxyNode(item));
+  }
+
+  for (const auto &item : json_result["peers"]) {
+    WireGuardPeer peer;
+    peer.index = item.value("index", 0);
+    peer.line = item.value("line", 0);
+    peer.status = item.value("status", "");
+    peer.name = item.value("name", "");
+    peer.error = item.value("error", "");
+    imported.peers.push_back(std::move(peer));
+  }
+
+  if (json_result.contains("ignored")) {
+    imported.ignored =
+        json_result["ignored"].get<std::vector<std::string>>();
+  }
+
+  return imported;
+}
+
+std::vector<ShareLink> exportShareLinks(const std::string &proxies) {
+  std::vector<ShareLink> links;
+  auto json_result = callBridge(ExportShareLinks, proxies, "ExportShareLinks");
+
+  for (const auto &item : json_result) {
+    ShareLink link;
+    link.name = item.value("name", "");
+    link.type = item.value("type", "");
+    link.link = item.value("link", "");
+    link.error = item.value("error", "");
+    if (item.contains("unsupported")) {
+      link.unsupported =
+          item["unsupported"].get<std::vector<std::string>>();
+    }
+    links.push_back(std::move(link));
+  }
+
+  return links;
+}
+
+std::vector<SingBoxExport> exportSingBox(const std::string &proxies) {
+  std::vector<SingBoxExport> exports;
+  auto json_result = callBridge(ExportSingBox, proxies, "ExportSingBox");
+
+  for (const auto &item : json_result) {
+    SingBoxExport exported;
+    exported.name = item.value("name", "");
+    exported.type = item.value("type", "");
+    exported.error = item.value("error", "");
+    if (item.contains("outbounds")) {
+      for (const auto &outbound : item["outbounds"]) {
+        exported.outbounds.push_back(outbound.dump());
       }
+    }
+    if (item.contains("unsupported")) {
+      exported.unsupported =
+          item["unsupported"].get<std::vector<std::string>>();
+    }
+    exports.push_back(std::move(exported));
+  }
+
+  return exports;
+}
+
+std::vector<SingBoxExport>
+exportSingBoxNodes(const std::vector<ProxyNode> &nodes) {
+  if (nodes.empty())
+    return {};
+
+  return exportSingBox(nodesToJSON(nodes));
+}
+
+std::vector<ProxyValidation> validateProxies(const std::string &proxies) {
+  std::vector<ProxyValidation> results;
+  auto json_result = callBridge(ValidateProxies, proxies, "ValidateProxies");
+
+  for (const auto &item : json_result) {
+    ProxyValidation result;
+    result.index = item.value("index", 0);
+    result.name = item.value("name", "");
+    result.type = item.value("type", "");
+    result.accepted = item.value("accepted", false);
+    result.error = item.value("error", "");
+    results.push_back(std::move(result));
+  }
+
+  return results;
+}
+
+std::vector<ProxyValidation> validateNodes(const std::vector<ProxyNode> &nodes) {
+  if (nodes.empty())
+    return {};
 
-      nodes.push_back(node);
+  return validateProxies(nodesToJSON(nodes));
+}
+
+size_t dropRejectedNodes(std::vector<ProxyNode> &nodes,
+                         std::vector<ProxyValidation> *rejected) {
+  auto results = validateNodes(nodes);
+
+  std::vector<ProxyNode> kept;
+  kept.reserve(nodes.size());
+  for (size_t i = 0; i < nodes.size() && i < results.size(); i++) {
+    if (results[i].accepted) {
+      kept.push_back(std::move(nodes[i]));
+    } else if (rejected) {
+      rejected->push_back(results[i]);
     }
+  }
 
-  } catch (const nlohmann::json::exception &e) {
-    FreeString(result);
-    throw std::runtime_error(std::string("JSON parse error: ") + e.what());
+  size_t removed = nodes.size() - kept.size();
+  nodes = std::move(kept);
+  return removed;
+}
+
+ConfigValidation validateConfig(const std::string &config) {
+  ConfigValidation validation;
+  auto json_result = callBridge(ValidateConfig, config, "ValidateConfig");
+
+  validation.valid = json_result.value("valid", false);
+  for (const auto &item : json_result["errors"]) {
+    ConfigError error;
+    error.path = item.value("path", "");
+    error.line = item.value("line", 0);
+    error.column = item.value("column", 0);
+    error.message = item.value("message", "");
+    validation.errors.push_back(std::move(error));
   }
 
-  // Free Go-allocated memory
-  FreeString(result);
+  return validation;
+}
 
-  return nodes;
+RuleValidation validateRules(const std::string &rules, const std::string &mode,
+                             const std::string &behavior) {
+  RuleValidation validation;
+  nlohmann::json options = {{"mode", mode}};
+  if (!behavior.empty()) {
+    options["behavior"] = behavior;
+  }
+  auto json_result =
+      callBridge(ValidateRules, rules, options.dump(), "ValidateRules");
+
+  validation.mode = json_result.value("mode", "");
+  validation.behavior = json_result.value("behavior", "");
+  validation.valid = json_result.value("valid", 0);
+  validation.invalid = json_result.value("invalid", 0);
+  for (const auto &item : json_result["rules"]) {
+    RuleCheck check;
+    check.index = item.value("index", 0);
+    check.rule = item.value("rule", "");
+    check.type = item.value("type", "");
+    check.normalized = item.value("normalized", "");
+    check.error = item.value("error", "");
+    validation.rules.push_back(std::move(check));
+  }
+  if (json_result.contains("counts")) {
+    validation.counts =
+        json_result["counts"].get<std::map<std::string, int>>();
+  }
+
+  return validation;
+}
+
+RuleValidation validateRuleLines(const std::vector<std::string> &lines) {
+  if (lines.empty())
+    return {};
+
+  return validateRules(nlohmann::json(lines).dump());
+}
+
+MrsRuleSet compileRuleSetMrs(const std::string &content,
+                             const std::string &behavior) {
+  MrsRuleSet compiled;
+  std::string options;
+  if (!behavior.empty()) {
+    options = nlohmann::json{{"behavior", behavior}}.dump();
+  }
+  auto json_result = callBridge(ConvertRuleSetToMrs, content, options,
+                                "ConvertRuleSetToMrs");
+
+  compiled.behavior = json_result.value("behavior", "");
+  compiled.format = json_result.value("format", "");
+  compiled.count = json_result.value("count", 0);
+  compiled.data = base64Decode(json_result.value("mrs", ""));
+  if (json_result.contains("invalid")) {
+    compiled.invalid = json_result["invalid"].get<std::vector<std::string>>();
+  }
+
+  return compiled;
+}
+
+RuleSetPayload decodeRuleSetMrs(const std::string &mrs) {
+  RuleSetPayload decoded;
+  auto json_result =
+      callBridge(ConvertMrsToRuleSet, base64Encode(mrs), "ConvertMrsToRuleSet");
+
+  decoded.behavior = json_result.value("behavior", "");
+  decoded.count = json_result.value("count", 0);
+  decoded.payload = json_result["payload"].get<std::vector<std::string>>();
+
+  return decoded;
+}
+
+void setMaxDecompressedSize(long long size) { SetMaxDecompressedSize(size); }
+
+void setConversionTimeout(long long ms) { SetConversionTimeout(ms); }
+
+BridgeInfo bridgeInfo() {
+  BridgeInfo info;
+  auto json_result = parseBridgeResult(::BridgeInfo(), "BridgeInfo");
+
+  info.mihomo_version = json_result.value("mihomo_version", "");
+  info.go_version = json_result.value("go_version", "");
+  info.api_version = json_result.value("api_version", 0);
+  info.response_version = json_result.value("response_version", 0);
+  info.schemes = json_result.value("schemes", std::vector<std::string>{});
+  info.aliases =
+      json_result.value("aliases", std::map<std::string, std::string>{});
+  info.transports = json_result.value(
+      "transports", std::map<std::string, std::vector<std::string>>{});
+  info.formats = json_result.value("formats", std::vector<std::string>{});
+
+  return info;
+}
+
+std::map<std::string, std::map<std::string, ProxyParam>> proxyParams() {
+  std::map<std::string, std::map<std::string, ProxyParam>> table;
+  auto json_result = parseBridgeResult(ParamCompat(), "ParamCompat");
+
+  for (const auto &[proxy_type, params] : json_result.items()) {
+    auto &entries = table[proxy_type];
+    for (const auto &[name, item] : params.items()) {
+      ProxyParam param;
+      param.type = item.value("type", "");
+      param.optional = item.value("optional", false);
+      param.source = item.value("source", "");
+      entries[name] = param;
+    }
+  }
+
+  return table;
+}
+
+const std::map<std::string, std::map<std::string, ParamCompatInfo>> &
+runtimeParamCompat() {
+  // The option structs of the linked library never change, so the bridge
+  // is asked once. Whether a key is hardcoded depends on ConvertsV2Ray,
+  // which reflection cannot see, so that flag comes from PARAM_COMPAT.
+  static const auto table = [] {
+    std::map<std::string, std::map<std::string, ParamCompatInfo>> table;
+    try {
+      for (const auto &[proxy_type, params] : proxyParams()) {
+        auto generated = PARAM_COMPAT.find(proxy_type);
+        for (const auto &[name, param] : params) {
+          bool hardcoded = false;
+          if (generated != PARAM_COMPAT.end()) {
+            auto it = generated->second.find(name);
+            hardcoded = it != generated->second.end() && it->second.hardcoded;
+          }
+          table[proxy_type][name] = {true, param.type, hardcoded};
+        }
+      }
+    } catch (const std::exception &) {
+      table.clear();
+    }
+    return table;
+  }();
+  return table;
 }
 
 bool isMihomoParserAvailable() {
-  // Simple check: try to call the function with empty input
   try {
-    char empty[] = "";
-    char *result = ConvertSubscription(empty);
-    if (result) {
-      FreeString(result);
-      return true;
-    }
-  } catch (...) {
+    bridgeInfo();
+    return true;
+  } catch (const std::exception &) {
     return false;
   }
-  return false;
 }
 
 } // namespace mihomo
@@ -2,12 +2,36 @@
 #define MIHOMO_BRIDGE_H
 
 #include <map>
+#include <stdexcept>
 #include <string>
 #include <vector>
 
 
 namespace mihomo {
 
+/**
+ * @brief Error reported by the Go bridge in its response envelope
+ *
+ * what() keeps the "Mihomo parser error: " prefix; code() is the machine
+ * readable reason, e.g. "invalid_input", "unknown_session" or
+ * "internal_panic" if the bridge recovered from a panic.
+ */
+class BridgeError : public std::runtime_error {
+public:
+  BridgeError(std::string code, const std::string &message,
+              std::string details = "")
+      : std::runtime_error("Mihomo parser error: " + message),
+        code_(std::move(code)), details_(std::move(details)) {}
+
+  const std::string &code() const { return code_; }
+  // JSON encoded details, empty if the bridge sent none
+  const std::string &details() const { return details_; }
+
+private:
+  std::string code_;
+  std::string details_;
+};
+
 /**
  * @brief Proxy node structure parsed from subscription links
  */
@@ -17,6 +41,7 @@ struct ProxyNode {
   std::string server;
   int port;
   std::map<std::string, std::string> params; // Additional parameters
+  std::string raw; // Original proxy JSON object returned by the bridge
 
   // For easier access
   std::string toYAML() const;
@@ -31,9 +56,640 @@ struct ProxyNode {
  */
 std::vector<ProxyNode> parseSubscription(const std::string &subscription);
 
+/**
+ * @brief Encoding fix applied to one component of a share link
+ */
+struct LinkRewrite {
+  std::string component; // "link", "userinfo", "path", "query" or "fragment"
+  std::string before;
+  std::string after;
+};
+
+/**
+ * @brief Outcome of one subscription line
+ */
+struct LineDiagnostic {
+  int line = 0;
+  std::string scheme;
+  std::string status; // "parsed", "skipped" or "failed"
+  std::string code;   // Machine-readable reason, e.g. "missing_port"
+  std::string message;
+  std::string name;   // Proxy name for parsed lines
+  std::string format; // "surge", "loon", "quanx" or "sip008"
+  // Proxy line options that have no mihomo equivalent
+  std::vector<std::string> unsupported;
+  std::vector<LinkRewrite> rewrites; // Applied before the line was parsed
+  std::string original_name;         // Name before cleanup, if it changed
+  // Cleanups applied to the name: "transcoded", "invisible", "control"
+  // or "nfc"
+  std::vector<std::string> name_fixes;
+};
+
+/**
+ * @brief Parsed nodes together with per-line diagnostics
+ */
+struct SubscriptionDiagnostics {
+  std::vector<ProxyNode> nodes;
+  std::vector<LineDiagnostic> lines;
+  int total = 0;
+  int parsed = 0;
+  int skipped = 0;
+  int failed = 0;
+  // Decoding steps that removed the envelope, outermost first, e.g.
+  // {"unwrap", "base64url"}; empty for plain text
+  std::vector<std::string> envelope;
+  std::vector<int> envelope_lines; // Lines decoded one by one (mixed content)
+};
+
+/**
+ * @brief Parse subscription content and report the outcome of every line
+ *
+ * @param subscription Base64-encoded or plain-text subscription data
+ * @return Parsed nodes and one diagnostic per input line
+ * @throws std::runtime_error if the bridge call fails
+ */
+SubscriptionDiagnostics diagnoseSubscription(const std::string &subscription);
+
+/**
+ * @brief Handle that stops conversions from another thread
+ *
+ * Pass handle() as ConvertOptions::cancel_handle. cancel() makes the
+ * conversions running under it return what they converted so far, marked
+ * truncated; later conversions with the handle stop at once. The handle is
+ * released on destruction.
+ */
+class CancelHandle {
+public:
+  CancelHandle();
+  ~CancelHandle();
+  CancelHandle(const CancelHandle &) = delete;
+  CancelHandle &operator=(const CancelHandle &) = delete;
+
+  // Safe to call from any thread, any number of times
+  void cancel();
+  long long handle() const { return handle_; }
+
+private:
+  long long handle_ = 0;
+};
+
+/**
+ * @brief Options of convertSubscription, parseAny and ConversionSession
+ *
+ * The defaults convert like diagnoseSubscription.
+ */
+struct ConvertOptions {
+  // "full" removes the envelope and normalizes links, "envelope" only
+  // removes the envelope, "none" converts the content as given. Content
+  // with NUL bytes (UTF-16) is rejected under "none".
+  std::string preprocess = "full";
+  // Fail at the first line that names a scheme but yields no proxy
+  bool strict = false;
+  // Link schemes or proxy types to keep, e.g. {"ss", "vmess"}; empty
+  // keeps all
+  std::vector<std::string> allowed_schemes;
+  int max_nodes = 0; // 0 for no limit
+  // "suffix" numbers repeated names, "keep" leaves them alone, "drop"
+  // drops proxies whose name is taken
+  std::string dedup = "suffix";
+  // JSON encoded values set where the proxy type accepts the key and the
+  // proxy lacks it, e.g. {"udp", "true"}
+  std::map<std::string, std::string> default_params;
+  // Stop after that many milliseconds; 0 uses the conversion timeout
+  long long timeout_ms = 0;
+  long long cancel_handle = 0; // CancelHandle::handle(), 0 for none
+};
+
+/**
+ * @brief Outcome of convertSubscription
+ */
+struct ConvertResult {
+  // Lines left out by the options are skipped with the code
+  // "scheme_not_allowed", "max_nodes" or "duplicate_name"
+  SubscriptionDiagnostics diagnostics;
+  // max_nodes was reached, or the timeout or cancel handle stopped the
+  // conversion before the last line
+  bool truncated = false;
+  std::vector<std::string> warnings;
+};
+
+/**
+ * @brief Convert subscription content under conversion options
+ *
+ * @param subscription Base64-encoded or plain-text subscription data
+ * @param options Preprocessing, strictness, filters and default params
+ * @return Parsed nodes and one diagnostic per input line
+ * @throws BridgeError with the line's code if strict parsing fails or the
+ *         cancel handle is unknown, or std::runtime_error if the bridge
+ *         call fails
+ */
+ConvertResult convertSubscription(const std::string &subscription,
+                                  const ConvertOptions &options);
+
+/**
+ * @brief Content whose format was detected and parsed by parseAny
+ */
+struct AnyParseResult {
+  // "base64", "links", "proxy-lines" (Surge, Loon, Quantumult X),
+  // "clash", "singbox", "xray", "sip008" or "wireguard"
+  std::string format;
+  // 1 for unambiguous markers, otherwise the share of recognized lines
+  double confidence = 0;
+  // Nodes, plus the lines and envelope for line based formats
+  SubscriptionDiagnostics diagnostics;
+  bool truncated = false; // max_nodes was reached or the conversion stopped
+  // What the importers or the options had to leave out
+  std::vector<std::string> warnings;
+};
+
+/**
+ * @brief Detect the format of arbitrary content and parse its proxies
+ *
+ * Recognizes base64 and plain share links, Clash YAML, sing-box and Xray
+ * JSON, SIP008, Surge/Loon/Quantumult X proxy lines and wg-quick
+ * configurations, compressed or not.
+ *
+ * @param content Subscription, config file or pasted text
+ * @param options Applied to the lines of line based formats and to the
+ *        proxies of the other formats
+ * @return Detected format, confidence and the parsed nodes
+ * @throws std::runtime_error if the format is not recognized or its
+ *         parser rejects the content
+ */
+AnyParseResult parseAny(const std::string &content,
+                        const ConvertOptions &options = {});
+
+/**
+ * @brief Proxy returned by a conversion session with its source
+ */
+struct SessionProxy {
+  std::string source;
+  int group = 0;
+  std::string renamed_from; // Name before cross-source de-duplication
+  ProxyNode node;
+};
+
+/**
+ * @brief Outcome of one input fed into a conversion session
+ */
+struct SessionSource {
+  std::string id;
+  int group = 0;
+  int total = 0;
+  int parsed = 0;
+  int failed = 0;
+  std::vector<LineDiagnostic> errors; // Lines that were skipped or failed
+  bool truncated = false; // The conversion timeout passed before the last line
+};
+
+/**
+ * @brief Everything a conversion session produced, in feed order
+ */
+struct SessionResult {
+  std::vector<SessionProxy> proxies;
+  std::vector<SessionSource> sources;
+  bool truncated = false; // max_nodes was reached
+  // Proxies left out by dedup "drop" or max_nodes
+  std::vector<std::string> warnings;
+};
+
+/**
+ * @brief Batch conversion of many inputs through one bridge session
+ *
+ * Every input is converted under the session's options. Proxy names are
+ * de-duplicated and max_nodes is applied across all fed inputs when the
+ * session is finalized. A session that is never finalized is closed on
+ * destruction.
+ */
+class ConversionSession {
+public:
+  /**
+   * @param options Conversion options for all inputs of the session
+   * @throws std::runtime_error if the options are invalid
+   */
+  explicit ConversionSession(const ConvertOptions &options = {});
+  ~ConversionSession();
+  ConversionSession(const ConversionSession &) = delete;
+  ConversionSession &operator=(const ConversionSession &) = delete;
+
+  /**
+   * @brief Parse subscription content into the session
+   *
+   * @param source Caller-chosen id reported with every proxy and error
+   * @param group Group id reported with every proxy
+   * @param content Base64-encoded or plain-text subscription data
+   * @return Summary and errors of this input
+   * @throws std::runtime_error if the session is closed or the call fails
+   */
+  SessionSource feed(const std::string &source, int group,
+                     const std::string &content);
+
+  /**
+   * @brief Collect all proxies and per-source reports, closing the session
+   *
+   * @return Proxies with source attribution and one report per feed
+   * @throws std::runtime_error if the session is closed or the call fails
+   */
+  SessionResult finalize();
+
+private:
+  long long handle_ = 0;
+  std::string preprocess_;
+};
+
+/**
+ * @brief Proxies loaded from a proxy-provider payload
+ */
+struct ProviderParseResult {
+  std::string format; // "yaml", "base64" or "links"
+  std::vector<ProxyNode> nodes;
+};
+
+/**
+ * @brief Parse a proxy-provider payload the way mihomo loads a provider
+ *
+ * @param content Clash YAML with a proxies list, base64 or plain links
+ * @param options Optional JSON object with provider keys (filter,
+ *                exclude-filter, exclude-type, dialer-proxy, override)
+ * @return Detected payload format and the proxies the provider would load
+ * @throws std::runtime_error if mihomo would reject the payload
+ */
+ProviderParseResult parseProvider(const std::string &content,
+                                  const std::string &options = "");
+
+/**
+ * @brief Outcome of one sing-box outbound
+ */
+struct SingBoxOutbound {
+  int index = 0; // Position in the outbounds list
+  std::string tag;
+  std::string type;
+  std::string status; // "converted", "skipped" or "failed"
+  std::string name;   // Proxy name for converted outbounds
+  std::vector<std::string> unsupported; // sing-box fields mihomo cannot carry
+  std::string error;
+};
+
+/**
+ * @brief Proxies imported from sing-box outbounds
+ */
+struct SingBoxImport {
+  std::vector<ProxyNode> nodes;
+  std::vector<SingBoxOutbound> outbounds;
+};
+
+/**
+ * @brief Convert sing-box outbounds to mihomo proxies
+ *
+ * @param config sing-box config with an outbounds list, a bare outbounds
+ *               array or a single outbound object
+ * @return Converted nodes and one report per outbound
+ * @throws std::runtime_error if the input is not sing-box JSON
+ */
+SingBoxImport importSingBox(const std::string &config);
+
+/**
+ * @brief Outcome of one Xray/v2ray outbound
+ */
+struct XrayOutbound {
+  int index = 0; // Position in the outbounds list
+  std::string tag;
+  std::string protocol;
+  std::string status; // "converted", "skipped" or "failed"
+  std::string name;   // Proxy name for converted outbounds
+  std::vector<std::string> unsupported; // Xray fields mihomo cannot carry
+  std::string error;
+};
+
+/**
+ * @brief Proxies imported from Xray/v2ray outbounds
+ */
+struct XrayImport {
+  std::vector<ProxyNode> nodes;
+  std::vector<XrayOutbound> outbounds;
+};
+
+/**
+ * @brief Convert Xray/v2ray client outbounds to mihomo proxies
+ *
+ * freedom, blackhole and other local outbounds are skipped; outbounds
+ * whose streamSettings mihomo cannot express (xhttp, kcp, quic) fail.
+ *
+ * @param config Xray config with an outbounds list, a bare outbounds array
+ *               or a single outbound object
+ * @return Converted nodes and one report per outbound
+ * @throws std::runtime_error if the input is not Xray JSON
+ */
+XrayImport importXray(const std::string &config);
+
+/**
+ * @brief Outcome of one [Peer] section of a WireGuard configuration
+ */
+struct WireGuardPeer {
+  int index = 0; // Position among the [Peer] sections
+  int line = 0;
+  std::string status; // "converted" or "failed"
+  std::string name;   // Proxy name for converted peers
+  std::string error;
+};
+
+/**
+ * @brief Proxies imported from a wg-quick configuration
+ */
+struct WireGuardImport {
+  std::vector<ProxyNode> nodes;
+  std::vector<WireGuardPeer> peers;
+  std::vector<std::string> ignored; // Interface keys mihomo has no use for
+};
+
+/**
+ * @brief Convert a wg-quick configuration to mihomo wireguard proxies
+ *
+ * Every [Peer] with an Endpoint becomes one proxy carrying the [Interface]
+ * settings, including AmneziaWG parameters.
+ *
+ * @param config Content of a WireGuard .conf file
+ * @return Converted nodes and one report per peer
+ * @throws std::runtime_error if the [Interface] section is missing or
+ *         invalid
+ */
+WireGuardImport importWireGuardConf(const std::string &config);
+
+/**
+ * @brief Share link exported from a mihomo proxy
+ */
+struct ShareLink {
+  std::string name;
+  std::string type;
+  std::string link;                     // Empty if the proxy cannot be exported
+  std::vector<std::string> unsupported; // Fields the link format cannot carry
+  std::string error;
+};
+
+/**
+ * @brief Convert mihomo proxies back to share links
+ *
+ * @param proxies JSON array of proxy maps, or Clash YAML with a proxies list
+ * @return One entry per input proxy, in input order
+ * @throws std::runtime_error if the input cannot be decoded
+ */
+std::vector<ShareLink> exportShareLinks(const std::string &proxies);
+
+/**
+ * @brief sing-box outbounds exported from a mihomo proxy
+ */
+struct SingBoxExport {
+  std::string name;
+  std::string type;
+  // Serialized JSON objects: the proxy outbound first, then any outbound
+  // it uses as detour (e.g. shadowtls). Empty if the proxy cannot be
+  // exported.
+  std::vector<std::string> outbounds;
+  std::vector<std::string> unsupported; // Fields sing-box cannot carry
+  std::string error;
+};
+
+/**
+ * @brief Convert mihomo proxies to sing-box outbounds
+ *
+ * @param proxies JSON array of proxy maps, or Clash YAML with a proxies list
+ * @return One entry per input proxy, in input order
+ * @throws std::runtime_error if the input cannot be decoded
+ */
+std::vector<SingBoxExport> exportSingBox(const std::string &proxies);
+
+/**
+ * @brief Convert parsed nodes to sing-box outbounds
+ *
+ * @param nodes Nodes returned by the bridge, or built from mihomo fields
+ * @return One entry per node, in input order
+ */
+std::vector<SingBoxExport> exportSingBoxNodes(const std::vector<ProxyNode> &nodes);
+
+/**
+ * @brief Verdict of mihomo's outbound constructor for one proxy
+ */
+struct ProxyValidation {
+  int index = 0; // Position in the input list
+  std::string name;
+  std::string type;
+  bool accepted = false;
+  std::string error; // Exact constructor error when rejected
+};
+
+/**
+ * @brief Instantiate proxies offline with mihomo's adapter parser
+ *
+ * @param proxies JSON array of proxy maps, or Clash YAML with a proxies list
+ * @return One verdict per input proxy, in input order
+ * @throws std::runtime_error if the input cannot be decoded
+ */
+std::vector<ProxyValidation> validateProxies(const std::string &proxies);
+
+/**
+ * @brief Instantiate parsed nodes offline with mihomo's adapter parser
+ *
+ * @param nodes Nodes returned by parseSubscription/diagnoseSubscription
+ * @return One verdict per node, in input order
+ */
+std::vector<ProxyValidation> validateNodes(const std::vector<ProxyNode> &nodes);
+
+/**
+ * @brief Remove nodes that mihomo would refuse to load
+ *
+ * @param nodes Nodes to filter in place
+ * @param rejected Optional output for the verdicts of removed nodes
+ * @return Number of removed nodes
+ */
+size_t dropRejectedNodes(std::vector<ProxyNode> &nodes,
+                         std::vector<ProxyValidation> *rejected = nullptr);
+
+/**
+ * @brief One problem found in a mihomo config
+ */
+struct ConfigError {
+  std::string path; // YAML path, e.g. "proxy-groups[2]" or "rules[10]"
+  int line = 0;
+  int column = 0;
+  std::string message;
+};
+
+/**
+ * @brief Verdict of the offline mihomo config check
+ */
+struct ConfigValidation {
+  bool valid = false;
+  std::vector<ConfigError> errors;
+};
+
+/**
+ * @brief Check a complete mihomo config offline
+ *
+ * Runs mihomo's config parsing (proxies, providers, groups, listeners,
+ * rule-providers, sub-rules, rules, tunnels) without starting listeners,
+ * downloading geodata or resolving anything.
+ *
+ * @param config Generated Clash/mihomo YAML
+ * @return Whether the core would accept it, and every error found
+ * @throws std::runtime_error if the bridge call fails
+ */
+ConfigValidation validateConfig(const std::string &config);
+
+/**
+ * @brief Verdict of mihomo's rule parser for one rule line
+ */
+struct RuleCheck {
+  int index = 0; // Position in the input list
+  std::string rule;
+  std::string type;
+  std::string normalized; // Canonical form of an accepted rule
+  std::string error;      // Parser error when rejected
+};
+
+/**
+ * @brief Result of checking a list of rules
+ */
+struct RuleValidation {
+  std::string mode;     // "rules" or "payload"
+  std::string behavior; // Payload behavior in payload mode
+  std::vector<RuleCheck> rules;
+  std::map<std::string, int> counts; // Accepted rules per type
+  int valid = 0;
+  int invalid = 0;
+};
+
+/**
+ * @brief Check rules with mihomo's rule parser
+ *
+ * @param rules JSON array of rules, or a YAML/text rule list
+ * @param mode "rules" for config rules (TYPE,payload,target) or "payload"
+ *             for rule-provider entries
+ * @param behavior Payload behavior (domain, ipcidr, classical); detected
+ *                 if empty
+ * @return One verdict per rule and counts per rule type
+ * @throws std::runtime_error if the input or options are invalid
+ */
+RuleValidation validateRules(const std::string &rules,
+                             const std::string &mode = "rules",
+                             const std::string &behavior = "");
+
+/**
+ * @brief Check config rule lines with mihomo's rule parser
+ *
+ * @param lines Rules in TYPE,payload,target[,params] form
+ * @return One verdict per line, in input order
+ */
+RuleValidation validateRuleLines(const std::vector<std::string> &lines);
+
+/**
+ * @brief Rule payload compiled to MRS
+ */
+struct MrsRuleSet {
+  std::string behavior; // "domain" or "ipcidr"
+  std::string format;   // Detected input format: "yaml" or "text"
+  int count = 0;        // Number of rules stored
+  std::vector<std::string> invalid; // Entries dropped by the conversion
+  std::string data;                 // Binary MRS content
+};
+
+/**
+ * @brief Text payload decoded from an MRS file
+ */
+struct RuleSetPayload {
+  std::string behavior;
+  int count = 0;
+  std::vector<std::string> payload;
+};
+
+/**
+ * @brief Compile a rule list to MRS with mihomo's rule-provider converter
+ *
+ * @param content YAML payload or text rule list (Surge/classical lines are
+ *                accepted when they only use domain or IP-CIDR rules)
+ * @param behavior "domain" or "ipcidr"; detected from the payload if empty
+ * @return Binary MRS content and conversion details
+ * @throws std::runtime_error if the payload cannot be stored as MRS
+ */
+MrsRuleSet compileRuleSetMrs(const std::string &content,
+                             const std::string &behavior = "");
+
+/**
+ * @brief Decode an MRS file back into its text payload
+ *
+ * @param mrs Binary MRS content
+ * @return Behavior and rules stored in the file
+ * @throws std::runtime_error if the data is not a valid MRS file
+ */
+RuleSetPayload decodeRuleSetMrs(const std::string &mrs);
+
+/**
+ * @brief Set the size cap for decompressed subscription payloads
+ *
+ * gzip, zlib, zstd and brotli payloads are inflated by the bridge before
+ * parsing; payloads that inflate past the cap are rejected.
+ *
+ * @param size Cap in bytes, 0 for no cap
+ */
+void setMaxDecompressedSize(long long size);
+
+/**
+ * @brief Set the time limit of every conversion
+ *
+ * Conversions that run out of time stop between lines and return the
+ * proxies converted so far, marked truncated, so one pathological
+ * subscription cannot hold a worker thread. ConvertOptions::timeout_ms
+ * overrides it per call.
+ *
+ * @param ms Limit in milliseconds, 0 for no limit
+ */
+void setConversionTimeout(long long ms);
+
+/**
+ * @brief Linked parser version and capabilities reported by the bridge
+ */
+struct BridgeInfo {
+  std::string mihomo_version; // mihomo module version from the build info
+  std::string go_version;
+  int api_version = 0;
+  int response_version = 0;
+  std::vector<std::string> schemes;
+  std::map<std::string, std::string> aliases; // Alias -> parsed scheme
+  // Proxy type -> network values its outbound accepts
+  std::map<std::string, std::vector<std::string>> transports;
+  std::vector<std::string> formats; // Formats detected by parseAny
+};
+
+/**
+ * @brief Query the version and capabilities of the linked bridge
+ *
+ * @return Runtime information of the Go library
+ * @throws std::runtime_error if the bridge call fails
+ */
+BridgeInfo bridgeInfo();
+
+/**
+ * @brief Top-level key a mihomo proxy type accepts
+ */
+struct ProxyParam {
+  std::string type; // "bool", "int", "float", "string", "array" or "object"
+  bool optional = false;
+  std::string source; // Option struct that declares it, e.g. "BasicOption"
+};
+
+/**
+ * @brief Query the keys every proxy type of the linked mihomo accepts
+ *
+ * The bridge reflects over mihomo's outbound option structs, so the result
+ * always matches the library that parses the proxies.
+ *
+ * @return Proxy type -> key -> parameter
+ * @throws std::runtime_error if the bridge call fails
+ */
+std::map<std::string, std::map<std::string, ProxyParam>> proxyParams();
+
 /**
  * @brief Check if mihomo parser is available
- * @return true if the Go library is properly linked
+ * @return true if the Go library is properly linked and answers
  */
 bool isMihomoParserAvailable();
 
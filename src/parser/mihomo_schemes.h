@@ -1,6 +1,6 @@
 // Auto-generated by scripts/generate_schemes.go
 // DO NOT EDIT MANUALLY
-// Based on mihomo version: latest
+// Based on mihomo version: v1.19.20
 
 #pragma once
 #include <vector>
@@ -23,5 +23,13 @@ namespace mihomo {
         "http",
         "https",
         "anytls",
+        "wireguard",
+        "wg",
+        "ssh",
+        "snell",
+        "mieru",
+        "mierus",
+        "socks5+tls",
+        "socks+tls",
     };
 }
@@ -179,6 +179,60 @@ const std::map<std::string, std::map<std::string, ParamCompatInfo>> PARAM_COMPAT
         {"udp-mtu", {true, "int", false}}, // hysteria2
         {"up", {true, "string", false}}, // hysteria2
     }},
+    // Protocol: mieru
+    {"mieru", {
+        {"dialer-proxy", {true, "string", false}}, // BasicOption
+        {"handshake-mode", {true, "string", false}}, // mieru
+        {"interface-name", {true, "string", false}}, // BasicOption
+        {"ip-version", {true, "string", false}}, // BasicOption
+        {"mptcp", {true, "bool", false}}, // BasicOption
+        {"multiplexing", {true, "string", false}}, // mieru
+        {"name", {true, "string", false}}, // mieru
+        {"password", {true, "string", false}}, // mieru
+        {"port", {true, "int", false}}, // mieru
+        {"port-range", {true, "string", false}}, // mieru
+        {"routing-mark", {true, "int", false}}, // BasicOption
+        {"server", {true, "string", false}}, // mieru
+        {"tfo", {true, "bool", false}}, // BasicOption
+        {"transport", {true, "string", false}}, // mieru
+        {"udp", {true, "bool", false}}, // mieru
+        {"username", {true, "string", false}}, // mieru
+    }},
+    // Protocol: mierus
+    {"mierus", {
+        {"dialer-proxy", {true, "string", false}}, // BasicOption
+        {"handshake-mode", {true, "string", false}}, // mierus
+        {"interface-name", {true, "string", false}}, // BasicOption
+        {"ip-version", {true, "string", false}}, // BasicOption
+        {"mptcp", {true, "bool", false}}, // BasicOption
+        {"multiplexing", {true, "string", false}}, // mierus
+        {"name", {true, "string", false}}, // mierus
+        {"password", {true, "string", false}}, // mierus
+        {"port", {true, "int", false}}, // mierus
+        {"port-range", {true, "string", false}}, // mierus
+        {"routing-mark", {true, "int", false}}, // BasicOption
+        {"server", {true, "string", false}}, // mierus
+        {"tfo", {true, "bool", false}}, // BasicOption
+        {"transport", {true, "string", false}}, // mierus
+        {"udp", {true, "bool", false}}, // mierus
+        {"username", {true, "string", false}}, // mierus
+    }},
+    // Protocol: snell
+    {"snell", {
+        {"dialer-proxy", {true, "string", false}}, // BasicOption
+        {"interface-name", {true, "string", false}}, // BasicOption
+        {"ip-version", {true, "string", false}}, // BasicOption
+        {"mptcp", {true, "bool", false}}, // BasicOption
+        {"name", {true, "string", false}}, // snell
+        {"obfs-opts", {true, "object", false}}, // snell
+        {"port", {true, "int", false}}, // snell
+        {"psk", {true, "string", false}}, // snell
+        {"routing-mark", {true, "int", false}}, // BasicOption
+        {"server", {true, "string", false}}, // snell
+        {"tfo", {true, "bool", false}}, // BasicOption
+        {"udp", {true, "bool", false}}, // snell
+        {"version", {true, "int", false}}, // snell
+    }},
     // Protocol: socks
     {"socks", {
         {"certificate", {true, "string", false}}, // socks
@@ -259,6 +313,24 @@ const std::map<std::string, std::map<std::string, ParamCompatInfo>> PARAM_COMPAT
         {"udp-over-tcp", {true, "bool", true}}, // ss [HARDCODED]
         {"udp-over-tcp-version", {true, "int", false}}, // ss
     }},
+    // Protocol: ssh
+    {"ssh", {
+        {"dialer-proxy", {true, "string", false}}, // BasicOption
+        {"host-key", {true, "array", false}}, // ssh
+        {"host-key-algorithms", {true, "array", false}}, // ssh
+        {"interface-name", {true, "string", false}}, // BasicOption
+        {"ip-version", {true, "string", false}}, // BasicOption
+        {"mptcp", {true, "bool", false}}, // BasicOption
+        {"name", {true, "string", false}}, // ssh
+        {"password", {true, "string", false}}, // ssh
+        {"port", {true, "int", false}}, // ssh
+        {"private-key", {true, "string", false}}, // ssh
+        {"private-key-passphrase", {true, "string", false}}, // ssh
+        {"routing-mark", {true, "int", false}}, // BasicOption
+        {"server", {true, "string", false}}, // ssh
+        {"tfo", {true, "bool", false}}, // BasicOption
+        {"username", {true, "string", false}}, // ssh
+    }},
     // Protocol: ssr
     {"ssr", {
         {"cipher", {true, "string", false}}, // ssr
@@ -415,12 +487,80 @@ const std::map<std::string, std::map<std::string, ParamCompatInfo>> PARAM_COMPAT
         {"ws-opts", {true, "string", false}}, // vmess
         {"xudp", {true, "bool", true}}, // vmess [HARDCODED]
     }},
+    // Protocol: wg
+    {"wg", {
+        {"allowed-ips", {true, "array", false}}, // wg
+        {"amnezia-wg-option", {true, "string", false}}, // wg
+        {"dialer-proxy", {true, "string", false}}, // BasicOption
+        {"dns", {true, "array", false}}, // wg
+        {"interface-name", {true, "string", false}}, // BasicOption
+        {"ip", {true, "string", false}}, // wg
+        {"ip-version", {true, "string", false}}, // BasicOption
+        {"ipv6", {true, "string", false}}, // wg
+        {"mptcp", {true, "bool", false}}, // BasicOption
+        {"mtu", {true, "int", false}}, // wg
+        {"name", {true, "string", false}}, // wg
+        {"peers", {true, "array", false}}, // wg
+        {"persistent-keepalive", {true, "int", false}}, // wg
+        {"port", {true, "int", false}}, // wg
+        {"pre-shared-key", {true, "string", false}}, // wg
+        {"private-key", {true, "string", false}}, // wg
+        {"public-key", {true, "string", false}}, // wg
+        {"refresh-server-ip-interval", {true, "int", false}}, // wg
+        {"remote-dns-resolve", {true, "bool", false}}, // wg
+        {"reserved", {true, "array", false}}, // wg
+        {"routing-mark", {true, "int", false}}, // BasicOption
+        {"server", {true, "string", false}}, // wg
+        {"tfo", {true, "bool", false}}, // BasicOption
+        {"udp", {true, "bool", false}}, // wg
+        {"workers", {true, "int", false}}, // wg
+    }},
+    // Protocol: wireguard
+    {"wireguard", {
+        {"allowed-ips", {true, "array", false}}, // wireguard
+        {"amnezia-wg-option", {true, "string", false}}, // wireguard
+        {"dialer-proxy", {true, "string", false}}, // BasicOption
+        {"dns", {true, "array", false}}, // wireguard
+        {"interface-name", {true, "string", false}}, // BasicOption
+        {"ip", {true, "string", false}}, // wireguard
+        {"ip-version", {true, "string", false}}, // BasicOption
+        {"ipv6", {true, "string", false}}, // wireguard
+        {"mptcp", {true, "bool", false}}, // BasicOption
+        {"mtu", {true, "int", false}}, // wireguard
+        {"name", {true, "string", false}}, // wireguard
+        {"peers", {true, "array", false}}, // wireguard
+        {"persistent-keepalive", {true, "int", false}}, // wireguard
+        {"port", {true, "int", false}}, // wireguard
+        {"pre-shared-key", {true, "string", false}}, // wireguard
+        {"private-key", {true, "string", false}}, // wireguard
+        {"public-key", {true, "string", false}}, // wireguard
+        {"refresh-server-ip-interval", {true, "int", false}}, // wireguard
+        {"remote-dns-resolve", {true, "bool", false}}, // wireguard
+        {"reserved", {true, "array", false}}, // wireguard
+        {"routing-mark", {true, "int", false}}, // BasicOption
+        {"server", {true, "string", false}}, // wireguard
+        {"tfo", {true, "bool", false}}, // BasicOption
+        {"udp", {true, "bool", false}}, // wireguard
+        {"workers", {true, "int", false}}, // wireguard
+    }},
 };
 
+// Parameters of the linked mihomo, reflected from its option structs by the
+// bridge and merged with the hardcoded flags above (see mihomo_bridge.cpp).
+// Empty if the bridge cannot be queried.
+const std::map<std::string, std::map<std::string, ParamCompatInfo>>& runtimeParamCompat();
+
+// The runtime table, or PARAM_COMPAT without one
+inline const std::map<std::string, std::map<std::string, ParamCompatInfo>>& paramCompatTable() {
+    const auto& runtime = runtimeParamCompat();
+    return runtime.empty() ? PARAM_COMPAT : runtime;
+}
+
 // Check if a protocol supports a specific parameter
 inline bool isParamSupported(const std::string& protocol, const std::string& param) {
-    auto proto_it = PARAM_COMPAT.find(protocol);
-    if (proto_it == PARAM_COMPAT.end()) return false;
+    const auto& table = paramCompatTable();
+    auto proto_it = table.find(protocol);
+    if (proto_it == table.end()) return false;
     auto param_it = proto_it->second.find(param);
     return param_it != proto_it->second.end() && param_it->second.supported;
 }
@@ -428,8 +568,9 @@ inline bool isParamSupported(const std::string& protocol, const std::string& par
 // Check if a parameter is hardcoded by Mihomo for this protocol
 // Hardcoded parameters should NOT be overridden by global settings
 inline bool isParamHardcoded(const std::string& protocol, const std::string& param) {
-    auto proto_it = PARAM_COMPAT.find(protocol);
-    if (proto_it == PARAM_COMPAT.end()) return false;
+    const auto& table = paramCompatTable();
+    auto proto_it = table.find(protocol);
+    if (proto_it == table.end()) return false;
     auto param_it = proto_it->second.find(param);
     return param_it != proto_it->second.end() && param_it->second.hardcoded;
 }